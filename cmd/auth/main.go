@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/trichner/tb/pkg/googleauth"
+	"github.com/trichner/tb/pkg/sheets"
+)
+
+var cli struct {
+	Status struct {
+		googleauth.Flags `embed:""`
+	} `cmd:"" help:"Show the identity and scopes used for Google APIs."`
+}
+
+func Exec(ctx context.Context, args []string) {
+	k, err := kong.New(&cli, kong.Name(args[0]))
+	if err != nil {
+		log.Fatal(err)
+	}
+	kctx, err := k.Parse(args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch kctx.Command() {
+	case "status":
+		err := printStatus(ctx, cli.Status.Config())
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		panic(kctx.Command())
+	}
+}
+
+func printStatus(ctx context.Context, cfg *googleauth.Config) error {
+	status, err := googleauth.Describe(ctx, cfg, sheets.Scopes...)
+	if err != nil {
+		return fmt.Errorf("cannot determine authentication status: %w", err)
+	}
+
+	email := status.Email
+	if email == "" {
+		email = "unknown, token carries no email scope"
+	}
+
+	fmt.Printf("method:    %s\n", status.Method)
+	fmt.Printf("origin:    %s\n", status.Origin)
+	fmt.Printf("identity:  %s\n", email)
+	if status.Subject != "" {
+		fmt.Printf("subject:   %s\n", status.Subject)
+	}
+	fmt.Printf("expires:   %s\n", status.Expiry.Format(time.RFC3339))
+	fmt.Printf("requested: %s\n", strings.Join(status.Scopes, " "))
+	fmt.Printf("granted:   %s\n", strings.Join(status.GrantedScopes, " "))
+
+	missing := missingScopes(status.Scopes, status.GrantedScopes)
+	if len(missing) > 0 {
+		fmt.Printf("missing:   %s\n", strings.Join(missing, " "))
+	}
+	return nil
+}
+
+func missingScopes(requested, granted []string) []string {
+	var missing []string
+	for _, s := range requested {
+		if !slices.Contains(granted, s) {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
@@ -1,6 +1,7 @@
 package jiracli
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -24,7 +25,10 @@ var cli struct {
 		Remove string `help:"Groups to remove, comma separated." required:""`
 	} `cmd:"" help:"Add groups to an existing user."`
 	Issues struct {
-		Query string `help:"search by query" required:""`
+		Query  string   `help:"search by query" required:""`
+		Limit  int      `help:"maximum number of issues to print, 0 for all"`
+		Fields []string `help:"additional fields to print as they are returned by Jira, e.g. 'created,priority'"`
+		Expand []string `help:"sections to include, only 'changelog' is supported" enum:"changelog" placeholder:"SECTION"`
 	} `cmd:"" help:"Find or update issues"`
 }
 
@@ -46,13 +50,18 @@ func Exec(ctx context.Context, args []string) {
 		name := deriveNameFromEmail(email)
 		createUser(name, email, groups)
 	case "issues":
-		queryIssues(cli.Issues.Query)
+		queryIssues(cli.Issues.Query, &jira.SearchOptions{
+			Limit:  cli.Issues.Limit,
+			Fields: cli.Issues.Fields,
+			Expand: cli.Issues.Expand,
+		})
 	default:
 		panic(kctx.Command())
 	}
 }
 
-func queryIssues(query string) {
+// queryIssues prints the matching issues as NDJSON while paging through them
+func queryIssues(query string, opts *jira.SearchOptions) {
 	clientCredentials, err := credentials.FindCredentials()
 	if err != nil {
 		log.Fatal(err)
@@ -63,12 +72,14 @@ func queryIssues(query string) {
 		log.Fatal(err)
 	}
 
-	issues, err := service.SearchByQuery(query)
-	if err != nil {
-		log.Fatal(err)
-	}
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
 
-	err = json.NewEncoder(os.Stdout).Encode(issues)
+	enc := json.NewEncoder(w)
+	err = service.SearchByQuery(query, opts, func(issue *jira.Issue) error {
+		return enc.Encode(issue)
+	})
+	w.Flush()
 	if err != nil {
 		log.Fatal(err)
 	}
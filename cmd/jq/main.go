@@ -0,0 +1,88 @@
+package jq
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/query"
+)
+
+var cli struct {
+	Filter      string   `arg:"" help:"jq filter, e.g. '.items[] | select(.qty > 1) | {id, qty}'"`
+	Files       []string `arg:"" optional:"" help:"JSON or NDJSON files to read instead of stdin" type:"existingfile"`
+	RawOutput   bool     `short:"r" help:"print strings without quotes"`
+	InputFormat string   `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]), kong.Description("Filter and project JSON with a subset of jq."))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	q, err := query.Compile(cli.Filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	emit := func(n ast.Node) error {
+		return write(w, n, cli.RawOutput)
+	}
+
+	format := jsontree.Format(cli.InputFormat)
+	if len(cli.Files) == 0 {
+		if err := apply(q, os.Stdin, format, emit); err != nil {
+			w.Flush()
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, path := range cli.Files {
+		if err := applyFile(q, path, format, emit); err != nil {
+			w.Flush()
+			log.Fatalf("%s: %v", path, err)
+		}
+	}
+}
+
+func applyFile(q *query.Query, path string, format jsontree.Format, emit func(ast.Node) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return apply(q, f, format, emit)
+}
+
+func apply(q *query.Query, r io.Reader, format jsontree.Format, emit func(ast.Node) error) error {
+	dec, err := jsontree.NewDecoderFormat(r, format)
+	if err != nil {
+		return err
+	}
+	return q.ApplyDecoder(dec, emit)
+}
+
+func write(w io.Writer, n ast.Node, raw bool) error {
+	if t, ok := n.(ast.TextNode); ok && raw {
+		_, err := io.WriteString(w, t.Value()+"\n")
+		return err
+	}
+
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -9,11 +9,28 @@ import (
 
 	"github.com/alecthomas/kong"
 
+	"github.com/trichner/tb/pkg/googleauth"
 	"github.com/trichner/tb/pkg/json2sheet"
+	"github.com/trichner/tb/pkg/jsontree"
 )
 
 var cli struct {
 	SpreadsheetUrl string `help:"complete URL to the spreadsheet"`
+	SkipInvalid    bool   `help:"skip NDJSON records that are not valid JSON and report them on stderr"`
+	InputFormat    string `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+	Array          bool   `help:"read the input as a single array of objects, e.g. '[{\"a\":1},{\"a\":2}]', and write a row per element"`
+
+	Flatten          bool   `help:"spread nested objects and arrays across columns named by their path, e.g. 'address.city'"`
+	FlattenSeparator string `help:"separator between nested property names" default:"."`
+	FlattenArrays    string `help:"how to flatten arrays: 'brackets' for 'tags[0]', 'separator' for 'tags.0' or 'keep' to write them as JSON text" enum:"brackets,separator,keep" default:"brackets"`
+
+	googleauth.Flags `embed:""`
+}
+
+var arrayModes = map[string]jsontree.ArrayMode{
+	"brackets":  jsontree.ArraysBracketed,
+	"separator": jsontree.ArraysSeparated,
+	"keep":      jsontree.ArraysAsValues,
 }
 
 func Exec(ctx context.Context, args []string) {
@@ -21,15 +38,32 @@ func Exec(ctx context.Context, args []string) {
 	_, err := parser.Parse(args[1:])
 	parser.FatalIfErrorf(err)
 
+	ctx = googleauth.WithConfig(ctx, cli.Config())
+
+	opts := &json2sheet.Options{
+		SkipInvalid: cli.SkipInvalid,
+		Format:      jsontree.Format(cli.InputFormat),
+		Array:       cli.Array,
+		OnSkip: func(err error) {
+			log.Printf("skipping %v", err)
+		},
+	}
+	if cli.Flatten {
+		opts.Flatten = &jsontree.FlattenOptions{
+			Separator: cli.FlattenSeparator,
+			Arrays:    arrayModes[cli.FlattenArrays],
+		}
+	}
+
 	spreadsheetUrl := strings.TrimSpace(cli.SpreadsheetUrl)
 	if spreadsheetUrl != "" {
-		url, err := json2sheet.UpdateSheet(ctx, spreadsheetUrl, os.Stdin)
+		url, err := json2sheet.UpdateSheet(ctx, spreadsheetUrl, os.Stdin, opts)
 		if err != nil {
 			log.Fatal(err)
 		}
 		fmt.Println(url)
 	} else {
-		url, err := json2sheet.WriteToNewSheet(ctx, os.Stdin)
+		url, err := json2sheet.WriteToNewSheet(ctx, os.Stdin, opts)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -1,6 +1,7 @@
 package json2sheet
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,7 +11,9 @@ import (
 )
 
 func TestExec(t *testing.T) {
-	url, err := json2sheet.WriteToNewSheet(os.Stdin)
+	t.Skip("integration test")
+
+	url, err := json2sheet.WriteToNewSheet(context.Background(), os.Stdin, nil)
 	if err != nil {
 		log.Fatal(err)
 	}
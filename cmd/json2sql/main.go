@@ -0,0 +1,71 @@
+package json2sql
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/tb/pkg/json2sql"
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+var cli struct {
+	Table     string   `help:"table to insert into, optionally schema qualified" required:""`
+	Create    bool     `help:"create the table if it does not exist, column types are inferred from the first batch"`
+	BatchSize int      `help:"number of records inserted per transaction" default:"500"`
+	UpsertKey []string `help:"columns identifying a row, existing rows are updated instead of rejected" optional:""`
+	Rejected  string   `help:"file to write rejected records to as NDJSON" optional:"" type:"path"`
+
+	InputFormat string `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+
+	sql2json.ConnectionFlags `embed:""`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	cfg, err := cli.Config()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := sql2json.Open(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	opts := &json2sql.Options{
+		Table:     cli.Table,
+		Create:    cli.Create,
+		BatchSize: cli.BatchSize,
+		UpsertKey: cli.UpsertKey,
+		Format:    jsontree.Format(cli.InputFormat),
+	}
+	if cli.Rejected != "" {
+		f, err := os.Create(cli.Rejected)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		opts.Rejected = f
+	}
+
+	stats, err := json2sql.Load(ctx, db, os.Stdin, opts)
+	if stats != nil {
+		log.Printf("loaded %d of %d records into %q, rejected %d", stats.Loaded, stats.Records, cli.Table, stats.Rejected)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
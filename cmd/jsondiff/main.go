@@ -0,0 +1,78 @@
+package jsondiff
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/diff"
+	"github.com/trichner/tb/pkg/jsontree/printer"
+)
+
+var cli struct {
+	Key         []string `help:"property identifying a record, repeat it for composite keys, names starting with '/' are JSON Pointers such as '/user/id'" required:""`
+	Old         string   `arg:"" help:"NDJSON file with the old records" type:"existingfile"`
+	New         string   `arg:"" help:"NDJSON file with the new records" type:"existingfile"`
+	InputFormat string   `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]), kong.Description("Compare two NDJSON exports record by record, matched by key. "+
+		"Prints one line per added, removed or changed record, changes as JSON Patch, and exits with 1 if there are any."))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	counts, err := compare(w, cli.Old, cli.New, jsontree.Format(cli.InputFormat), &diff.Options{Key: cli.Key})
+	w.Flush()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(counts) > 0 {
+		log.Printf("%d added, %d removed, %d changed", counts[diff.KindAdded], counts[diff.KindRemoved], counts[diff.KindChanged])
+		os.Exit(1)
+	}
+}
+
+// compare writes the changes between the files and returns how many there are of
+// each kind
+func compare(w io.Writer, oldPath, newPath string, format jsontree.Format, opts *diff.Options) (map[diff.Kind]int, error) {
+	before, err := os.Open(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	defer before.Close()
+
+	after, err := os.Open(newPath)
+	if err != nil {
+		return nil, err
+	}
+	defer after.Close()
+
+	beforeDec, err := jsontree.NewDecoderFormat(before, format)
+	if err != nil {
+		return nil, err
+	}
+	afterDec, err := jsontree.NewDecoderFormat(after, format)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[diff.Kind]int{}
+	err = diff.Records(beforeDec, afterDec, opts, func(c *diff.Change) error {
+		counts[c.Kind]++
+		if err := (&printer.Config{}).Fprint(w, c.Node()); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "\n")
+		return err
+	})
+	return counts, err
+}
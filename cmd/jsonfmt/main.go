@@ -0,0 +1,93 @@
+package jsonfmt
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/printer"
+)
+
+var cli struct {
+	Files         []string `arg:"" optional:"" help:"JSON or NDJSON files to read instead of stdin" type:"existingfile"`
+	Indent        string   `default:"  " help:"indentation per nesting level"`
+	Compact       bool     `short:"c" help:"print every record on a single line, e.g. to keep NDJSON"`
+	SortKeys      bool     `short:"S" help:"sort object keys rather than keeping their order"`
+	CompactArrays bool     `help:"print arrays of scalars on a single line"`
+	ASCII         bool     `short:"a" help:"escape all non-ASCII characters"`
+	Canonical     bool     `help:"print canonical JSON as of RFC 8785, implies --compact and --sort-keys"`
+	InputFormat   string   `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]), kong.Description("Reformat JSON or NDJSON, keeping key order and number text."))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	config := &printer.Config{
+		Indent:        cli.Indent,
+		SortKeys:      cli.SortKeys,
+		CompactArrays: cli.CompactArrays,
+		ASCII:         cli.ASCII,
+		Canonical:     cli.Canonical,
+	}
+	if cli.Compact {
+		config.Indent = ""
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if len(cli.Files) == 0 {
+		if err := format(w, os.Stdin, jsontree.Format(cli.InputFormat), config); err != nil {
+			w.Flush()
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, path := range cli.Files {
+		if err := formatFile(w, path, jsontree.Format(cli.InputFormat), config); err != nil {
+			w.Flush()
+			log.Fatalf("%s: %v", path, err)
+		}
+	}
+}
+
+func formatFile(w io.Writer, path string, in jsontree.Format, config *printer.Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return format(w, f, in, config)
+}
+
+func format(w io.Writer, r io.Reader, in jsontree.Format, config *printer.Config) error {
+	dec, err := jsontree.NewDecoderFormat(r, in)
+	if err != nil {
+		return err
+	}
+	for {
+		n, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := config.Fprint(w, n); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package jsonschema
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/printer"
+	"github.com/trichner/tb/pkg/jsontree/schema"
+)
+
+var cli struct {
+	Infer struct {
+		Files       []string `arg:"" optional:"" help:"JSON or NDJSON files to read instead of stdin" type:"existingfile"`
+		MaxEnum     int      `help:"maximum number of distinct strings listed as an enum, -1 to disable enums" default:"10"`
+		InputFormat string   `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+	} `cmd:"" help:"Infer a JSON Schema that all records match."`
+	Validate struct {
+		Schema      string   `help:"JSON Schema file to validate against" required:"" type:"existingfile"`
+		Files       []string `arg:"" optional:"" help:"JSON or NDJSON files to read instead of stdin" type:"existingfile"`
+		InputFormat string   `help:"syntax of the input: json, jsonc (comments and trailing commas), json5 or yaml" enum:"json,jsonc,json5,yaml" default:"json"`
+	} `cmd:"" help:"Report records that do not match a JSON Schema, exits with 1 if there are any."`
+}
+
+func Exec(ctx context.Context, args []string) {
+	k, err := kong.New(&cli, kong.Name(args[0]), kong.Description("Infer JSON Schema from NDJSON or validate records against it."))
+	if err != nil {
+		log.Fatal(err)
+	}
+	kctx, err := k.Parse(args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	switch kctx.Selected().Name {
+	case "infer":
+		err = infer(w, cli.Infer.Files, jsontree.Format(cli.Infer.InputFormat), cli.Infer.MaxEnum)
+	case "validate":
+		var invalid int
+		invalid, err = validate(w, cli.Validate.Schema, cli.Validate.Files, jsontree.Format(cli.Validate.InputFormat))
+		if err == nil && invalid > 0 {
+			w.Flush()
+			log.Printf("%d invalid records", invalid)
+			os.Exit(1)
+		}
+	default:
+		panic(kctx.Command())
+	}
+	if err != nil {
+		w.Flush()
+		log.Fatal(err)
+	}
+}
+
+func infer(w io.Writer, files []string, format jsontree.Format, maxEnum int) error {
+	i := schema.NewInferrer(&schema.InferOptions{MaxEnum: maxEnum})
+	err := eachRecord(files, format, func(_ string, _ int, n ast.Node) error {
+		i.Add(n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	config := &printer.Config{Indent: "  ", CompactArrays: true}
+	if err := config.Fprint(w, i.Schema()); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func validate(w io.Writer, schemaPath string, files []string, format jsontree.Format) (int, error) {
+	s, err := readSchema(schemaPath)
+	if err != nil {
+		return 0, err
+	}
+
+	invalid := 0
+	err = eachRecord(files, format, func(path string, record int, n ast.Node) error {
+		errs := s.Validate(n)
+		if len(errs) > 0 {
+			invalid++
+		}
+		for _, e := range errs {
+			if _, err := fmt.Fprintf(w, "%srecord %d: %v\n", path, record, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return invalid, err
+}
+
+func readSchema(path string) (*schema.Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	n, err := jsontree.NewDecoder(f).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema.Compile(n)
+}
+
+// eachRecord calls fn with every record of the files, or of stdin if there are none.
+// The prefix is the file name followed by a colon, or empty for stdin.
+func eachRecord(files []string, format jsontree.Format, fn func(prefix string, record int, n ast.Node) error) error {
+	if len(files) == 0 {
+		return decodeAll(os.Stdin, format, "", fn)
+	}
+
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = decodeAll(f, format, path+": ", fn)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeAll(r io.Reader, format jsontree.Format, prefix string, fn func(prefix string, record int, n ast.Node) error) error {
+	dec, err := jsontree.NewDecoderFormat(r, format)
+	if err != nil {
+		return err
+	}
+	for {
+		n, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s%w", prefix, err)
+		}
+		if err := fn(prefix, dec.Record(), n); err != nil {
+			return err
+		}
+	}
+}
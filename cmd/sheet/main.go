@@ -0,0 +1,200 @@
+package sheet
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/alecthomas/kong"
+	"github.com/trichner/tb/pkg/googleauth"
+	"github.com/trichner/tb/pkg/sheets"
+)
+
+var cli struct {
+	Ls struct {
+		SpreadsheetId  string `help:"ID of the spreadsheet" xor:"spreadsheet" required:""`
+		SpreadsheetUrl string `help:"complete URL to any sheet of the spreadsheet" xor:"spreadsheet" required:""`
+	} `cmd:"" help:"List the sheets of a spreadsheet."`
+	Clear struct {
+		SpreadsheetUrl string `help:"complete URL to the sheet" required:""`
+		Range          string `help:"range in A1 notation, e.g. 'A2:C', defaults to the whole sheet" optional:""`
+	} `cmd:"" help:"Clear the values of a sheet."`
+	Rename struct {
+		SpreadsheetUrl string `help:"complete URL to the sheet" required:""`
+		Title          string `help:"new title of the sheet" required:""`
+	} `cmd:"" help:"Rename a sheet."`
+	Copy struct {
+		SpreadsheetUrl string `help:"complete URL to the sheet" required:""`
+		Title          string `help:"title of the copy" required:""`
+	} `cmd:"" help:"Copy a sheet within its spreadsheet."`
+	Protect struct {
+		SpreadsheetUrl string   `help:"complete URL to the sheet" required:""`
+		Range          string   `help:"range in A1 notation, e.g. 'A1:C1', defaults to the whole sheet" optional:""`
+		Description    string   `help:"description of the protected range" optional:""`
+		WarningOnly    bool     `help:"only warn when editing instead of preventing it"`
+		Editor         []string `help:"email of a user allowed to edit, can be repeated" optional:""`
+	} `cmd:"" help:"Protect a sheet or a range of it from editing."`
+
+	googleauth.Flags `embed:""`
+}
+
+func Exec(ctx context.Context, args []string) {
+	k, err := kong.New(&cli, kong.Name(args[0]))
+	if err != nil {
+		log.Fatal(err)
+	}
+	kctx, err := k.Parse(args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx = googleauth.WithConfig(ctx, cli.Config())
+
+	switch kctx.Command() {
+	case "ls":
+		err = listSheets(ctx, cli.Ls.SpreadsheetId, cli.Ls.SpreadsheetUrl)
+	case "clear":
+		err = clearSheet(ctx, cli.Clear.SpreadsheetUrl, cli.Clear.Range)
+	case "rename":
+		err = renameSheet(ctx, cli.Rename.SpreadsheetUrl, cli.Rename.Title)
+	case "copy":
+		err = copySheet(ctx, cli.Copy.SpreadsheetUrl, cli.Copy.Title)
+	case "protect":
+		opts := &sheets.ProtectOptions{
+			Description: cli.Protect.Description,
+			WarningOnly: cli.Protect.WarningOnly,
+			Editors:     cli.Protect.Editor,
+		}
+		err = protectSheet(ctx, cli.Protect.SpreadsheetUrl, cli.Protect.Range, opts)
+	default:
+		panic(kctx.Command())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func listSheets(ctx context.Context, spreadsheetId, spreadsheetUrl string) error {
+	if spreadsheetUrl != "" {
+		id, _, err := sheets.ParseSpreadsheetUrl(spreadsheetUrl)
+		if err != nil {
+			return err
+		}
+		spreadsheetId = id
+	}
+
+	service, err := sheets.NewSheetService(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot create service: %w", err)
+	}
+
+	ss, err := service.GetSpreadSheet(spreadsheetId)
+	if err != nil {
+		return err
+	}
+
+	spreadsheet, err := ss.Get()
+	if err != nil {
+		return err
+	}
+
+	allSheets := spreadsheet.Sheets
+	sort.Slice(allSheets, func(i, j int) bool {
+		return allSheets[i].Index < allSheets[j].Index
+	})
+
+	fmt.Printf("ID: %s\n", spreadsheet.Id)
+	fmt.Printf("Index             Id     Title\n")
+	for _, s := range allSheets {
+		fmt.Printf("%3d     %12d \t %s\n", s.Index, s.Id, s.Title)
+	}
+	return nil
+}
+
+func clearSheet(ctx context.Context, spreadsheetUrl, a1 string) error {
+	sheet, _, err := openSheet(ctx, spreadsheetUrl)
+	if err != nil {
+		return err
+	}
+
+	if a1 == "" {
+		return sheet.Clear()
+	}
+	r, err := sheets.ParseA1(a1)
+	if err != nil {
+		return err
+	}
+	return sheet.ClearRange(r)
+}
+
+func renameSheet(ctx context.Context, spreadsheetUrl, title string) error {
+	sheet, _, err := openSheet(ctx, spreadsheetUrl)
+	if err != nil {
+		return err
+	}
+	return sheet.Rename(title)
+}
+
+func copySheet(ctx context.Context, spreadsheetUrl, title string) error {
+	sheet, spreadsheetId, err := openSheet(ctx, spreadsheetUrl)
+	if err != nil {
+		return err
+	}
+
+	dup, err := sheet.Duplicate(title)
+	if err != nil {
+		return err
+	}
+
+	info, err := dup.Get()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("https://docs.google.com/spreadsheets/d/%s/edit#gid=%d\n", spreadsheetId, info.Id)
+	return nil
+}
+
+func protectSheet(ctx context.Context, spreadsheetUrl, a1 string, opts *sheets.ProtectOptions) error {
+	sheet, _, err := openSheet(ctx, spreadsheetUrl)
+	if err != nil {
+		return err
+	}
+
+	r, err := parseRangeOrWholeSheet(a1)
+	if err != nil {
+		return err
+	}
+	return sheet.Protect(r, opts)
+}
+
+func openSheet(ctx context.Context, spreadsheetUrl string) (sheets.SheetOps, string, error) {
+	spreadsheetId, sheetId, err := sheets.ParseSpreadsheetUrl(spreadsheetUrl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	service, err := sheets.NewSheetService(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot create service: %w", err)
+	}
+
+	ss, err := service.GetSpreadSheet(spreadsheetId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sheet, err := ss.SheetById(sheetId)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot find sheet %d in %q: %w", sheetId, spreadsheetId, err)
+	}
+	return sheet, spreadsheetId, nil
+}
+
+func parseRangeOrWholeSheet(a1 string) (*sheets.Range, error) {
+	if a1 == "" {
+		return sheets.WholeSheet(""), nil
+	}
+	return sheets.ParseA1(a1)
+}
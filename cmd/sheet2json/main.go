@@ -12,6 +12,7 @@ import (
 	"github.com/alecthomas/kong"
 	"github.com/posener/complete/v2"
 	"github.com/posener/complete/v2/predict"
+	"github.com/trichner/tb/pkg/googleauth"
 	"github.com/trichner/tb/pkg/sheet2json"
 )
 
@@ -19,6 +20,8 @@ var cli struct {
 	SpreadsheetID  string `help:"spreadsheet ID"`
 	SheetID        int64  `help:"ID of the sheet within the spreadsheet"`
 	SpreadsheetUrl string `help:"complete URL to the spreadsheet"`
+
+	googleauth.Flags `embed:""`
 }
 
 func Completions() complete.Completer {
@@ -39,6 +42,8 @@ func Exec(ctx context.Context, args []string) {
 	_, err := parser.Parse(args[1:])
 	parser.FatalIfErrorf(err)
 
+	ctx = googleauth.WithConfig(ctx, cli.Config())
+
 	var spreadsheetId string
 	var sheetId int64 = -1
 	if cli.SpreadsheetUrl != "" {
@@ -1,6 +1,7 @@
 package sheet2json
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -16,5 +17,7 @@ func TestUrlToSpreadsheet(t *testing.T) {
 }
 
 func TestExec(t *testing.T) {
-	Exec([]string{"sheet2json", "--spreadsheet-id=https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit#gid=886605725"})
+	t.Skip("integration test")
+
+	Exec(context.Background(), []string{"sheet2json", "--spreadsheet-id=https://docs.google.com/spreadsheets/d/1dAN8MO9NDVPqVIoOxC9H_j4Ir5c1viQ97igGdXOyXsU/edit#gid=886605725"})
 }
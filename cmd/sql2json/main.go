@@ -2,134 +2,86 @@ package sql2json
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
-	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/alecthomas/kong"
-	"github.com/go-sql-driver/mysql"
+	"github.com/trichner/tb/pkg/sql2json"
 )
 
-type cli struct {
-	DbConnectionUri string `help:"database connection URI, e.g.: 'root@tcp(127.0.0.1:3306)/mydb'" required:"" env:"SQL2JSON_DB_CONNECTION_URI"`
+type queryCmd struct {
+	sql2json.ConnectionFlags `embed:""`
+
+	Query           string        `help:"a sql query fetching the results, named parameters are referenced as ':name'" xor:"query" required:"" env:"SQL2JSON_QUERY"`
+	QueryFile       *os.File      `help:"file containing the sql query" xor:"query" required:""`
+	Param           []string      `help:"bind parameter, 'name=value' for named and plain values for positional parameters, can be repeated" optional:"" short:"p"`
+	Batch           bool          `help:"read parameter sets as NDJSON from stdin and run the query once per line, objects hold named and arrays positional parameters"`
+	OmitNull        bool          `help:"leave out NULL columns instead of writing them as null" env:"SQL2JSON_OMIT_NULL"`
+	Timeout         time.Duration `help:"abort the query after this duration, e.g. '30s' or '5m', 0 waits forever" default:"0" env:"SQL2JSON_TIMEOUT"`
+	MaxRows         int           `help:"stop after writing this many rows, 0 is unlimited" default:"0" env:"SQL2JSON_MAX_ROWS"`
+	Quiet           bool          `help:"do not report progress on stderr" short:"q"`
+	Format          string        `help:"output format, rows of result sets after the first are tagged with '_resultSet' in JSON formats" enum:"ndjson,json-array,csv,tsv,table" default:"ndjson" short:"f" env:"SQL2JSON_FORMAT"`
+	LabelResultSets bool          `help:"tag the rows of every result set with '_resultSet', including the first" env:"SQL2JSON_LABEL_RESULT_SETS"`
+}
 
-	DbUser     string `help:"user for the database" optional:"" env:"SQL2JSON_DB_USER"`
-	DbPassword string `help:"password for the database" optional:"" env:"SQL2JSON_DB_PASSWORD"`
-	DbName     string `help:"name of the database" optional:"" env:"SQL2JSON_DB_NAME"`
-	Query      string `help:"a sql query fetching the results" required:"" env:"SQL2JSON_QUERY"`
+type cli struct {
+	Query queryCmd `cmd:"" default:"withargs" help:"Run a query and write the rows, this is the default command."`
+
+	Profile struct {
+		Add struct {
+			Name            string `arg:"" help:"name of the profile"`
+			DbConnectionUri string `help:"database connection URI without password" required:""`
+			DbUser          string `help:"user for the database" optional:""`
+			DbName          string `help:"name of the database" optional:""`
+			PasswordStdin   bool   `help:"read the password from stdin instead of prompting for it"`
+		} `cmd:"" help:"Add or replace a profile, the password is stored in the keyring."`
+		List   struct{} `cmd:"" help:"List all profiles."`
+		Remove struct {
+			Name string `arg:"" help:"name of the profile"`
+		} `cmd:"" help:"Remove a profile and its password."`
+		Test struct {
+			Name string `arg:"" help:"name of the profile"`
+		} `cmd:"" help:"Connect to the database of a profile."`
+	} `cmd:"" help:"Manage named connection profiles."`
 }
 
 func Exec(ctx context.Context, args []string) {
-	// kong expects only actual arguments and not the program itself
-	args = args[1:]
-
 	var flags cli
 
-	k, err := kong.New(&flags)
+	k, err := kong.New(&flags, kong.Name(args[0]))
 	if err != nil {
 		log.Fatalf("cannot parse arguments: %v", err)
 	}
-	_, err = k.Parse(args)
-	if err != nil {
-		log.Fatalf("cannot parse arguments: %v", err)
-	}
-
-	dsn, err := parseDsnConfig(&flags)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	log.Printf("connecting to database")
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	pingErr := db.Ping()
-	if pingErr != nil {
-		log.Fatal(pingErr)
-	}
-
-	encoder := json.NewEncoder(os.Stdout)
-	err = execQuery(db, flags.Query, encoder)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func execQuery(db *sql.DB, query string, writer *json.Encoder) error {
-	var err error
-
-	rows, err := db.Query(query)
-	if err != nil {
-		return fmt.Errorf("cannot execute query: %w", err)
-	}
-	defer rows.Close()
 
-	header, err := rows.Columns()
+	// kong expects only actual arguments and not the program itself
+	kctx, err := k.Parse(args[1:])
 	if err != nil {
-		return fmt.Errorf("cannot get result column names: %w", err)
-	}
-
-	length := len(header)
-	for rows.Next() {
-		pointers := make([]interface{}, length)
-		row := make([]*string, length)
-
-		for i := range pointers {
-			pointers[i] = &row[i]
-		}
-
-		if err := rows.Scan(pointers...); err != nil {
-			return fmt.Errorf("cannot scan row: %w", err)
-		}
-
-		if err := writer.Encode(mapRow(header, row)); err != nil {
-			return fmt.Errorf("cannot write row: %w", err)
-		}
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("cannot scan rows: %w", err)
+		log.Fatalf("cannot parse arguments: %v", err)
 	}
 
-	return nil
-}
-
-func mapRow(headers []string, r []*string) map[string]string {
-	mapped := make(map[string]string)
-
-	for i := range r {
-		v := r[i]
-		if v != nil {
-			mapped[headers[i]] = *v
+	switch kctx.Command() {
+	case "query":
+		err = runQuery(ctx, &flags.Query)
+	case "profile add <name>":
+		add := flags.Profile.Add
+		profile := &sql2json.Profile{
+			Name:          add.Name,
+			ConnectionUri: add.DbConnectionUri,
+			User:          add.DbUser,
+			Database:      add.DbName,
 		}
+		err = addProfile(profile, add.PasswordStdin)
+	case "profile list":
+		err = listProfiles()
+	case "profile remove <name>":
+		err = removeProfile(flags.Profile.Remove.Name)
+	case "profile test <name>":
+		err = testProfile(ctx, flags.Profile.Test.Name)
+	default:
+		panic(kctx.Command())
 	}
-	return mapped
-}
-
-func parseDsnConfig(args *cli) (string, error) {
-	cfg, err := mysql.ParseDSN(args.DbConnectionUri)
 	if err != nil {
-		return "", fmt.Errorf("invalid mysql connection URI: %w", err)
-	}
-
-	dbName := args.DbName
-	if dbName != "" {
-		cfg.DBName = dbName
-	}
-
-	dbUser := args.DbUser
-	if dbUser != "" {
-		cfg.User = dbUser
-	}
-
-	dbPassword := args.DbPassword
-	if dbPassword != "" {
-		cfg.Passwd = dbPassword
+		log.Fatal(err)
 	}
-
-	return cfg.FormatDSN(), nil
 }
@@ -0,0 +1,106 @@
+package sql2json
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+func openProfileStore() (*sql2json.ProfileStore, error) {
+	path, err := sql2json.DefaultProfilesPath()
+	if err != nil {
+		return nil, err
+	}
+	return sql2json.NewProfileStore(path), nil
+}
+
+func addProfile(profile *sql2json.Profile, passwordStdin bool) error {
+	store, err := openProfileStore()
+	if err != nil {
+		return err
+	}
+
+	password, err := readPassword(passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Put(profile, password); err != nil {
+		return err
+	}
+	log.Printf("added profile %q", profile.Name)
+	return nil
+}
+
+func readPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("cannot read password from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	p := promptui.Prompt{
+		Label: "Password (empty for none)",
+		Mask:  '*',
+	}
+	return p.Run()
+}
+
+func listProfiles() error {
+	store, err := openProfileStore()
+	if err != nil {
+		return err
+	}
+
+	profiles, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCONNECTION URI\tUSER\tDATABASE")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Name, p.ConnectionUri, p.User, p.Database)
+	}
+	return w.Flush()
+}
+
+func removeProfile(name string) error {
+	store, err := openProfileStore()
+	if err != nil {
+		return err
+	}
+	return store.Remove(name)
+}
+
+func testProfile(ctx context.Context, name string) error {
+	store, err := openProfileStore()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := store.Config(name)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql2json.Open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("profile %q: %w", name, err)
+	}
+	defer db.Close()
+
+	fmt.Printf("profile %q: connected\n", name)
+	return nil
+}
@@ -0,0 +1,80 @@
+package sql2json
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+func runQuery(ctx context.Context, flags *queryCmd) error {
+	query := flags.Query
+	if flags.QueryFile != nil {
+		b, err := io.ReadAll(flags.QueryFile)
+		flags.QueryFile.Close()
+		if err != nil {
+			return fmt.Errorf("cannot read query: %w", err)
+		}
+		query = string(b)
+	}
+
+	if flags.Batch && len(flags.Param) > 0 {
+		return fmt.Errorf("cannot combine --param with --batch")
+	}
+
+	cfg, err := flags.Config()
+	if err != nil {
+		return err
+	}
+
+	// cancel the running query on Ctrl-C rather than leaving it behind on the server
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if flags.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.Timeout)
+		defer cancel()
+	}
+
+	log.Printf("connecting to database")
+	db, err := sql2json.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	writer, err := sql2json.NewRowWriter(sql2json.Format(flags.Format), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	opts := &sql2json.Options{OmitNull: flags.OmitNull, MaxRows: flags.MaxRows, LabelResultSets: flags.LabelResultSets}
+	if !flags.Quiet {
+		opts.Progress = func(rows int) {
+			log.Printf("%d rows written", rows)
+		}
+	}
+	if flags.Batch {
+		err = sql2json.ExecBatch(ctx, db, query, os.Stdin, writer, opts)
+	} else {
+		params := &sql2json.Params{}
+		for _, p := range flags.Param {
+			params.ParseParam(p)
+		}
+		err = sql2json.ExecQuery(ctx, db, query, params, writer, opts)
+	}
+	if errors.Is(err, sql2json.ErrMaxRows) {
+		log.Printf("stopped after %d rows", flags.MaxRows)
+	} else if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/trichner/tb/pkg/googleauth"
+	"github.com/trichner/tb/pkg/syncjob"
+)
+
+var cli struct {
+	Job []string `arg:"" help:"job files in YAML, run in order" type:"existingfile"`
+
+	googleauth.Flags `embed:""`
+}
+
+func Exec(ctx context.Context, args []string) {
+	parser := kong.Must(&cli, kong.Name(args[0]), kong.Description("Copy rows from a source to a destination as described in a job file."))
+	_, err := parser.Parse(args[1:])
+	parser.FatalIfErrorf(err)
+
+	ctx = googleauth.WithConfig(ctx, cli.Config())
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, path := range cli.Job {
+		job, err := syncjob.LoadJob(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("running %s", path)
+		if err := syncjob.Run(ctx, job); err != nil {
+			log.Fatalf("job %s failed: %v", path, err)
+		}
+	}
+}
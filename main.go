@@ -6,18 +6,26 @@ import (
 	"os"
 	"time"
 
+	"github.com/trichner/tb/cmd/auth"
 	"github.com/trichner/tb/cmd/tags"
 
 	"github.com/lmittmann/tint"
 
 	"github.com/trichner/tb/cmd/csv2json"
+	"github.com/trichner/tb/cmd/sheet"
 	"github.com/trichner/tb/cmd/sheet2json"
+	"github.com/trichner/tb/cmd/sync"
 	"github.com/trichner/tb/pkg/cmdreg"
 
 	"github.com/trichner/tb/cmd/sql2json"
 
 	"github.com/trichner/tb/cmd/jiracli"
+	"github.com/trichner/tb/cmd/jq"
 	"github.com/trichner/tb/cmd/json2sheet"
+	"github.com/trichner/tb/cmd/json2sql"
+	"github.com/trichner/tb/cmd/jsondiff"
+	"github.com/trichner/tb/cmd/jsonfmt"
+	"github.com/trichner/tb/cmd/jsonschema"
 	"github.com/trichner/tb/cmd/kraki"
 )
 
@@ -30,12 +38,20 @@ func main() {
 
 	r := cmdreg.New(cmdreg.WithProgramName("tb"))
 
+	r.RegisterFunc("auth", auth.Exec)
 	r.RegisterFunc("csv2json", csv2json.Exec)
 	r.RegisterFunc("jiracli", jiracli.Exec)
+	r.RegisterFunc("jq", jq.Exec)
+	r.RegisterFunc("jsondiff", jsondiff.Exec)
+	r.RegisterFunc("jsonfmt", jsonfmt.Exec)
+	r.RegisterFunc("jsonschema", jsonschema.Exec)
 	r.RegisterFunc("json2sheet", json2sheet.Exec)
+	r.RegisterFunc("json2sql", json2sql.Exec)
 	r.RegisterFunc("kraki", kraki.Exec)
+	r.RegisterFunc("sheet", sheet.Exec)
 	r.RegisterFunc("sheet2json", sheet2json.Exec, cmdreg.WithCompletion(sheet2json.Completions()))
 	r.RegisterFunc("sql2json", sql2json.Exec)
+	r.RegisterFunc("sync", sync.Exec)
 	r.RegisterFunc("tag", tags.Exec)
 
 	r.RegisterFunc("help", help(r))
@@ -0,0 +1,256 @@
+// Package googleauth selects and sets up the credentials used to talk to Google APIs.
+package googleauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/trichner/oauthflows"
+	"github.com/trichner/tb/pkg/oauth2tokenstore"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	keyringItemServiceName = "toolbox googleapis.com"
+
+	defaultClientSecretFile = "client_secret.json"
+
+	envMethod      = "TB_GOOGLE_AUTH"
+	envCredentials = "TB_GOOGLE_CREDENTIALS"
+	envSubject     = "TB_GOOGLE_SUBJECT"
+)
+
+type Method string
+
+const (
+	// MethodBuiltin uses the OAuth client embedded in tb with a browser flow
+	MethodBuiltin = Method("builtin")
+	// MethodClientSecret uses an OAuth client from a 'client_secret.json' with a browser flow
+	MethodClientSecret = Method("client-secret")
+	// MethodServiceAccount uses a service account key, optionally impersonating a user
+	MethodServiceAccount = Method("service-account")
+	// MethodADC uses the application default credentials, e.g. from 'gcloud auth application-default login'
+	MethodADC = Method("adc")
+)
+
+type Config struct {
+	Method Method
+
+	// CredentialsFile is the 'client_secret.json' or service account key, depending on the method
+	CredentialsFile string
+
+	// Subject is the user to impersonate with domain-wide delegation, only for service accounts
+	Subject string
+}
+
+// Flags can be embedded into a kong CLI to select the authentication method
+type Flags struct {
+	GoogleAuth        string `help:"authentication for Google APIs" enum:"builtin,client-secret,service-account,adc" default:"builtin" env:"TB_GOOGLE_AUTH"`
+	GoogleCredentials string `help:"path to the client_secret.json or service account key" optional:"" env:"TB_GOOGLE_CREDENTIALS"`
+	GoogleSubject     string `help:"user to impersonate with a service account, requires domain-wide delegation" optional:"" env:"TB_GOOGLE_SUBJECT"`
+}
+
+func (f *Flags) Config() *Config {
+	return &Config{
+		Method:          Method(f.GoogleAuth),
+		CredentialsFile: f.GoogleCredentials,
+		Subject:         f.GoogleSubject,
+	}
+}
+
+// ConfigFromEnv reads the configuration from the same environment variables as the Flags
+func ConfigFromEnv() *Config {
+	method := Method(os.Getenv(envMethod))
+	if method == "" {
+		method = MethodBuiltin
+	}
+	return &Config{
+		Method:          method,
+		CredentialsFile: os.Getenv(envCredentials),
+		Subject:         os.Getenv(envSubject),
+	}
+}
+
+type contextKey struct{}
+
+// WithConfig returns a context carrying the configuration, this is how commands pass their
+// flags down to the services
+func WithConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the configuration set by WithConfig and falls back to ConfigFromEnv
+func FromContext(ctx context.Context) *Config {
+	cfg, ok := ctx.Value(contextKey{}).(*Config)
+	if !ok || cfg == nil {
+		return ConfigFromEnv()
+	}
+	return cfg
+}
+
+func (c *Config) validate() error {
+	switch c.Method {
+	case MethodBuiltin, MethodADC:
+		if c.CredentialsFile != "" {
+			return fmt.Errorf("credentials file %q is not used by %q authentication", c.CredentialsFile, c.Method)
+		}
+	case MethodClientSecret:
+	case MethodServiceAccount:
+		if c.CredentialsFile == "" {
+			return fmt.Errorf("%q authentication requires a credentials file", c.Method)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown authentication method %q", c.Method)
+	}
+
+	if c.Subject != "" {
+		return fmt.Errorf("subject %q is only supported for %q authentication", c.Subject, MethodServiceAccount)
+	}
+	return nil
+}
+
+// NewClient returns an HTTP client authenticating all requests with the configured credentials
+func NewClient(ctx context.Context, cfg *Config, scopes ...string) (*http.Client, error) {
+	creds, err := newCredentials(ctx, cfg, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, creds.source), nil
+}
+
+type credentials struct {
+	source oauth2.TokenSource
+
+	// description of where the credentials come from
+	origin string
+
+	// email is the identity if known upfront, e.g. of a service account
+	email string
+}
+
+func newCredentials(ctx context.Context, cfg *Config, scopes []string) (*credentials, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Method {
+	case MethodBuiltin:
+		return newBrowserFlowCredentials(ctx, builtinConfig(scopes), "built-in OAuth client")
+	case MethodClientSecret:
+		return newClientSecretCredentials(ctx, cfg.CredentialsFile, scopes)
+	case MethodServiceAccount:
+		return newServiceAccountCredentials(ctx, cfg.CredentialsFile, cfg.Subject, scopes)
+	case MethodADC:
+		return newDefaultCredentials(ctx, scopes)
+	}
+	panic(cfg.Method)
+}
+
+func builtinConfig(scopes []string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     deobfuscate("11f54bda98e094e03def692dffdfa93fb0773efcf9258ea5f28c4a3e9825a102d2c0628a3a7205c43f719c757ae2dfcbae35eddd2e5c3f276c83ed10259203240be3afa1ce519e"),
+		ClientSecret: deobfuscate("74a04fb1f8fbc9b443ea2345e2fbad3198607ed6f22cdad9"),
+		Endpoint:     google.Endpoint,
+		Scopes:       scopes,
+	}
+}
+
+func newClientSecretCredentials(ctx context.Context, file string, scopes []string) (*credentials, error) {
+	if file == "" {
+		file = defaultClientSecretFile
+	}
+
+	slurp, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", file, err)
+	}
+
+	conf, err := google.ConfigFromJSON(slurp, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse config %s: %w", file, err)
+	}
+
+	return newBrowserFlowCredentials(ctx, conf, file)
+}
+
+func newBrowserFlowCredentials(ctx context.Context, conf *oauth2.Config, origin string) (*credentials, error) {
+	ts, err := oauthflows.NewBrowserFlowTokenSource(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	store := oauth2tokenstore.NewKeyringTokenStore(keyringItemServiceName)
+	ts, err = oauthflows.NewCachedTokenSource(ctx, deriveCacheName(conf), store, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credentials{
+		source: oauth2.ReuseTokenSource(nil, ts),
+		origin: origin,
+	}, nil
+}
+
+func newServiceAccountCredentials(ctx context.Context, file, subject string, scopes []string) (*credentials, error) {
+	slurp, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", file, err)
+	}
+
+	conf, err := google.JWTConfigFromJSON(slurp, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse service account key %s: %w", file, err)
+	}
+	conf.Subject = subject
+
+	email := conf.Email
+	if subject != "" {
+		email = subject
+	}
+
+	return &credentials{
+		source: conf.TokenSource(ctx),
+		origin: file,
+		email:  email,
+	}, nil
+}
+
+func newDefaultCredentials(ctx context.Context, scopes []string) (*credentials, error) {
+	creds, err := google.FindDefaultCredentials(ctx, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find application default credentials: %w", err)
+	}
+
+	// only set for service account keys, the identity of user credentials is only known from the token
+	var key struct {
+		ClientEmail string `json:"client_email"`
+	}
+	if len(creds.JSON) > 0 {
+		_ = json.Unmarshal(creds.JSON, &key)
+	}
+
+	return &credentials{
+		source: creds.TokenSource,
+		origin: "application default credentials",
+		email:  key.ClientEmail,
+	}, nil
+}
+
+// deriveCacheName matches the cache name of oauthflows.NewClient, this keeps tokens cached by
+// previous versions valid
+func deriveCacheName(config *oauth2.Config) string {
+	hash := sha256.New()
+	hash.Write([]byte(config.ClientID))
+	hash.Write([]byte(config.ClientSecret))
+	hash.Write([]byte(strings.Join(config.Scopes, " ")))
+	hashed := hash.Sum(nil)[:16]
+	return hex.EncodeToString(hashed)
+}
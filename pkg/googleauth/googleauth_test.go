@@ -0,0 +1,133 @@
+package googleauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(envMethod, "")
+	t.Setenv(envCredentials, "")
+	t.Setenv(envSubject, "")
+	assert.Equal(t, &Config{Method: MethodBuiltin}, ConfigFromEnv())
+
+	t.Setenv(envMethod, "service-account")
+	t.Setenv(envCredentials, "key.json")
+	t.Setenv(envSubject, "octo@example.com")
+	assert.Equal(t, &Config{Method: MethodServiceAccount, CredentialsFile: "key.json", Subject: "octo@example.com"}, ConfigFromEnv())
+}
+
+func TestFromContext(t *testing.T) {
+	cfg := &Config{Method: MethodADC}
+	ctx := WithConfig(context.Background(), cfg)
+	assert.Same(t, cfg, FromContext(ctx))
+}
+
+func TestConfig_validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		expectedErr string
+	}{
+		{name: "builtin", cfg: Config{Method: MethodBuiltin}},
+		{name: "adc", cfg: Config{Method: MethodADC}},
+		{name: "client secret default file", cfg: Config{Method: MethodClientSecret}},
+		{name: "service account with subject", cfg: Config{Method: MethodServiceAccount, CredentialsFile: "key.json", Subject: "a@example.com"}},
+		{
+			name:        "service account without key",
+			cfg:         Config{Method: MethodServiceAccount},
+			expectedErr: `"service-account" authentication requires a credentials file`,
+		},
+		{
+			name:        "subject without service account",
+			cfg:         Config{Method: MethodClientSecret, Subject: "a@example.com"},
+			expectedErr: `subject "a@example.com" is only supported for "service-account" authentication`,
+		},
+		{
+			name:        "builtin with file",
+			cfg:         Config{Method: MethodBuiltin, CredentialsFile: "client_secret.json"},
+			expectedErr: `credentials file "client_secret.json" is not used by "builtin" authentication`,
+		},
+		{
+			name:        "unknown",
+			cfg:         Config{Method: "magic"},
+			expectedErr: `unknown authentication method "magic"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.cfg.validate()
+			if test.expectedErr != "" {
+				assert.EqualError(t, err, test.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDescribe_ServiceAccount(t *testing.T) {
+	var assertion string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assertion = r.FormValue("assertion")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"t0ken","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/tokeninfo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "t0ken", r.URL.Query().Get("access_token"))
+		_, _ = w.Write([]byte(`{"scope":"https://www.googleapis.com/auth/spreadsheets","expires_in":"3599"}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	original := tokenInfoEndpoint
+	tokenInfoEndpoint = srv.URL + "/tokeninfo"
+	t.Cleanup(func() {
+		tokenInfoEndpoint = original
+	})
+
+	keyFile := writeServiceAccountKey(t, srv.URL+"/token")
+	cfg := &Config{Method: MethodServiceAccount, CredentialsFile: keyFile, Subject: "octo@example.com"}
+
+	status, err := Describe(context.Background(), cfg, "https://www.googleapis.com/auth/spreadsheets")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, assertion)
+	assert.Equal(t, MethodServiceAccount, status.Method)
+	assert.Equal(t, keyFile, status.Origin)
+	assert.Equal(t, "octo@example.com", status.Email)
+	assert.Equal(t, "octo@example.com", status.Subject)
+	assert.Equal(t, []string{"https://www.googleapis.com/auth/spreadsheets"}, status.GrantedScopes)
+}
+
+func writeServiceAccountKey(t *testing.T, tokenUri string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	sa, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "robot@project.iam.gserviceaccount.com",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenUri,
+	})
+	assert.NoError(t, err)
+
+	p := filepath.Join(t.TempDir(), "key.json")
+	assert.NoError(t, os.WriteFile(p, sa, 0o600))
+	return p
+}
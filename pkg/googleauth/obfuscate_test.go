@@ -1,4 +1,4 @@
-package sheets
+package googleauth
 
 import (
 	"fmt"
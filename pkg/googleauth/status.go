@@ -0,0 +1,98 @@
+package googleauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenInfoEndpoint is replaced in tests
+var tokenInfoEndpoint = "https://oauth2.googleapis.com/tokeninfo"
+
+type Status struct {
+	Method Method
+	Origin string
+
+	// Email is the identity in use, empty if it cannot be determined
+	Email   string
+	Subject string
+
+	// Scopes are the requested scopes while GrantedScopes are the ones the token actually carries
+	Scopes        []string
+	GrantedScopes []string
+	Expiry        time.Time
+}
+
+// Describe obtains a token with the given configuration and reports the identity and scopes it
+// carries. Depending on the method, this may start a browser flow.
+func Describe(ctx context.Context, cfg *Config, scopes ...string) (*Status, error) {
+	creds, err := newCredentials(ctx, cfg, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := creds.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain token from %s: %w", creds.origin, err)
+	}
+
+	info, err := fetchTokenInfo(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	email := creds.email
+	if info.Email != "" {
+		email = info.Email
+	}
+
+	expiry := token.Expiry
+	if seconds, err := strconv.ParseInt(info.ExpiresIn, 10, 64); err == nil {
+		expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+
+	return &Status{
+		Method:        cfg.Method,
+		Origin:        creds.origin,
+		Email:         email,
+		Subject:       cfg.Subject,
+		Scopes:        scopes,
+		GrantedScopes: strings.Fields(info.Scope),
+		Expiry:        expiry,
+	}, nil
+}
+
+type tokenInfo struct {
+	Email     string `json:"email"`
+	Scope     string `json:"scope"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+func fetchTokenInfo(ctx context.Context, accessToken string) (*tokenInfo, error) {
+	u := tokenInfoEndpoint + "?access_token=" + url.QueryEscape(accessToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect access token at %q: %w", tokenInfoEndpoint, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to introspect access token at %q: %s", tokenInfoEndpoint, res.Status)
+	}
+
+	var info tokenInfo
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to parse access token introspection: %w", err)
+	}
+	return &info, nil
+}
@@ -1,6 +1,7 @@
 package jira
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/trichner/tb/pkg/jira/credentials"
@@ -18,6 +19,21 @@ type Issue struct {
 	Labels      []string     `json:"labels"`
 	StoryPoints *int         `json:"storyPoints"`
 	IssueLinks  []*IssueLink `json:"issueLinks"`
+
+	// Fields holds the raw values of the fields selected with SearchOptions.Fields
+	Fields map[string]json.RawMessage `json:"fields,omitempty"`
+
+	// Changelog is only set if expanded with SearchOptions.Expand
+	Changelog []*ChangelogEntry `json:"changelog,omitempty"`
+}
+
+// ChangelogEntry is a change of a single field of an issue
+type ChangelogEntry struct {
+	Created string  `json:"created"`
+	Author  string  `json:"author"`
+	Field   string  `json:"field"`
+	From    *string `json:"from"`
+	To      *string `json:"to"`
 }
 
 type Version struct {
@@ -100,7 +116,7 @@ func (j *JiraService) GetByKey(key string) (*Issue, error) {
 		return &issueVos[0], nil
 	}
 
-	return nil, fmt.Errorf("found multiple issues for the same key: " + key)
+	return nil, fmt.Errorf("found multiple issues for the same key %q", key)
 }
 
 func (j *JiraService) GetVersion(projectId, version string) (*Version, error) {
@@ -118,14 +134,6 @@ func (j *JiraService) GetVersion(projectId, version string) (*Version, error) {
 	return v, nil
 }
 
-func (j *JiraService) SearchByQuery(query string) ([]Issue, error) {
-	issues, _, err := j.client.Issue.Search(query, nil)
-	if err != nil {
-		return nil, err
-	}
-	return mapJiraListToVoList(issues), nil
-}
-
 func findVersion(version string, versions []gojira.Version) *gojira.Version {
 	for _, v := range versions {
 		if v.Name == version {
@@ -0,0 +1,161 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	gojira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// pageSize is the number of issues requested at once, Jira may cap it lower
+const pageSize = 100
+
+// issueFields are the fields Issue is mapped from, customfield_10951 is the squad and
+// customfield_10004 the story points
+var issueFields = []string{
+	"summary", "issuetype", "assignee", "fixVersions", "status", "labels", "issuelinks",
+	"customfield_10951", "customfield_10004",
+}
+
+type SearchOptions struct {
+	// Limit is the maximum number of issues, all matching issues are returned if it
+	// is zero
+	Limit int
+
+	// Fields are fetched in addition to the ones of Issue and returned as they are
+	// in Issue.Fields, e.g. "created" or "customfield_10010"
+	Fields []string
+
+	// Expand lists the sections to expand, "changelog" fills Issue.Changelog
+	Expand []string
+}
+
+// SearchByQuery calls fn with every issue matching the JQL query. Issues are fetched
+// page by page as fn consumes them.
+func (j *JiraService) SearchByQuery(query string, opts *SearchOptions, fn func(*Issue) error) error {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+
+	count := 0
+	for {
+		size := pageSize
+		if opts.Limit > 0 {
+			size = min(size, opts.Limit-count)
+		}
+
+		page, err := j.searchPage(query, count, size, opts)
+		if err != nil {
+			return fmt.Errorf("cannot search issues %d to %d: %w", count+1, count+size, err)
+		}
+
+		for _, raw := range page.Issues {
+			issue, err := mapSearchResult(raw, opts.Fields)
+			if err != nil {
+				return err
+			}
+			if err := fn(issue); err != nil {
+				return err
+			}
+		}
+
+		count += len(page.Issues)
+		if len(page.Issues) == 0 || count >= page.Total || (opts.Limit > 0 && count >= opts.Limit) {
+			return nil
+		}
+	}
+}
+
+// searchPage is a page of search results, the issues are kept raw to pick the
+// selected fields from them
+type searchPage struct {
+	Issues []json.RawMessage `json:"issues"`
+	Total  int               `json:"total"`
+}
+
+func (j *JiraService) searchPage(query string, startAt, maxResults int, opts *SearchOptions) (*searchPage, error) {
+	params := url.Values{}
+	params.Set("jql", query)
+	params.Set("startAt", strconv.Itoa(startAt))
+	params.Set("maxResults", strconv.Itoa(maxResults))
+	params.Set("fields", strings.Join(append(slices.Clone(issueFields), opts.Fields...), ","))
+	if len(opts.Expand) > 0 {
+		params.Set("expand", strings.Join(opts.Expand, ","))
+	}
+
+	req, err := j.client.NewRequest(http.MethodGet, "rest/api/2/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &searchPage{}
+	resp, err := j.client.Do(req, page)
+	if err != nil {
+		return nil, gojira.NewJiraError(resp, err)
+	}
+	return page, nil
+}
+
+func mapSearchResult(raw json.RawMessage, fields []string) (*Issue, error) {
+	var issue gojira.Issue
+	if err := json.Unmarshal(raw, &issue); err != nil {
+		return nil, fmt.Errorf("cannot parse issue: %w", err)
+	}
+	if issue.Fields == nil {
+		issue.Fields = &gojira.IssueFields{}
+	}
+
+	vo := mapJiraToVo(issue)
+	if issue.Changelog != nil {
+		vo.Changelog = mapChangelog(issue.Changelog)
+	}
+
+	if len(fields) > 0 {
+		var rawIssue struct {
+			Fields map[string]json.RawMessage `json:"fields"`
+		}
+		if err := json.Unmarshal(raw, &rawIssue); err != nil {
+			return nil, fmt.Errorf("cannot parse fields of issue %s: %w", issue.Key, err)
+		}
+
+		vo.Fields = map[string]json.RawMessage{}
+		for _, name := range fields {
+			v, ok := rawIssue.Fields[name]
+			if !ok {
+				v = json.RawMessage("null")
+			}
+			vo.Fields[name] = v
+		}
+	}
+	return &vo, nil
+}
+
+// mapChangelog flattens the history of an issue into one entry per changed field,
+// oldest first as Jira lists them
+func mapChangelog(changelog *gojira.Changelog) []*ChangelogEntry {
+	entries := []*ChangelogEntry{}
+	for _, h := range changelog.Histories {
+		for _, item := range h.Items {
+			entries = append(entries, &ChangelogEntry{
+				Created: h.Created,
+				Author:  h.Author.DisplayName,
+				Field:   item.Field,
+				From:    optional(item.FromString),
+				To:      optional(item.ToString),
+			})
+		}
+	}
+	return entries
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,129 @@
+package jira
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchServer serves total issues from the search endpoint and records the query
+// parameters of every request
+func searchServer(t *testing.T, total int, requests *[]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/api/2/search", r.URL.Path)
+		q := r.URL.Query()
+		*requests = append(*requests, map[string]string{
+			"startAt":    q.Get("startAt"),
+			"maxResults": q.Get("maxResults"),
+			"expand":     q.Get("expand"),
+		})
+
+		startAt, _ := strconv.Atoi(q.Get("startAt"))
+		maxResults, _ := strconv.Atoi(q.Get("maxResults"))
+		changelog := ""
+		if q.Get("expand") == "changelog" {
+			changelog = `,"changelog":{"histories":[{"author":{"displayName":"Alice"},"created":"2024-02-01","items":[{"field":"status","fromString":"Open","toString":"Done"}]}]}`
+		}
+
+		// pages hold at most two issues, fewer than asked for as Jira may do too
+		var issues []string
+		for i := startAt; i < min(total, startAt+min(maxResults, 2)); i++ {
+			issues = append(issues, fmt.Sprintf(`{"key":"ARC-%d","fields":{"summary":"issue %d","created":"2024-01-01T10:00:00.000+0000","status":{"name":"Open"}}%s}`, i, i, changelog))
+		}
+		fmt.Fprintf(w, `{"startAt":%d,"maxResults":%d,"total":%d,"issues":[%s]}`, startAt, maxResults, total, strings.Join(issues, ","))
+	}))
+}
+
+func TestSearchByQuery(t *testing.T) {
+	var requests []map[string]string
+	server := searchServer(t, 5, &requests)
+	defer server.Close()
+
+	svc, err := NewJiraService(server.URL, "user", "token")
+	assert.NoError(t, err)
+
+	var keys []string
+	err = svc.SearchByQuery("project = ARC", nil, func(issue *Issue) error {
+		keys = append(keys, issue.Key)
+		assert.Nil(t, issue.Fields)
+		assert.Nil(t, issue.Changelog)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ARC-0", "ARC-1", "ARC-2", "ARC-3", "ARC-4"}, keys)
+	assert.Equal(t, []string{"0", "2", "4"}, []string{requests[0]["startAt"], requests[1]["startAt"], requests[2]["startAt"]})
+	assert.Len(t, requests, 3)
+}
+
+func TestSearchByQuery_Limit(t *testing.T) {
+	var requests []map[string]string
+	server := searchServer(t, 5, &requests)
+	defer server.Close()
+
+	svc, err := NewJiraService(server.URL, "user", "token")
+	assert.NoError(t, err)
+
+	var keys []string
+	err = svc.SearchByQuery("project = ARC", &SearchOptions{Limit: 3}, func(issue *Issue) error {
+		keys = append(keys, issue.Key)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ARC-0", "ARC-1", "ARC-2"}, keys)
+	assert.Equal(t, []map[string]string{
+		{"startAt": "0", "maxResults": "3", "expand": ""},
+		{"startAt": "2", "maxResults": "1", "expand": ""},
+	}, requests)
+}
+
+func TestSearchByQuery_FieldsAndChangelog(t *testing.T) {
+	var requests []map[string]string
+	server := searchServer(t, 1, &requests)
+	defer server.Close()
+
+	svc, err := NewJiraService(server.URL, "user", "token")
+	assert.NoError(t, err)
+
+	opts := &SearchOptions{Fields: []string{"created", "priority"}, Expand: []string{"changelog"}}
+	var issues []*Issue
+	err = svc.SearchByQuery("project = ARC", opts, func(issue *Issue) error {
+		issues = append(issues, issue)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "changelog", requests[0]["expand"])
+
+	assert.Len(t, issues, 1)
+	actual, err := json.Marshal(issues[0].Fields)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"created":"2024-01-01T10:00:00.000+0000","priority":null}`, string(actual))
+	assert.Equal(t, "issue 0", issues[0].Summary)
+
+	from, to := "Open", "Done"
+	assert.Equal(t, []*ChangelogEntry{
+		{Created: "2024-02-01", Author: "Alice", Field: "status", From: &from, To: &to},
+	}, issues[0].Changelog)
+}
+
+func TestSearchByQuery_WithError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errorMessages":["Error in the JQL Query"]}`)
+	}))
+	defer server.Close()
+
+	svc, err := NewJiraService(server.URL, "user", "token")
+	assert.NoError(t, err)
+
+	err = svc.SearchByQuery("project =", nil, func(issue *Issue) error {
+		return nil
+	})
+	assert.ErrorContains(t, err, "cannot search issues 1 to 100")
+	assert.ErrorContains(t, err, "Error in the JQL Query")
+}
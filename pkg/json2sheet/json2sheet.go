@@ -2,11 +2,13 @@ package json2sheet
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/url"
 
+	"github.com/trichner/tb/pkg/jsontree"
 	"github.com/trichner/tb/pkg/sheets"
 )
 
@@ -14,8 +16,95 @@ const (
 	streamTypeUnknown = iota
 	streamTypeObjects
 	streamTypeArrays
+
+	// streamTypeArrayOfObjects is a single top-level array with objects as elements,
+	// it cannot be told apart from NDJSON made of arrays by looking at the start
+	streamTypeArrayOfObjects
 )
 
+// peekSize is how much of the input is looked at to guess the stream type
+const peekSize = 512
+
+type Options struct {
+	// SkipInvalid skips NDJSON records which are not valid JSON rather than failing,
+	// it does not apply to the elements of a top-level array
+	SkipInvalid bool
+
+	// OnSkip is called with the error of every skipped record
+	OnSkip func(err error)
+
+	// Flatten spreads nested objects and arrays of object records across columns
+	// named by their path, e.g. 'address.city', rather than writing them as JSON text
+	Flatten *jsontree.FlattenOptions
+
+	// Format is the syntax of the input, JSON if empty. The lists of YAML documents
+	// are written as one row per element.
+	Format jsontree.Format
+
+	// Array reads the input as a single top-level array of objects, e.g. an API
+	// response like [{"a":1},{"a":2}], and writes one row per element. The elements
+	// are decoded one at a time. Otherwise a top-level array is a row of its own, as
+	// are the lines of NDJSON made of arrays.
+	Array bool
+}
+
+func (o *Options) format() jsontree.Format {
+	if o == nil {
+		return jsontree.FormatJSON
+	}
+	return o.Format
+}
+
+func (o *Options) decoder(r io.Reader) (*jsontree.Decoder, error) {
+	d, err := jsontree.NewDecoderFormat(r, o.format())
+	if err != nil {
+		return nil, err
+	}
+	if o != nil {
+		d.SkipInvalid = o.SkipInvalid
+		d.OnSkip = o.OnSkip
+	}
+	return d, nil
+}
+
+// records reads the elements of a top-level array of objects one by one, otherwise
+// the input is read as a stream of records
+func (o *Options) records(r *bufio.Reader, streamType int) (records, error) {
+	var recs records
+	var err error
+	switch {
+	case o.format() == jsontree.FormatYAML:
+		var d *jsontree.Decoder
+		d, err = o.decoder(r)
+		recs = &elements{records: d}
+	case streamType == streamTypeArrayOfObjects:
+		recs, err = jsontree.NewArrayDecoderFormat(r, o.format())
+	default:
+		recs, err = o.decoder(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if o != nil && o.Flatten != nil {
+		recs = &flattened{records: recs, opts: o.Flatten}
+	}
+	return recs, nil
+}
+
+// peekStreamType guesses the type of JSON streams, YAML is always read as records
+func peekStreamType(r io.Reader, opts *Options) (*bufio.Reader, int) {
+	br := bufio.NewReader(r)
+	if opts.format() == jsontree.FormatYAML {
+		return br, streamTypeUnknown
+	}
+	if opts != nil && opts.Array {
+		return br, streamTypeArrayOfObjects
+	}
+	peek, _ := br.Peek(peekSize)
+	return br, guessJsonStreamType(peek)
+}
+
 type SheetUpdater interface {
 	UpdateValues(data [][]string) error
 }
@@ -24,7 +113,7 @@ type SheetAppender interface {
 	AppendValues(data [][]string) error
 }
 
-func UpdateSheet(ctx context.Context, spreadsheetUrl string, r io.Reader) (*url.URL, error) {
+func UpdateSheet(ctx context.Context, spreadsheetUrl string, r io.Reader, opts *Options) (*url.URL, error) {
 	svc, err := sheets.NewSheetService(ctx)
 	if err != nil {
 		return nil, err
@@ -45,7 +134,12 @@ func UpdateSheet(ctx context.Context, spreadsheetUrl string, r io.Reader) (*url.
 		return nil, err
 	}
 
-	err = WriteObjectsTo(sheet, r)
+	br, streamType := peekStreamType(r, opts)
+	recs, err := opts.records(br, streamType)
+	if err != nil {
+		return nil, err
+	}
+	err = writeObjectsTo(sheet, recs)
 	if err != nil {
 		return nil, err
 	}
@@ -53,7 +147,7 @@ func UpdateSheet(ctx context.Context, spreadsheetUrl string, r io.Reader) (*url.
 	return url.Parse(spreadsheetUrl)
 }
 
-func WriteToNewSheet(ctx context.Context, r io.Reader) (*url.URL, error) {
+func WriteToNewSheet(ctx context.Context, r io.Reader, opts *Options) (*url.URL, error) {
 	svc, err := sheets.NewSheetService(ctx)
 	if err != nil {
 		return nil, err
@@ -69,22 +163,23 @@ func WriteToNewSheet(ctx context.Context, r io.Reader) (*url.URL, error) {
 		return nil, err
 	}
 
-	br := bufio.NewReader(r)
-
-	streamType := streamTypeUnknown
-	peek, err := br.Peek(2)
-	if err == nil {
-		streamType = guessJsonStreamType(peek)
-	}
-
+	br, streamType := peekStreamType(r, opts)
 	if streamType == streamTypeArrays {
 		// using append makes chunking easier and auto-extends the range
-		err = AppendArraysTo(sheet, br)
+		d, err := opts.decoder(br)
+		if err != nil {
+			return nil, err
+		}
+		err = appendArraysTo(sheet, d)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err = AppendObjectsTo(sheet, br)
+		recs, err := opts.records(br, streamType)
+		if err != nil {
+			return nil, err
+		}
+		err = writeObjectsTo(sheet, recs)
 		if err != nil {
 			return nil, err
 		}
@@ -100,6 +195,10 @@ func WriteToNewSheet(ctx context.Context, r io.Reader) (*url.URL, error) {
 }
 
 func guessJsonStreamType(peeked []byte) int {
+	peeked = bytes.TrimLeft(peeked, " \t\r\n")
+	if len(peeked) == 0 {
+		return streamTypeUnknown
+	}
 	if peeked[0] == '[' {
 		return streamTypeArrays
 	}
@@ -1,20 +1,227 @@
 package json2sheet
 
 import (
-	"fmt"
+	"context"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/sheets"
+	"github.com/trichner/tb/pkg/sheets/sheetstest"
 )
 
 func TestWriteToNewSheet(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
 	buf := strings.NewReader(`
 	{"a":"hello","b":"world"}
 	{"b":2,"a":1,"c":3}
 	{"d":4,"a":1,"c":3}
 	`)
-	url, err := WriteToNewSheet(buf)
-	fmt.Println(url)
+	url, err := WriteToNewSheet(ctx, buf, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://docs.google.com/spreadsheets/d/spreadsheet-1/edit#gid=0", url.String())
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"a", "b", "c", "d"},
+		{"hello", "world"},
+		{"1", "2", "3"},
+		{"1", "", "3", "4"},
+	}, sheet.Cells)
+}
+
+func TestWriteToNewSheet_Arrays(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader("[\"hello\", \"world\"]\n[true, null, 55.88]\n")
+	_, err := WriteToNewSheet(ctx, buf, nil)
+	assert.NoError(t, err)
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"hello", "world"},
+		{"TRUE", "", "55.88"},
+	}, sheet.Cells)
+}
+
+func TestWriteToNewSheet_ArrayOfObjects(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader(`[
+	  {"a":"hello","b":"world"},
+	  {"b":2,"c":3}
+	]`)
+	_, err := WriteToNewSheet(ctx, buf, &Options{Array: true})
+	assert.NoError(t, err)
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"a", "b", "c"},
+		{"hello", "world"},
+		{"", "2", "3"},
+	}, sheet.Cells)
+}
+
+func TestWriteToNewSheet_ArraysOfObjects(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader("[{\"a\":1}]\n[{\"b\":2}, 3]\n")
+	_, err := WriteToNewSheet(ctx, buf, nil)
+	assert.NoError(t, err)
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{`{"a":1}`},
+		{`{"b":2}`, "3"},
+	}, sheet.Cells)
+}
+
+func TestWriteToNewSheet_Flatten(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader(`
+	{"id":1,"address":{"city":"Zurich","zip":"8000"},"tags":["a","b"]}
+	{"id":2,"address":{"city":"Bern"},"tags":[],"extra":{"x":null}}
+	`)
+	opts := &Options{Flatten: &jsontree.FlattenOptions{}}
+	_, err := WriteToNewSheet(ctx, buf, opts)
+	assert.NoError(t, err)
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"id", "address.city", "address.zip", "tags[0]", "tags[1]", "tags", "extra.x"},
+		{"1", "Zurich", "8000", "a", "b"},
+		{"2", "Bern", "", "", "", "[]", ""},
+	}, sheet.Cells)
+}
+
+func TestWriteToNewSheet_FlattenCollision(t *testing.T) {
+	ctx := sheets.WithService(context.Background(), sheetstest.NewService())
+
+	buf := strings.NewReader("{\"a\":{\"b\":1}}\n{\"a.b\":1,\"a\":{\"b\":2}}\n")
+	_, err := WriteToNewSheet(ctx, buf, &Options{Flatten: &jsontree.FlattenOptions{}})
+	assert.EqualError(t, err, `record 2: cannot flatten, the path "a.b" occurs more than once`)
+}
+
+func TestWriteToNewSheet_YAML(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader(`
+- a: hello
+  b: world
+- b: 2
+  c: 3
+---
+d: true
+`)
+	_, err := WriteToNewSheet(ctx, buf, &Options{Format: jsontree.FormatYAML})
+	assert.NoError(t, err)
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"a", "b", "c", "d"},
+		{"hello", "world"},
+		{"", "2", "3"},
+		{"", "", "", "TRUE"},
+	}, sheet.Cells)
+}
+
+func TestWriteToNewSheet_JSON5(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader(`[
+	  {a: 'hello', b: 0x10}, // first
+	  {b: .5,},
+	]`)
+	_, err := WriteToNewSheet(ctx, buf, &Options{Format: jsontree.FormatJSON5, Array: true})
+	assert.NoError(t, err)
+
+	sheet := fake.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"a", "b"},
+		{"hello", "16"},
+		{"", "0.5"},
+	}, sheet.Cells)
+}
+
+func TestGuessJsonStreamType(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected int
+	}{
+		{raw: `{"a":1}`, expected: streamTypeObjects},
+		{raw: "\n  {\"a\":1}", expected: streamTypeObjects},
+		{raw: `["a",1]`, expected: streamTypeArrays},
+		{raw: `[[1]]`, expected: streamTypeArrays},
+		{raw: "[\n  {\"a\":1}", expected: streamTypeArrays},
+		{raw: "[{\"a\":1}]\n[{\"b\":2}]", expected: streamTypeArrays},
+		{raw: "", expected: streamTypeUnknown},
+		{raw: "42", expected: streamTypeUnknown},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, guessJsonStreamType([]byte(tt.raw)), tt.raw)
+	}
+}
+
+func TestWriteToNewSheet_SkipInvalid(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	buf := strings.NewReader("{\"a\":1}\n{\"a\":2\n{\"a\":3}\n{\"a\":\"\x01\"}\n")
+
+	_, err := WriteToNewSheet(ctx, buf, nil)
+	assert.ErrorContains(t, err, "record 2: expected ',' or '}' but got '{' at line 3, column 1")
+
+	buf.Seek(0, io.SeekStart)
+	var skipped []error
+	_, err = WriteToNewSheet(ctx, buf, &Options{SkipInvalid: true, OnSkip: func(err error) {
+		skipped = append(skipped, err)
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, skipped, 2)
+
+	sheet := fake.Spreadsheet("spreadsheet-2").Sheets[0]
+	assert.Equal(t, [][]string{
+		{"a"},
+		{"1"},
+		{"3"},
+	}, sheet.Cells)
+}
+
+func TestUpdateSheet(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	ss := fake.AddSpreadSheet("abc123", "existing")
+	sheet := ss.Sheets[0]
+	sheet.RowCount = 1
+	sheet.ColumnCount = 1
+
+	buf := strings.NewReader(`{"a":"hello","b":"world"}`)
+	url, err := UpdateSheet(ctx, "https://docs.google.com/spreadsheets/d/abc123/edit#gid=0", buf, nil)
 	assert.NoError(t, err)
+	assert.Equal(t, "abc123", strings.Split(url.Path, "/")[3])
+
+	assert.Equal(t, [][]string{{"a", "b"}, {"hello", "world"}}, sheet.Cells)
+	assert.Equal(t, 2, sheet.RowCount)
+	assert.Equal(t, 2, sheet.ColumnCount)
+}
+
+func TestUpdateSheet_UnknownSheet(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+	fake.AddSpreadSheet("abc123", "existing")
+
+	_, err := UpdateSheet(ctx, "https://docs.google.com/spreadsheets/d/abc123/edit#gid=42", strings.NewReader(`{}`), nil)
+	assert.ErrorIs(t, err, sheets.ErrNotFound)
 }
@@ -7,11 +7,21 @@ import (
 
 	"github.com/trichner/tb/pkg/jsontree"
 	"github.com/trichner/tb/pkg/jsontree/ast"
-	"github.com/trichner/tb/pkg/jsontree/lexer"
+	"github.com/trichner/tb/pkg/sheets"
 )
 
+// batchSize is the number of rows written at once, sheets which can be written to at
+// any row are filled batch by batch so only that many rows are held in memory
+const batchSize = 1000
+
+// sheetWriter is a SheetUpdater which can also write further down, e.g. sheets.SheetOps
+type sheetWriter interface {
+	SheetUpdater
+	WriteRange(r *sheets.Range, data [][]string) error
+}
+
 func WriteArraysTo(to SheetUpdater, from io.Reader) error {
-	rows, err := mapArraysToRows(from)
+	rows, err := mapArraysToRows(jsontree.NewDecoder(from))
 	if err != nil {
 		return err
 	}
@@ -20,25 +30,140 @@ func WriteArraysTo(to SheetUpdater, from io.Reader) error {
 }
 
 func WriteObjectsTo(to SheetUpdater, from io.Reader) error {
-	rows, err := mapObjectsToRows(from)
+	return writeObjectsTo(to, jsontree.NewDecoder(from))
+}
+
+// records is a source of JSON records, e.g. a stream of NDJSON or the elements of a
+// top-level array
+type records interface {
+	Decode() (ast.Node, error)
+	Record() int
+}
+
+// flattened flattens object records, other records are passed on as they are
+type flattened struct {
+	records
+	opts *jsontree.FlattenOptions
+}
+
+func (f *flattened) Decode() (ast.Node, error) {
+	n, err := f.records.Decode()
+	if err != nil || n.Type() != ast.NodeTypeObject {
+		return n, err
+	}
+	flat, err := jsontree.Flatten(n, f.opts)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("record %d: %w", f.Record(), err)
 	}
+	return flat, nil
+}
 
-	return to.UpdateValues(rows)
+// elements spreads records that are arrays into their elements, e.g. the list of
+// objects of a YAML document
+type elements struct {
+	records
+	pending []ast.Node
+	record  int
 }
 
-func AppendArraysTo(to SheetAppender, from io.Reader) error {
-	rows, err := mapArraysToRows(from)
-	if err != nil {
+func (e *elements) Decode() (ast.Node, error) {
+	for len(e.pending) == 0 {
+		n, err := e.records.Decode()
+		if err != nil {
+			return nil, err
+		}
+		if n.Type() != ast.NodeTypeArray {
+			e.record++
+			return n, nil
+		}
+		e.pending = n.(ast.ArrayNode).Items()
+	}
+
+	n := e.pending[0]
+	e.pending = e.pending[1:]
+	e.record++
+	return n, nil
+}
+
+func (e *elements) Record() int {
+	return e.record
+}
+
+// writeObjectsTo writes the records starting at A1, in batches if the sheet supports
+// it. The header row is written with the first batch and once more at the end if
+// later records have properties which were not known yet.
+func writeObjectsTo(to SheetUpdater, from records) error {
+	w, ok := to.(sheetWriter)
+	if !ok {
+		rows, err := mapObjectsToRows(from)
+		if err != nil {
+			return err
+		}
+		return to.UpdateValues(rows)
+	}
+
+	row, written := 0, 0
+	headers, err := mapObjectsToBatches(from, func(headers map[string]int, rows [][]string) error {
+		var err error
+		if row == 0 {
+			rows[0] = headersToRow(headers)
+			written = len(headers)
+			err = w.UpdateValues(rows)
+		} else {
+			err = w.WriteRange(rowsRange(row, rows), rows)
+		}
+		row += len(rows)
+		return err
+	})
+	if err != nil || len(headers) == written {
 		return err
 	}
 
+	header := [][]string{headersToRow(headers)}
+	return w.WriteRange(rowsRange(0, header), header)
+}
+
+// rowsRange returns the range the rows cover when written at the zero based row
+func rowsRange(row int, rows [][]string) *sheets.Range {
+	width := 1
+	for _, r := range rows {
+		width = max(width, len(r))
+	}
+	return &sheets.Range{StartRow: row, EndRow: row + len(rows), EndColumn: width}
+}
+
+func AppendArraysTo(to SheetAppender, from io.Reader) error {
+	return appendArraysTo(to, jsontree.NewDecoder(from))
+}
+
+// appendArraysTo appends the records in batches of batchSize rows
+func appendArraysTo(to SheetAppender, from records) error {
+	var rows [][]string
+	for {
+		row, err := mapArrayToRow(from)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, row)
+		if len(rows) == batchSize {
+			if err := to.AppendValues(rows); err != nil {
+				return err
+			}
+			rows = nil
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
 	return to.AppendValues(rows)
 }
 
 func AppendObjectsTo(to SheetAppender, from io.Reader) error {
-	rows, err := mapObjectsToRows(from)
+	rows, err := mapObjectsToRows(jsontree.NewDecoder(from))
 	if err != nil {
 		return err
 	}
@@ -46,18 +171,37 @@ func AppendObjectsTo(to SheetAppender, from io.Reader) error {
 	return to.AppendValues(rows)
 }
 
-func mapObjectsToRows(from io.Reader) ([][]string, error) {
+// ReadObjectRows reads a stream of JSON objects into rows just like they are written to a sheet,
+// the first row is a header of all property names in order of appearance
+func ReadObjectRows(from io.Reader) ([][]string, error) {
+	return mapObjectsToRows(jsontree.NewDecoder(from))
+}
+
+func mapObjectsToRows(from records) ([][]string, error) {
 	var rows [][]string
+	headers, err := mapObjectsToBatches(from, func(_ map[string]int, batch [][]string) error {
+		rows = append(rows, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	l := lexer.NewLexer(from)
+	rows[0] = headersToRow(headers)
+	return rows, nil
+}
 
+// mapObjectsToBatches maps the records to rows and calls emit with batches of up to
+// batchSize rows and the header columns known so far. The first batch starts with an
+// empty row for the header, all header columns are returned at the end.
+func mapObjectsToBatches(from records, emit func(headers map[string]int, rows [][]string) error) (map[string]int, error) {
 	// write empty header row for a start
-	rows = append(rows, []string{})
+	rows := [][]string{{}}
 
 	headers := map[string]int{}
 
 	for {
-		root, err := jsontree.Parse(l)
+		root, err := from.Decode()
 		if err == io.EOF {
 			break
 		}
@@ -66,7 +210,7 @@ func mapObjectsToRows(from io.Reader) ([][]string, error) {
 		}
 
 		if root.Type() != ast.NodeTypeObject {
-			return nil, fmt.Errorf("json is not an object: %s", root.Type())
+			return nil, fmt.Errorf("record %d: json is not an object: %s at %s", from.Record(), root.Type(), root.Pos())
 		}
 
 		node := root.(ast.ObjectNode)
@@ -75,38 +219,57 @@ func mapObjectsToRows(from io.Reader) ([][]string, error) {
 
 		row := toRow(node, headers)
 		rows = append(rows, row)
+
+		if len(rows) == batchSize {
+			if err := emit(headers, rows); err != nil {
+				return nil, err
+			}
+			rows = nil
+		}
 	}
 
-	rows[0] = headersToRow(headers)
-	return rows, nil
+	if len(rows) > 0 {
+		if err := emit(headers, rows); err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
 }
 
-func mapArraysToRows(from io.Reader) ([][]string, error) {
+func mapArraysToRows(from records) ([][]string, error) {
 	var rows [][]string
-	l := lexer.NewLexer(from)
 	for {
-		root, err := jsontree.Parse(l)
+		row, err := mapArrayToRow(from)
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return nil, err
 		}
-
-		if root.Type() != ast.NodeTypeArray {
-			return nil, fmt.Errorf("json object is not an array: %s", root.Type())
-		}
-
-		node := root.(ast.ArrayNode)
-		row := make([]string, len(node.Items()))
-		for i, v := range node.Items() {
-			row[i] = toString(v)
-		}
 		rows = append(rows, row)
 	}
 	return rows, nil
 }
 
+// mapArrayToRow decodes the next record, which must be an array, into a row
+func mapArrayToRow(from records) ([]string, error) {
+	root, err := from.Decode()
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Type() != ast.NodeTypeArray {
+		return nil, fmt.Errorf("record %d: json object is not an array: %s at %s", from.Record(), root.Type(), root.Pos())
+	}
+
+	node := root.(ast.ArrayNode)
+	row := make([]string, len(node.Items()))
+	for i, v := range node.Items() {
+		row[i] = toString(v)
+	}
+	return row, nil
+}
+
 func headersToRow(headers map[string]int) []string {
 	row := make([]string, len(headers))
 	for k, v := range headers {
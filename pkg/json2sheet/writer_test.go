@@ -1,10 +1,12 @@
 package json2sheet
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/sheets"
 )
 
 type mockSheetWriter struct {
@@ -44,3 +46,37 @@ func TestWriteObjectsTo(t *testing.T) {
 	assert.Equal(t, 4, len(rows))
 	assert.Equal(t, 4, len(rows[0]))
 }
+
+type mockRangeWriter struct {
+	mockSheetWriter
+	ranges []string
+	writes [][][]string
+}
+
+func (m *mockRangeWriter) WriteRange(r *sheets.Range, data [][]string) error {
+	m.ranges = append(m.ranges, r.A1())
+	m.writes = append(m.writes, data)
+	return nil
+}
+
+func TestWriteObjectsTo_Batches(t *testing.T) {
+	var src strings.Builder
+	for i := range batchSize + 1 {
+		fmt.Fprintf(&src, "{\"a\":%d}\n", i)
+	}
+	src.WriteString(`{"b":true}`)
+
+	m := &mockRangeWriter{}
+	err := WriteObjectsTo(m, strings.NewReader(src.String()))
+	assert.NoError(t, err)
+
+	// the first batch holds the header, the header is written again once complete
+	assert.Len(t, m.invocations, 1)
+	assert.Len(t, m.invocations[0], batchSize)
+	assert.Equal(t, []string{"a"}, m.invocations[0][0])
+	assert.Equal(t, []string{"A1001:B1003", "A1:B1"}, m.ranges)
+	assert.Equal(t, [][][]string{
+		{{"999"}, {"1000"}, {"", "TRUE"}},
+		{{"a", "b"}},
+	}, m.writes)
+}
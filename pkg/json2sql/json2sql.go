@@ -0,0 +1,417 @@
+// Package json2sql loads NDJSON objects into a database table, it is the inverse of sql2json.
+package json2sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+const (
+	defaultBatchSize = 500
+
+	// maxParams stays below the bind parameter limits of all supported databases, e.g. 32766 in
+	// SQLite and 65535 in PostgreSQL and MySQL
+	maxParams = 30000
+)
+
+// Options control how records are loaded
+type Options struct {
+	Table string
+
+	// Create creates the table if it does not exist, the column types are inferred from the
+	// first batch of records
+	Create bool
+
+	// BatchSize is the number of records inserted per transaction
+	BatchSize int
+
+	// UpsertKey are the columns identifying a row, existing rows are updated rather than rejected
+	UpsertKey []string
+
+	// Rejected receives the rejected records as NDJSON, they are only counted if nil
+	Rejected io.Writer
+
+	// Format is the syntax of the input, JSON if empty
+	Format jsontree.Format
+}
+
+type Stats struct {
+	Records  int
+	Loaded   int
+	Rejected int
+}
+
+// Rejection is written for each rejected record, Record is its one-based position in the input
+type Rejection struct {
+	Record int             `json:"record"`
+	Error  string          `json:"error"`
+	Value  json.RawMessage `json:"value"`
+}
+
+type record struct {
+	number int
+	node   ast.Node
+}
+
+type loader struct {
+	db      *sql2json.DB
+	opts    *Options
+	stats   *Stats
+	columns []string
+	reject  func(r *record, err error) error
+}
+
+// Load reads NDJSON objects and inserts them into the table, records which are not objects, have
+// unknown columns or are refused by the database are rejected. Parse errors of the input abort
+// the load since the remaining input cannot be trusted.
+func Load(ctx context.Context, db *sql2json.DB, r io.Reader, opts *Options) (*Stats, error) {
+	if opts.Table == "" {
+		return nil, fmt.Errorf("missing table")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	l := &loader{db: db, opts: opts, stats: &Stats{}}
+
+	var encoder *json.Encoder
+	if opts.Rejected != nil {
+		encoder = json.NewEncoder(opts.Rejected)
+	}
+	l.reject = func(r *record, err error) error {
+		l.stats.Rejected++
+		if encoder == nil {
+			return nil
+		}
+		value, merr := r.node.MarshalJSON()
+		if merr != nil {
+			return merr
+		}
+		return encoder.Encode(&Rejection{Record: r.number, Error: err.Error(), Value: value})
+	}
+
+	dec, err := jsontree.NewDecoderFormat(r, opts.Format)
+	if err != nil {
+		return l.stats, err
+	}
+	for {
+		batch, err := readBatch(dec, batchSize, l.stats)
+		if err != nil {
+			return l.stats, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		if l.columns == nil {
+			if err := l.prepareTable(ctx, batch); err != nil {
+				return l.stats, err
+			}
+		}
+
+		if err := l.loadBatch(ctx, batch); err != nil {
+			return l.stats, err
+		}
+	}
+	return l.stats, nil
+}
+
+func readBatch(dec *jsontree.Decoder, size int, stats *Stats) ([]*record, error) {
+	var batch []*record
+	for len(batch) < size {
+		node, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// the error names the record already
+			return nil, fmt.Errorf("cannot parse %w", err)
+		}
+		stats.Records++
+		batch = append(batch, &record{number: stats.Records, node: node})
+	}
+	return batch, nil
+}
+
+// prepareTable creates the table if requested and reads its columns
+func (l *loader) prepareTable(ctx context.Context, batch []*record) error {
+	if l.opts.Create {
+		var objects []ast.ObjectNode
+		for _, r := range batch {
+			if o, ok := r.node.(ast.ObjectNode); ok {
+				objects = append(objects, o)
+			}
+		}
+		if err := l.createTable(ctx, inferColumns(objects)); err != nil {
+			return err
+		}
+	}
+
+	rows, err := l.db.QueryContext(ctx, "SELECT * FROM "+quoteTable(l.db.Driver.Dialect, l.opts.Table)+" WHERE 1=0")
+	if err != nil {
+		return fmt.Errorf("cannot read columns of table %q: %w", l.opts.Table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("cannot read columns of table %q: %w", l.opts.Table, err)
+	}
+
+	for _, key := range l.opts.UpsertKey {
+		if !slices.Contains(columns, key) {
+			return fmt.Errorf("upsert key %q is not a column of table %q", key, l.opts.Table)
+		}
+	}
+
+	l.columns = columns
+	return nil
+}
+
+func (l *loader) createTable(ctx context.Context, columns []*column) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("cannot create table %q, no columns found in the first records", l.opts.Table)
+	}
+
+	dialect := l.db.Driver.Dialect
+	definitions := make([]string, 0, len(columns)+1)
+	for _, c := range columns {
+		key := slices.Contains(l.opts.UpsertKey, c.name)
+		definitions = append(definitions, quoteIdentifier(dialect, c.name)+" "+sqlType(dialect, c.typ, key))
+	}
+	if len(l.opts.UpsertKey) > 0 {
+		definitions = append(definitions, "PRIMARY KEY ("+l.quoteColumns(l.opts.UpsertKey)+")")
+	}
+
+	ddl := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteTable(dialect, l.opts.Table), strings.Join(definitions, ", "))
+	if _, err := l.db.ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("cannot create table %q: %w", l.opts.Table, err)
+	}
+	return nil
+}
+
+// loadBatch inserts the valid records in a single transaction, if the database refuses any of
+// them the records are retried one by one to find and reject the culprits
+func (l *loader) loadBatch(ctx context.Context, batch []*record) error {
+	var valid []*record
+	for _, r := range batch {
+		if err := l.validate(r); err != nil {
+			if err := l.reject(r, err); err != nil {
+				return err
+			}
+			continue
+		}
+		valid = append(valid, r)
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+
+	err := l.insertInTx(ctx, valid)
+	if err == nil {
+		l.stats.Loaded += len(valid)
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	for _, r := range valid {
+		if err := l.insert(ctx, l.db, []*record{r}); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := l.reject(r, err); err != nil {
+				return err
+			}
+			continue
+		}
+		l.stats.Loaded++
+	}
+	return nil
+}
+
+func (l *loader) validate(r *record) error {
+	o, ok := r.node.(ast.ObjectNode)
+	if !ok {
+		return fmt.Errorf("record is not an object but %s", r.node.Type())
+	}
+	if len(o.Properties()) == 0 {
+		return fmt.Errorf("record has no columns")
+	}
+
+	for _, p := range o.Properties() {
+		if !slices.Contains(l.columns, p.Name) {
+			return fmt.Errorf("unknown column %q", p.Name)
+		}
+	}
+
+	for _, key := range l.opts.UpsertKey {
+		if !hasProperty(o, key) {
+			return fmt.Errorf("missing upsert key %q", key)
+		}
+	}
+	return nil
+}
+
+func (l *loader) insertInTx(ctx context.Context, records []*record) error {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := l.insert(ctx, tx, records); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insert writes the records with multi-row inserts, records are grouped by the columns they set
+// so missing columns keep their defaults and upserts only update what is given
+func (l *loader) insert(ctx context.Context, e execer, records []*record) error {
+	for _, group := range l.groupByColumns(records) {
+		chunk := max(1, maxParams/len(group.columns))
+
+		for start := 0; start < len(group.records); start += chunk {
+			end := min(start+chunk, len(group.records))
+			query, args, err := l.insertStatement(group.columns, group.records[start:end])
+			if err != nil {
+				return err
+			}
+			if _, err := e.ExecContext(ctx, query, args...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type recordGroup struct {
+	columns []string
+	records []*record
+}
+
+// groupByColumns groups the records by the columns they set, the columns are in table order
+func (l *loader) groupByColumns(records []*record) []*recordGroup {
+	var groups []*recordGroup
+	byColumns := make(map[string]*recordGroup)
+
+	for _, r := range records {
+		var columns []string
+		for _, c := range l.columns {
+			if hasProperty(r.node.(ast.ObjectNode), c) {
+				columns = append(columns, c)
+			}
+		}
+
+		key := strings.Join(columns, "\x00")
+		g, ok := byColumns[key]
+		if !ok {
+			g = &recordGroup{columns: columns}
+			byColumns[key] = g
+			groups = append(groups, g)
+		}
+		g.records = append(g.records, r)
+	}
+	return groups
+}
+
+func hasProperty(o ast.ObjectNode, name string) bool {
+	_, ok := o.Get(name)
+	return ok
+}
+
+func (l *loader) insertStatement(columns []string, records []*record) (string, []any, error) {
+	dialect := l.db.Driver.Dialect
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", quoteTable(dialect, l.opts.Table), l.quoteColumns(columns))
+
+	args := make([]any, 0, len(records)*len(columns))
+	for i, r := range records {
+		values := make(map[string]ast.Node)
+		for _, p := range r.node.(ast.ObjectNode).Properties() {
+			values[p.Name] = p.Value
+		}
+
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for j, c := range columns {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString(placeholder(l.db.Driver.Placeholder, len(args)))
+
+			arg, err := toSqlValue(values[c])
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, arg)
+		}
+		query.WriteByte(')')
+	}
+
+	query.WriteString(l.upsertClause(columns))
+	return query.String(), args, nil
+}
+
+func (l *loader) upsertClause(columns []string) string {
+	if len(l.opts.UpsertKey) == 0 {
+		return ""
+	}
+
+	dialect := l.db.Driver.Dialect
+
+	var updates []string
+	for _, c := range columns {
+		if slices.Contains(l.opts.UpsertKey, c) {
+			continue
+		}
+		q := quoteIdentifier(dialect, c)
+		if dialect == sql2json.DialectMysql {
+			updates = append(updates, q+" = VALUES("+q+")")
+		} else {
+			updates = append(updates, q+" = excluded."+q)
+		}
+	}
+
+	if dialect == sql2json.DialectMysql {
+		if len(updates) == 0 {
+			// MySQL has no 'DO NOTHING', assigning a key to itself is the idiomatic no-op
+			q := quoteIdentifier(dialect, l.opts.UpsertKey[0])
+			updates = append(updates, q+" = "+q)
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	}
+
+	conflict := " ON CONFLICT (" + l.quoteColumns(l.opts.UpsertKey) + ")"
+	if len(updates) == 0 {
+		return conflict + " DO NOTHING"
+	}
+	return conflict + " DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+func (l *loader) quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(l.db.Driver.Dialect, c)
+	}
+	return strings.Join(quoted, ", ")
+}
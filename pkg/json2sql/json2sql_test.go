@@ -0,0 +1,158 @@
+package json2sql
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+func openTestDb(t *testing.T) *sql2json.DB {
+	db, err := sql2json.Open(context.Background(), &sql2json.Config{ConnectionUri: "sqlite://" + filepath.Join(t.TempDir(), "test.db")})
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func queryAll(t *testing.T, db *sql2json.DB, query string) string {
+	var buf bytes.Buffer
+	w, err := sql2json.NewRowWriter(sql2json.FormatNdjson, &buf)
+	assert.NoError(t, err)
+	assert.NoError(t, sql2json.ExecQuery(context.Background(), db, query, nil, w, &sql2json.Options{}))
+	return buf.String()
+}
+
+func TestLoad_CreateTable(t *testing.T) {
+	db := openTestDb(t)
+
+	input := `{"id":1,"name":"Alice","score":1.5,"admin":true,"tags":["a","b"]}
+{"id":2,"name":"Bob","score":2,"admin":false,"tags":null}
+{"id":3,"name":null}
+`
+	stats, err := Load(context.Background(), db, strings.NewReader(input), &Options{Table: "users", Create: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &Stats{Records: 3, Loaded: 3}, stats)
+
+	expected := `{"id":1,"name":"Alice","score":1.5,"admin":true,"tags":"[\"a\",\"b\"]"}
+{"id":2,"name":"Bob","score":2,"admin":false,"tags":null}
+{"id":3,"name":null,"score":null,"admin":null,"tags":null}
+`
+	assert.Equal(t, expected, queryAll(t, db, "SELECT * FROM users ORDER BY id"))
+
+	var ddl string
+	assert.NoError(t, db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'users'`).Scan(&ddl))
+	assert.Equal(t, `CREATE TABLE "users" ("id" INTEGER, "name" TEXT, "score" REAL, "admin" BOOLEAN, "tags" TEXT)`, ddl)
+}
+
+func TestLoad_EscapedText(t *testing.T) {
+	db := openTestDb(t)
+
+	_, err := Load(context.Background(), db, strings.NewReader(`{"name":"O\"Brian \u00e9\n"}`), &Options{Table: "users", Create: true})
+	assert.NoError(t, err)
+
+	var name string
+	assert.NoError(t, db.QueryRow(`SELECT name FROM users`).Scan(&name))
+	assert.Equal(t, "O\"Brian é\n", name)
+}
+
+func TestLoad_Batches(t *testing.T) {
+	db := openTestDb(t)
+
+	var input strings.Builder
+	for i := 0; i < 25; i++ {
+		input.WriteString(`{"n":1}` + "\n")
+	}
+
+	stats, err := Load(context.Background(), db, strings.NewReader(input.String()), &Options{Table: "numbers", Create: true, BatchSize: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 25, stats.Loaded)
+	assert.Equal(t, "{\"count(*)\":25}\n", queryAll(t, db, "SELECT count(*) FROM numbers"))
+}
+
+func TestLoad_Rejects(t *testing.T) {
+	db := openTestDb(t)
+
+	_, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	assert.NoError(t, err)
+
+	input := `{"id":1,"name":"Alice"}
+[1,2]
+{"id":2,"nick":"bob"}
+{"id":3}
+{"id":4,"name":"Dora"}
+{}
+`
+
+	var rejected bytes.Buffer
+	stats, err := Load(context.Background(), db, strings.NewReader(input), &Options{Table: "users", Rejected: &rejected})
+	assert.NoError(t, err)
+	assert.Equal(t, &Stats{Records: 6, Loaded: 2, Rejected: 4}, stats)
+
+	assert.Equal(t, "{\"id\":1,\"name\":\"Alice\"}\n{\"id\":4,\"name\":\"Dora\"}\n", queryAll(t, db, "SELECT * FROM users ORDER BY id"))
+
+	lines := strings.Split(strings.TrimSpace(rejected.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `{"record":2,"error":"record is not an object but NodeTypeArray","value":[1,2]}`)
+	assert.Contains(t, lines[1], `"unknown column \"nick\""`)
+	assert.Contains(t, lines[2], `"record has no columns"`)
+
+	// the database refuses records only after the invalid ones are rejected
+	assert.Contains(t, lines[3], `"record":4`)
+	assert.Contains(t, lines[3], `NOT NULL`)
+}
+
+func TestLoad_Upsert(t *testing.T) {
+	db := openTestDb(t)
+
+	opts := &Options{Table: "users", Create: true, UpsertKey: []string{"id"}}
+	_, err := Load(context.Background(), db, strings.NewReader(`{"id":1,"name":"Alice"}`+"\n"+`{"id":2,"name":"Bob"}`), opts)
+	assert.NoError(t, err)
+
+	stats, err := Load(context.Background(), db, strings.NewReader(`{"id":2,"name":"Bobby"}`+"\n"+`{"id":3,"name":"Carol"}`+"\n"+`{"id":1}`), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, &Stats{Records: 3, Loaded: 3}, stats)
+
+	expected := `{"id":1,"name":"Alice"}
+{"id":2,"name":"Bobby"}
+{"id":3,"name":"Carol"}
+`
+	assert.Equal(t, expected, queryAll(t, db, "SELECT * FROM users ORDER BY id"))
+}
+
+func TestLoad_Errors(t *testing.T) {
+	db := openTestDb(t)
+
+	_, err := Load(context.Background(), db, strings.NewReader(`{"id":1}`), &Options{Table: "missing"})
+	assert.Error(t, err)
+
+	_, err = Load(context.Background(), db, strings.NewReader(`{"id":1}`), &Options{Table: "t", Create: true, UpsertKey: []string{"key"}})
+	assert.Error(t, err)
+
+	stats, err := Load(context.Background(), db, strings.NewReader(`{"id":1} {"id":`), &Options{Table: "t", Create: true})
+	assert.Error(t, err)
+	assert.Equal(t, 1, stats.Records)
+}
+
+func TestUpsertClause(t *testing.T) {
+	tests := []struct {
+		dialect  sql2json.Dialect
+		columns  []string
+		expected string
+	}{
+		{sql2json.DialectPostgres, []string{"id", "name"}, ` ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`},
+		{sql2json.DialectSqlite, []string{"id"}, ` ON CONFLICT ("id") DO NOTHING`},
+		{sql2json.DialectMysql, []string{"id", "name"}, " ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)"},
+		{sql2json.DialectMysql, []string{"id"}, " ON DUPLICATE KEY UPDATE `id` = `id`"},
+	}
+	for _, tt := range tests {
+		l := &loader{
+			db:   &sql2json.DB{Driver: &sql2json.Driver{Dialect: tt.dialect}},
+			opts: &Options{UpsertKey: []string{"id"}},
+		}
+		assert.Equal(t, tt.expected, l.upsertClause(tt.columns))
+	}
+}
@@ -0,0 +1,177 @@
+package json2sql
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+// columnType is the type inferred for a column from the JSON values
+type columnType int
+
+const (
+	// typeUnknown is a column with only null values so far
+	typeUnknown columnType = iota
+	typeInteger
+	typeFloat
+	typeBoolean
+	typeText
+	typeJson
+)
+
+// column is a table column with the type inferred from the sampled values
+type column struct {
+	name string
+	typ  columnType
+}
+
+// inferColumns returns the columns of the sampled objects in order of first appearance
+func inferColumns(objects []ast.ObjectNode) []*column {
+	var columns []*column
+	byName := make(map[string]*column)
+
+	for _, o := range objects {
+		for _, p := range o.Properties() {
+			c, ok := byName[p.Name]
+			if !ok {
+				c = &column{name: p.Name}
+				byName[p.Name] = c
+				columns = append(columns, c)
+			}
+			c.typ = widen(c.typ, typeOf(p.Value))
+		}
+	}
+	return columns
+}
+
+func typeOf(n ast.Node) columnType {
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		return typeUnknown
+	case ast.NodeTypeBoolean:
+		return typeBoolean
+	case ast.NodeTypeNumber:
+		if _, err := n.(ast.NumberNode).ToInt64(); err == nil {
+			return typeInteger
+		}
+		return typeFloat
+	case ast.NodeTypeText:
+		return typeText
+	}
+	return typeJson
+}
+
+// widen returns a type that can hold values of both types, integers widen to floats while any
+// other mix falls back to text
+func widen(a, b columnType) columnType {
+	switch {
+	case a == b || b == typeUnknown:
+		return a
+	case a == typeUnknown:
+		return b
+	case (a == typeInteger && b == typeFloat) || (a == typeFloat && b == typeInteger):
+		return typeFloat
+	}
+	return typeText
+}
+
+// sqlType returns the column type in the given dialect, keys need a bounded length in MySQL
+func sqlType(dialect sql2json.Dialect, t columnType, key bool) string {
+	switch dialect {
+	case sql2json.DialectPostgres:
+		switch t {
+		case typeInteger:
+			return "BIGINT"
+		case typeFloat:
+			return "DOUBLE PRECISION"
+		case typeBoolean:
+			return "BOOLEAN"
+		case typeJson:
+			return "JSONB"
+		}
+		return "TEXT"
+	case sql2json.DialectMysql:
+		switch t {
+		case typeInteger:
+			return "BIGINT"
+		case typeFloat:
+			return "DOUBLE"
+		case typeBoolean:
+			return "BOOLEAN"
+		case typeJson:
+			return "JSON"
+		}
+		if key {
+			return "VARCHAR(255)"
+		}
+		return "TEXT"
+	}
+
+	// SQLite, a declared type of 'JSON' would have numeric affinity and mangle text
+	switch t {
+	case typeInteger:
+		return "INTEGER"
+	case typeFloat:
+		return "REAL"
+	case typeBoolean:
+		return "BOOLEAN"
+	}
+	return "TEXT"
+}
+
+// toSqlValue converts a JSON value to a value the drivers can bind, objects and arrays are bound
+// as their JSON text
+func toSqlValue(n ast.Node) (any, error) {
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		return nil, nil
+	case ast.NodeTypeBoolean:
+		return n.(ast.BooleanNode).Value(), nil
+	case ast.NodeTypeNumber:
+		number := n.(ast.NumberNode)
+		if i, err := number.ToInt64(); err == nil {
+			return i, nil
+		}
+		if f, err := number.ToFloat64(); err == nil && !math.IsInf(f, 0) {
+			return f, nil
+		}
+		// let the database deal with numbers exceeding float64, e.g. for DECIMAL columns
+		return number.Value(), nil
+	case ast.NodeTypeText:
+		return n.(ast.TextNode).Value(), nil
+	}
+
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// quoteTable quotes a possibly schema qualified table name such as 'reporting.users'
+func quoteTable(dialect sql2json.Dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(dialect, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteIdentifier(dialect sql2json.Dialect, name string) string {
+	quote := `"`
+	if dialect == sql2json.DialectMysql {
+		quote = "`"
+	}
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// placeholder returns the bind parameter for the zero-based index
+func placeholder(p sql2json.Placeholder, index int) string {
+	if p == sql2json.PlaceholderDollar {
+		return "$" + strconv.Itoa(index+1)
+	}
+	return "?"
+}
@@ -3,7 +3,10 @@ package ast
 import (
 	"bytes"
 	"encoding/json"
+	"slices"
 	"strconv"
+
+	"github.com/trichner/tb/pkg/jsontree/lexer"
 )
 
 //go:generate stringer -type=NodeType
@@ -22,6 +25,10 @@ const (
 type Node interface {
 	json.Marshaler
 	Type() NodeType
+
+	// Pos is where the node starts in the parsed input, it is the zero value for
+	// nodes that were not parsed
+	Pos() lexer.Position
 }
 
 type Property struct {
@@ -29,14 +36,36 @@ type Property struct {
 	Value Node
 }
 
+// ObjectNode keeps its properties in the order they were parsed or added
 type ObjectNode interface {
 	Node
 	Properties() []*Property
+
+	// Get returns the value of the first property with the given name
+	Get(name string) (Node, bool)
+
+	// Set replaces the value of the first property with the given name in place, or
+	// appends a new property if there is none
+	Set(name string, value Node)
+
+	// Delete removes all properties with the given name and reports whether there were any
+	Delete(name string) bool
 }
 
 type ArrayNode interface {
 	Node
 	Items() []Node
+
+	// Get returns the item at index i
+	Get(i int) (Node, bool)
+
+	// Set replaces the item at index i and reports whether the index was in range
+	Set(i int, value Node) bool
+
+	// Delete removes the item at index i and reports whether the index was in range
+	Delete(i int) bool
+
+	Append(items ...Node)
 }
 
 type BooleanNode interface {
@@ -101,14 +130,30 @@ func NewObjectNode(properties []*Property) ObjectNode {
 	})
 }
 
+// SetPos records where a node starts in the parsed input
+func SetPos(n Node, pos lexer.Position) {
+	if p, ok := n.(interface{ setPos(lexer.Position) }); ok {
+		p.setPos(pos)
+	}
+}
+
 type node struct {
 	nodeType NodeType
+	pos      lexer.Position
 }
 
 func (n *node) Type() NodeType {
 	return n.nodeType
 }
 
+func (n *node) Pos() lexer.Position {
+	return n.pos
+}
+
+func (n *node) setPos(pos lexer.Position) {
+	n.pos = pos
+}
+
 type nullNode struct {
 	node
 }
@@ -187,6 +232,33 @@ func (o *objectNode) Properties() []*Property {
 	return o.properties
 }
 
+func (o *objectNode) Get(name string) (Node, bool) {
+	for _, p := range o.properties {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (o *objectNode) Set(name string, value Node) {
+	for _, p := range o.properties {
+		if p.Name == name {
+			p.Value = value
+			return
+		}
+	}
+	o.properties = append(o.properties, &Property{Name: name, Value: value})
+}
+
+func (o *objectNode) Delete(name string) bool {
+	n := len(o.properties)
+	o.properties = slices.DeleteFunc(o.properties, func(p *Property) bool {
+		return p.Name == name
+	})
+	return len(o.properties) != n
+}
+
 func (o *objectNode) MarshalJSON() ([]byte, error) {
 	var buf bytes.Buffer
 	_, err := buf.WriteRune('{')
@@ -198,9 +270,8 @@ func (o *objectNode) MarshalJSON() ([]byte, error) {
 		if i != 0 {
 			buf.WriteByte(',')
 		}
-		buf.WriteString(`"`)
-		buf.WriteString(p.Name)
-		buf.WriteString(`":`)
+		writeEscaped(&buf, p.Name)
+		buf.WriteByte(':')
 
 		if p.Value == nil {
 			buf.WriteString("null")
@@ -231,6 +302,33 @@ func (a *arrayNode) Items() []Node {
 	return a.items
 }
 
+func (a *arrayNode) Get(i int) (Node, bool) {
+	if i < 0 || i >= len(a.items) {
+		return nil, false
+	}
+	return a.items[i], true
+}
+
+func (a *arrayNode) Set(i int, value Node) bool {
+	if i < 0 || i >= len(a.items) {
+		return false
+	}
+	a.items[i] = value
+	return true
+}
+
+func (a *arrayNode) Delete(i int) bool {
+	if i < 0 || i >= len(a.items) {
+		return false
+	}
+	a.items = slices.Delete(a.items, i, i+1)
+	return true
+}
+
+func (a *arrayNode) Append(items ...Node) {
+	a.items = append(a.items, items...)
+}
+
 func (a *arrayNode) MarshalJSON() ([]byte, error) {
 	var buf bytes.Buffer
 	buf.WriteRune('[')
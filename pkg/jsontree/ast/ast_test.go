@@ -15,3 +15,117 @@ func TestMarshallJSON(t *testing.T) {
 
 	assert.Equal(t, `{"a":"hello","b":null,"c":[2,4,8]}`, string(txt))
 }
+
+func TestMarshallJSON_Escaping(t *testing.T) {
+	n := NewObjectNode([]*Property{{Name: "say \"hi\"", Value: NewTextNode("line\nbreak\t\\")}})
+
+	txt, _ := n.MarshalJSON()
+
+	assert.Equal(t, `{"say \"hi\"":"line\nbreak\t\\"}`, string(txt))
+}
+
+func TestObjectNode_SetPreservesOrder(t *testing.T) {
+	n := NewObjectNode(nil)
+	n.Set("z", NewNumberNode("1"))
+	n.Set("a", NewNumberNode("2"))
+	n.Set("z", NewNumberNode("3"))
+
+	v, ok := n.Get("z")
+	assert.True(t, ok)
+	assert.Equal(t, "3", v.(NumberNode).Value())
+	assert.Equal(t, `{"z":3,"a":2}`, marshal(t, n))
+
+	assert.True(t, n.Delete("z"))
+	assert.False(t, n.Delete("z"))
+	assert.Equal(t, `{"a":2}`, marshal(t, n))
+}
+
+func TestWalk(t *testing.T) {
+	var visited []string
+	err := Walk(sample(), func(path Pointer, n Node) error {
+		visited = append(visited, path.String()+"="+typeName(n))
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"=object", "/a=object", "/a/b=array", "/a/b/0=number", "/a/b/1=number", "/a/b/2=number",
+		"/c~1d=text", "/e~0f=boolean",
+	}, visited)
+}
+
+func TestWalk_SkipChildren(t *testing.T) {
+	var visited []string
+	err := Walk(sample(), func(path Pointer, n Node) error {
+		visited = append(visited, path.String())
+		if n.Type() == NodeTypeArray {
+			return SkipChildren
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "/a", "/a/b", "/c~1d", "/e~0f"}, visited)
+}
+
+func TestEqual(t *testing.T) {
+	reordered := NewObjectNode([]*Property{
+		{Name: "e~f", Value: NewBooleanNode(true)},
+		{Name: "c/d", Value: NewTextNode("x")},
+		{Name: "a", Value: NewObjectNode([]*Property{
+			{Name: "b", Value: NewArrayNode([]Node{NewNumberNode("1.0"), NewNumberNode("2e0"), NewNumberNode("0.3e1")})},
+		})},
+	})
+	assert.True(t, Equal(sample(), reordered))
+
+	changed := sample()
+	_, _ = Set(changed, "/a/b/-", NewNullNode())
+	assert.False(t, Equal(sample(), changed))
+
+	assert.False(t, Equal(NewNumberNode("1"), NewTextNode("1")))
+	assert.False(t, Equal(NewNumberNode("1e1000000000"), NewNumberNode("1e1000000001")))
+	assert.False(t, Equal(NewArrayNode([]Node{NewNumberNode("1"), NewNumberNode("2")}),
+		NewArrayNode([]Node{NewNumberNode("2"), NewNumberNode("1")})))
+
+	duplicates := func(values ...string) Node {
+		var props []*Property
+		for _, v := range values {
+			props = append(props, &Property{Name: "a", Value: NewNumberNode(v)})
+		}
+		return NewObjectNode(props)
+	}
+	assert.True(t, Equal(duplicates("1", "2"), duplicates("1", "2")))
+	assert.False(t, Equal(duplicates("1", "2"), duplicates("2", "1")))
+}
+
+func TestCopy(t *testing.T) {
+	original := sample()
+	c := Copy(original)
+	assert.True(t, Equal(original, c))
+
+	_, err := Set(c, "/a/b/0", NewTextNode("changed"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":{"b":[1,2,3]},"c/d":"x","e~f":true}`, marshal(t, original))
+}
+
+func TestEqual_Numbers(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"1", "1.0", true},
+		{"100", "1e2", true},
+		{"100", "1E+2", true},
+		{"0.01", "1e-2", true},
+		{"0", "-0.0", true},
+		{"-1", "1", false},
+		{"1.5", "15", false},
+		{"1e1000000000", "1e1000000001", false},
+		{"10000000000000000000000001", "10000000000000000000000000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Equal(NewNumberNode(tt.a), NewNumberNode(tt.b)))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package ast
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Equal reports whether two nodes represent the same JSON value. The order of
+// properties does not matter, except among properties of the same name. Numbers are
+// compared by their value, e.g. 1 equals 1.0 and 1e2 equals 100.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Type() {
+	case NodeTypeObject:
+		ap, bp := a.(ObjectNode).Properties(), b.(ObjectNode).Properties()
+		if len(ap) != len(bp) {
+			return false
+		}
+		values := make(map[string][]Node, len(bp))
+		for _, p := range bp {
+			values[p.Name] = append(values[p.Name], p.Value)
+		}
+		for _, p := range ap {
+			v := values[p.Name]
+			if len(v) == 0 || !Equal(p.Value, v[0]) {
+				return false
+			}
+			values[p.Name] = v[1:]
+		}
+		return true
+	case NodeTypeArray:
+		ai, bi := a.(ArrayNode).Items(), b.(ArrayNode).Items()
+		if len(ai) != len(bi) {
+			return false
+		}
+		for i := range ai {
+			if !Equal(ai[i], bi[i]) {
+				return false
+			}
+		}
+		return true
+	case NodeTypeText:
+		return a.(TextNode).Value() == b.(TextNode).Value()
+	case NodeTypeNumber:
+		return equalNumbers(a.(NumberNode).Value(), b.(NumberNode).Value())
+	case NodeTypeBoolean:
+		return a.(BooleanNode).Value() == b.(BooleanNode).Value()
+	case NodeTypeNull:
+		return true
+	}
+	return false
+}
+
+func equalNumbers(a, b string) bool {
+	if a == b {
+		return true
+	}
+	an, adigits, aexp, aok := decimal(a)
+	bn, bdigits, bexp, bok := decimal(b)
+	return aok && bok && an == bn && adigits == bdigits && aexp.Cmp(bexp) == 0
+}
+
+// decimal normalizes a number to its sign, significant digits and exponent such that
+// the number is digits * 10^exp. Unlike parsing to big.Rat or big.Float this is exact
+// and does not blow up on huge exponents such as 1e1000000000.
+func decimal(s string) (neg bool, digits string, exp *big.Int, ok bool) {
+	neg = strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	exp = new(big.Int)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		if _, ok := exp.SetString(s[i+1:], 10); !ok {
+			return false, "", nil, false
+		}
+		s = s[:i]
+	}
+
+	intPart, frac, _ := strings.Cut(s, ".")
+	digits = intPart + frac
+	if digits == "" || strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return false, "", nil, false
+	}
+	exp.Sub(exp, big.NewInt(int64(len(frac))))
+
+	trimmed := strings.TrimRight(digits, "0")
+	exp.Add(exp, big.NewInt(int64(len(digits)-len(trimmed))))
+	digits = strings.TrimLeft(trimmed, "0")
+	if digits == "" {
+		return false, "", new(big.Int), true
+	}
+	return neg, digits, exp, true
+}
+
+// Copy returns a deep copy of a node, the copy shares nothing with the original and
+// keeps its positions
+func Copy(n Node) Node {
+	if n == nil {
+		return nil
+	}
+
+	var c Node
+	switch n.Type() {
+	case NodeTypeObject:
+		props := make([]*Property, 0, len(n.(ObjectNode).Properties()))
+		for _, p := range n.(ObjectNode).Properties() {
+			props = append(props, &Property{Name: p.Name, Value: Copy(p.Value)})
+		}
+		c = NewObjectNode(props)
+	case NodeTypeArray:
+		items := make([]Node, 0, len(n.(ArrayNode).Items()))
+		for _, item := range n.(ArrayNode).Items() {
+			items = append(items, Copy(item))
+		}
+		c = NewArrayNode(items)
+	case NodeTypeText:
+		c = NewTextNode(n.(TextNode).Value())
+	case NodeTypeNumber:
+		c = NewNumberNode(n.(NumberNode).Value())
+	case NodeTypeBoolean:
+		c = NewBooleanNode(n.(BooleanNode).Value())
+	default:
+		c = NewNullNode()
+	}
+
+	SetPos(c, n.Pos())
+	return c
+}
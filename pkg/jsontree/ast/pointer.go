@@ -0,0 +1,212 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned if a path does not point to an existing node
+var ErrNotFound = errors.New("not found")
+
+// Pointer is a parsed JSON Pointer as of RFC 6901, e.g. "/items/0/name". The empty
+// pointer refers to the root node.
+type Pointer []string
+
+// ParsePointer parses a JSON Pointer, where "~1" stands for "/" and "~0" for "~"
+// within a reference token
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid pointer %q: must be empty or start with '/'", s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		if !strings.Contains(t, "~") {
+			continue
+		}
+		var sb strings.Builder
+		for j := 0; j < len(t); j++ {
+			if t[j] != '~' {
+				sb.WriteByte(t[j])
+				continue
+			}
+			if j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1') {
+				return nil, fmt.Errorf("invalid pointer %q: '~' must be followed by '0' or '1'", s)
+			}
+			if t[j+1] == '0' {
+				sb.WriteByte('~')
+			} else {
+				sb.WriteByte('/')
+			}
+			j++
+		}
+		tokens[i] = sb.String()
+	}
+	return tokens, nil
+}
+
+func (p Pointer) String() string {
+	var sb strings.Builder
+	for _, t := range p {
+		sb.WriteByte('/')
+		t = strings.ReplaceAll(t, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(t, "/", "~1"))
+	}
+	return sb.String()
+}
+
+// Append returns a new pointer with the given reference token added
+func (p Pointer) Append(token string) Pointer {
+	return append(p[:len(p):len(p)], token)
+}
+
+// Get returns the node the path points to
+func Get(root Node, path string) (Node, error) {
+	p, err := ParsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(root)
+}
+
+// Set sets the node the path points to. Array items and object properties are replaced
+// in place, new properties are appended to objects and new items to arrays with
+// either the index after the last item or "-". Missing parent objects are created.
+// The root is returned, which is the value itself for the empty path.
+func Set(root Node, path string, value Node) (Node, error) {
+	p, err := ParsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Set(root, value)
+}
+
+// Delete removes the node the path points to from its parent
+func Delete(root Node, path string) error {
+	p, err := ParsePointer(path)
+	if err != nil {
+		return err
+	}
+	return p.Delete(root)
+}
+
+// Get returns the node the pointer points to
+func (p Pointer) Get(root Node) (Node, error) {
+	n := root
+	for i, t := range p {
+		var ok bool
+		if n, ok = child(n, t); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, p[:i+1])
+		}
+	}
+	return n, nil
+}
+
+// Set is like the package level Set
+func (p Pointer) Set(root Node, value Node) (Node, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+
+	parent := root
+	for i, t := range p[:len(p)-1] {
+		next, ok := child(parent, t)
+		if !ok {
+			o, isObject := parent.(ObjectNode)
+			if !isObject {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, p[:i+1])
+			}
+			next = NewObjectNode(nil)
+			o.Set(t, next)
+		}
+		parent = next
+	}
+
+	last := p[len(p)-1]
+	switch typed := parent.(type) {
+	case ObjectNode:
+		typed.Set(last, value)
+		return root, nil
+	case ArrayNode:
+		if last == "-" {
+			typed.Append(value)
+			return root, nil
+		}
+		idx, ok := index(last)
+		if ok && idx == len(typed.Items()) {
+			typed.Append(value)
+			return root, nil
+		}
+		if ok && typed.Set(idx, value) {
+			return root, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return nil, fmt.Errorf("cannot set %s: parent is a %s", p, typeName(parent))
+}
+
+// Delete is like the package level Delete
+func (p Pointer) Delete(root Node) error {
+	if len(p) == 0 {
+		return errors.New("cannot delete the root")
+	}
+
+	parent, err := p[:len(p)-1].Get(root)
+	if err != nil {
+		return err
+	}
+
+	last := p[len(p)-1]
+	var ok bool
+	switch typed := parent.(type) {
+	case ObjectNode:
+		ok = typed.Delete(last)
+	case ArrayNode:
+		var idx int
+		if idx, ok = index(last); ok {
+			ok = typed.Delete(idx)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return nil
+}
+
+func child(n Node, token string) (Node, bool) {
+	switch typed := n.(type) {
+	case ObjectNode:
+		return typed.Get(token)
+	case ArrayNode:
+		if idx, ok := index(token); ok {
+			return typed.Get(idx)
+		}
+	}
+	return nil, false
+}
+
+// index parses an array index, which must not have leading zeros
+func index(t string) (int, bool) {
+	if t == "" || (len(t) > 1 && t[0] == '0') {
+		return 0, false
+	}
+	for _, c := range t {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	i, err := strconv.Atoi(t)
+	return i, err == nil
+}
+
+func typeName(n Node) string {
+	if n == nil {
+		return "nil"
+	}
+	return strings.ToLower(strings.TrimPrefix(n.Type().String(), "NodeType"))
+}
@@ -0,0 +1,133 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sample is {"a":{"b":[1,2,3]},"c/d":"x","e~f":true}
+func sample() Node {
+	return NewObjectNode([]*Property{
+		{Name: "a", Value: NewObjectNode([]*Property{
+			{Name: "b", Value: NewArrayNode([]Node{NewNumberNode("1"), NewNumberNode("2"), NewNumberNode("3")})},
+		})},
+		{Name: "c/d", Value: NewTextNode("x")},
+		{Name: "e~f", Value: NewBooleanNode(true)},
+	})
+}
+
+func marshal(t *testing.T, n Node) string {
+	buf, err := n.MarshalJSON()
+	assert.NoError(t, err)
+	return string(buf)
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer  string
+		expected Pointer
+	}{
+		{"", Pointer{}},
+		{"/", Pointer{""}},
+		{"/a/0", Pointer{"a", "0"}},
+		{"/c~1d", Pointer{"c/d"}},
+		{"/e~0f", Pointer{"e~f"}},
+		{"/~01", Pointer{"~1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			p, err := ParsePointer(tt.pointer)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, p)
+			assert.Equal(t, tt.pointer, p.String())
+		})
+	}
+}
+
+func TestParsePointer_Invalid(t *testing.T) {
+	for _, s := range []string{"a", "/a~", "/a~2"} {
+		_, err := ParsePointer(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"", `{"a":{"b":[1,2,3]},"c/d":"x","e~f":true}`},
+		{"/a/b", `[1,2,3]`},
+		{"/a/b/2", `3`},
+		{"/c~1d", `"x"`},
+		{"/e~0f", `true`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			n, err := Get(sample(), tt.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, marshal(t, n))
+		})
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	for _, path := range []string{"/x", "/a/b/3", "/a/b/-", "/a/b/01", "/c~1d/x"} {
+		_, err := Get(sample(), path)
+		assert.ErrorIs(t, err, ErrNotFound, path)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/a", `{"a":null,"c/d":"x","e~f":true}`},
+		{"/z", `{"a":{"b":[1,2,3]},"c/d":"x","e~f":true,"z":null}`},
+		{"/a/b/0", `{"a":{"b":[null,2,3]},"c/d":"x","e~f":true}`},
+		{"/a/b/3", `{"a":{"b":[1,2,3,null]},"c/d":"x","e~f":true}`},
+		{"/a/b/-", `{"a":{"b":[1,2,3,null]},"c/d":"x","e~f":true}`},
+		{"/x/y/z", `{"a":{"b":[1,2,3]},"c/d":"x","e~f":true,"x":{"y":{"z":null}}}`},
+		{"", `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			root, err := Set(sample(), tt.path, NewNullNode())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, marshal(t, root))
+		})
+	}
+}
+
+func TestSet_Invalid(t *testing.T) {
+	for _, path := range []string{"/a/b/4", "/a/b/x", "/a/b/5/c", "/c~1d/x"} {
+		_, err := Set(sample(), path, NewNullNode())
+		assert.Error(t, err, path)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/a", `{"c/d":"x","e~f":true}`},
+		{"/c~1d", `{"a":{"b":[1,2,3]},"e~f":true}`},
+		{"/a/b/1", `{"a":{"b":[1,3]},"c/d":"x","e~f":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			root := sample()
+			assert.NoError(t, Delete(root, tt.path))
+			assert.Equal(t, tt.expected, marshal(t, root))
+		})
+	}
+}
+
+func TestDelete_Invalid(t *testing.T) {
+	for _, path := range []string{"", "/x", "/a/b/3", "/x/y"} {
+		assert.Error(t, Delete(sample(), path), path)
+	}
+}
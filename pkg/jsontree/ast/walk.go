@@ -0,0 +1,53 @@
+package ast
+
+import (
+	"errors"
+	"strconv"
+)
+
+// SkipChildren is returned by a WalkFunc to not descend into the children of the
+// current array or object, it is not returned by Walk
+var SkipChildren = errors.New("skip children")
+
+// WalkFunc is called for each node with the pointer to it from the root
+type WalkFunc func(path Pointer, n Node) error
+
+// Walk visits all nodes depth-first, parents before their children and children in
+// the order of the array or object. Walking stops at the first error returned by fn.
+func Walk(root Node, fn WalkFunc) error {
+	err := walk(Pointer{}, root, fn)
+	if errors.Is(err, SkipChildren) {
+		return nil
+	}
+	return err
+}
+
+func walk(path Pointer, n Node, fn WalkFunc) error {
+	if err := fn(path, n); err != nil {
+		return err
+	}
+
+	switch typed := n.(type) {
+	case ObjectNode:
+		for _, p := range typed.Properties() {
+			if err := walkChild(path.Append(p.Name), p.Value, fn); err != nil {
+				return err
+			}
+		}
+	case ArrayNode:
+		for i, item := range typed.Items() {
+			if err := walkChild(path.Append(strconv.Itoa(i)), item, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkChild(path Pointer, n Node, fn WalkFunc) error {
+	err := walk(path, n, fn)
+	if err == SkipChildren {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"testing"
+
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+// ndjson generates about size bytes of NDJSON records that look like a typical
+// export, with repeating keys, escaped and non-ASCII strings and nested values
+func ndjson(size int) []byte {
+	rng := rand.New(rand.NewPCG(1, 2))
+	statuses := []string{"active", "pending", "closed"}
+	names := []string{"Zoë", "Jürgen", "O'Brien", "李雷", `Quote "Q" Smith`, "tab\tbed"}
+
+	var buf bytes.Buffer
+	for i := 1; buf.Len() < size; i++ {
+		record := map[string]any{
+			"id":      i,
+			"name":    names[rng.IntN(len(names))],
+			"status":  statuses[rng.IntN(len(statuses))],
+			"score":   rng.Float64() * 100,
+			"active":  rng.IntN(2) == 0,
+			"manager": nil,
+			"tags":    []string{"a", "b", "c"}[:rng.IntN(4)],
+			"address": map[string]any{
+				"street": fmt.Sprintf("%d Main Street", rng.IntN(1000)),
+				"zip":    fmt.Sprintf("%05d", rng.IntN(100000)),
+			},
+			"orders": []map[string]any{
+				{"sku": "X-1", "qty": rng.IntN(10), "price": 9.99},
+				{"sku": "Y-2", "qty": rng.IntN(10), "price": 1e3},
+			},
+		}
+		b, err := json.Marshal(record)
+		if err != nil {
+			panic(err)
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// benchmarkInput is only generated when benchmarks run
+var benchmarkInput = sync.OnceValue(func() []byte {
+	return ndjson(4 << 20)
+})
+
+func BenchmarkDecoder(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		dec := NewDecoder(bytes.NewReader(input))
+		for {
+			_, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkDecoder_JSON5(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		dec, err := NewDecoderFormat(bytes.NewReader(input), FormatJSON5)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			_, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLexer(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		l := lexer.NewLexer(bytes.NewReader(input))
+		for {
+			token, err := l.Token()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if token.Type == lexer.TokenTypeEOF {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkEventReader(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		r := NewEventReader(lexer.NewLexer(bytes.NewReader(input)))
+		for {
+			_, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkEncodingJSON decodes the same input with encoding/json for comparison
+func BenchmarkEncodingJSON(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		dec := json.NewDecoder(bytes.NewReader(input))
+		dec.UseNumber()
+		for {
+			var v any
+			err := dec.Decode(&v)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+// Format is the syntax of the records read by a Decoder
+type Format string
+
+const (
+	FormatJSON Format = "json"
+
+	// FormatJSONC is JSON with comments and trailing commas
+	FormatJSONC Format = "jsonc"
+
+	// FormatJSON5 is JSON5, see lexer.ModeJSON5
+	FormatJSON5 Format = "json5"
+
+	// FormatYAML is a stream of YAML documents, each document is a record
+	FormatYAML Format = "yaml"
+)
+
+// Decoder reads a stream of JSON records such as NDJSON
+type Decoder struct {
+	l      lexer.Lexer
+	yaml   *yamlDecoder
+	record int
+
+	// SkipInvalid skips records with syntax errors rather than failing, decoding
+	// resumes on the line after the one the bad record started on. This recovers
+	// from bad records in NDJSON, where every record is on a line of its own, a
+	// record which is not complete at the end of its line is skipped with an
+	// "unexpected end of line" error. It does not apply to YAML.
+	SkipInvalid bool
+
+	// OnSkip is called with the error of every skipped record
+	OnSkip func(err error)
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{l: lexer.NewLexer(r)}
+}
+
+// NewDecoderFormat creates a decoder for records in the given format, the empty
+// format is JSON
+func NewDecoderFormat(r io.Reader, format Format) (*Decoder, error) {
+	if format == FormatYAML {
+		return &Decoder{yaml: newYAMLDecoder(r)}, nil
+	}
+	mode, ok := format.lexerMode()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return &Decoder{l: lexer.NewLexerMode(r, mode)}, nil
+}
+
+// lexerMode returns the lexer mode of the JSON formats
+func (f Format) lexerMode() (lexer.Mode, bool) {
+	switch f {
+	case FormatJSON, "":
+		return lexer.ModeJSON, true
+	case FormatJSONC:
+		return lexer.ModeJSONC, true
+	case FormatJSON5:
+		return lexer.ModeJSON5, true
+	}
+	return 0, false
+}
+
+// Decode parses the next record, io.EOF is returned at the end of the stream.
+// Errors are prefixed with the number of the record, starting at 1.
+func (d *Decoder) Decode() (ast.Node, error) {
+	for {
+		n, line, err := d.decode()
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+
+		var syntaxErr *lexer.SyntaxError
+		if !d.SkipInvalid || !errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("record %d: %w", d.record, err)
+		}
+
+		resumed, recoverErr := d.l.Recover(line)
+		if recoverErr != nil {
+			return nil, recoverErr
+		}
+		// a record that goes on past its line is cut off there, the error was found
+		// in what follows and is reported with the record it belongs to
+		if resumed <= syntaxErr.Pos.Line {
+			err = fmt.Errorf("unexpected end of line %d", line)
+		}
+		if d.OnSkip != nil {
+			d.OnSkip(fmt.Errorf("record %d: %w", d.record, err))
+		}
+	}
+}
+
+// Record is the number of the record decoded last
+func (d *Decoder) Record() int {
+	return d.record
+}
+
+// decode parses the next record and returns the line it started on
+func (d *Decoder) decode() (ast.Node, int, error) {
+	if d.yaml != nil {
+		n, err := d.yaml.decode()
+		if err != io.EOF {
+			d.record++
+		}
+		return n, 0, err
+	}
+
+	token, err := d.l.Peek()
+	if err != nil {
+		var syntaxErr *lexer.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			d.record++
+			return nil, syntaxErr.Pos.Line, err
+		}
+		return nil, 0, err
+	}
+	if token.Type == lexer.TokenTypeEOF {
+		return nil, 0, io.EOF
+	}
+
+	d.record++
+	n, err := Parse(d.l)
+	return n, token.Pos.Line, err
+}
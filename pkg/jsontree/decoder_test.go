@@ -0,0 +1,195 @@
+package jsontree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+const ndjsonWithErrors = `{"a":1}
+{"a": tru}
+{"a":3
+{"a":4}
+[1, 2] [3]
+{"a":5}
+`
+
+func TestDecoder_Decode(t *testing.T) {
+	d := NewDecoder(strings.NewReader(ndjsonWithErrors))
+
+	n, err := d.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n.Pos().Line)
+
+	_, err = d.Decode()
+	assert.EqualError(t, err, "record 2: invalid literal \"tru\" at line 2, column 7\n    {\"a\": tru}\n          ^")
+}
+
+func TestDecoder_Decode_SkipInvalid(t *testing.T) {
+	d := NewDecoder(strings.NewReader(ndjsonWithErrors))
+	d.SkipInvalid = true
+
+	var skipped []string
+	d.OnSkip = func(err error) {
+		skipped = append(skipped, strings.SplitN(err.Error(), "\n", 2)[0])
+	}
+
+	var records []string
+	var lines []int
+	for {
+		n, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records = append(records, marshal(t, n))
+		lines = append(lines, n.Pos().Line)
+	}
+
+	assert.Equal(t, []string{`{"a":1}`, `{"a":4}`, `[1,2]`, `[3]`, `{"a":5}`}, records)
+	assert.Equal(t, []int{1, 4, 5, 5, 6}, lines)
+	assert.Equal(t, []string{
+		`record 2: invalid literal "tru" at line 2, column 7`,
+		`record 3: unexpected end of line 3`,
+	}, skipped)
+	assert.Equal(t, 7, d.Record())
+}
+
+func TestDecoder_Decode_SkipInvalid_TruncatedRecord(t *testing.T) {
+	d := NewDecoder(strings.NewReader("{\"a\":1}\n{\"a\":\n{\"b\":2}\nnot json\n{\"c\":3}\n"))
+	d.SkipInvalid = true
+
+	var skipped []string
+	d.OnSkip = func(err error) {
+		skipped = append(skipped, strings.SplitN(err.Error(), "\n", 2)[0])
+	}
+
+	var records []string
+	for {
+		n, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records = append(records, fmt.Sprintf("%d: %s", d.Record(), marshal(t, n)))
+	}
+
+	assert.Equal(t, []string{`1: {"a":1}`, `3: {"b":2}`, `5: {"c":3}`}, records)
+	assert.Equal(t, []string{
+		`record 2: unexpected end of line 2`,
+		`record 4: invalid literal "not" at line 4, column 1`,
+	}, skipped)
+}
+
+func TestParse_Positions(t *testing.T) {
+	n, err := Parse(NewDecoder(strings.NewReader("{\n  \"a\": [true, \"x\"]\n}")).l)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, n.Pos().Column)
+	arr := n.(ast.ObjectNode).Properties()[0].Value
+	assert.Equal(t, 2, arr.Pos().Line)
+	assert.Equal(t, 8, arr.Pos().Column)
+	assert.Equal(t, int64(16), arr.(ast.ArrayNode).Items()[1].Pos().Offset)
+}
+
+func marshal(t *testing.T, n ast.Node) string {
+	b, err := n.MarshalJSON()
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func decodeAll(t *testing.T, d *Decoder) []string {
+	var records []string
+	for {
+		n, err := d.Decode()
+		if err == io.EOF {
+			return records
+		}
+		if !assert.NoError(t, err) {
+			return records
+		}
+		records = append(records, marshal(t, n))
+	}
+}
+
+func TestNewDecoderFormat(t *testing.T) {
+	tests := []struct {
+		format   Format
+		input    string
+		expected []string
+	}{
+		{FormatJSON, `{"a":1} [2]`, []string{`{"a":1}`, `[2]`}},
+		{FormatJSONC, "// config\n{\"a\": [1, 2,], /* b */ \"b\": null,}\n", []string{`{"a":[1,2],"b":null}`}},
+		{FormatJSON5, `{a: 'x', 'b': +.5,} [0x10]`, []string{`{"a":"x","b":0.5}`, `[16]`}},
+		{FormatYAML, `
+z: 1
+a: [true, null, 1.50, 0x1F, "2"]
+nested:
+  date: 2024-01-02
+  text: |
+    multi
+---
+- x
+`, []string{`{"z":1,"a":[true,null,1.50,31,"2"],"nested":{"date":"2024-01-02","text":"multi\n"}}`, `["x"]`}},
+		{FormatYAML, ``, nil},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			d, err := NewDecoderFormat(strings.NewReader(tt.input), tt.format)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, decodeAll(t, d))
+		})
+	}
+
+	_, err := NewDecoderFormat(strings.NewReader(""), "xml")
+	assert.EqualError(t, err, `unknown format "xml"`)
+}
+
+func TestNewDecoderFormat_YAMLAnchors(t *testing.T) {
+	input := `
+defaults: &defaults
+  adapter: postgres
+  host: localhost
+development:
+  <<: *defaults
+  host: dev.example.com
+  ports: &ports [1, 2]
+  more: *ports
+`
+	d, err := NewDecoderFormat(strings.NewReader(input), FormatYAML)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"defaults":{"adapter":"postgres","host":"localhost"},"development":{"host":"dev.example.com","ports":[1,2],"more":[1,2],"adapter":"postgres"}}`,
+	}, decodeAll(t, d))
+}
+
+func TestNewDecoderFormat_YAMLErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a: .inf", "record 1: .inf cannot be represented in JSON at line 1, column 4"},
+		{"a: [1\n", "record 1: yaml: line 1: did not find expected ',' or ']'"},
+		{"{[1]: 2}", "record 1: unsupported non-scalar key at line 1, column 2"},
+		{`a: &a ["x","x","x","x","x","x","x","x","x"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g]
+`, "record 1: document expands too many aliases"},
+	}
+	for _, tt := range tests {
+		d, err := NewDecoderFormat(strings.NewReader(tt.input), FormatYAML)
+		assert.NoError(t, err)
+
+		_, err = d.Decode()
+		assert.EqualError(t, err, tt.expected)
+	}
+}
@@ -0,0 +1,109 @@
+// Package diff compares jsontree nodes and streams of records. Differences are
+// described by JSON Patch operations as of RFC 6902.
+package diff
+
+import (
+	"strconv"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+// Op is the kind of a JSON Patch operation
+type Op string
+
+const (
+	OpAdd     Op = "add"
+	OpRemove  Op = "remove"
+	OpReplace Op = "replace"
+)
+
+// Operation is a JSON Patch operation, Value is nil for OpRemove
+type Operation struct {
+	Op    Op
+	Path  ast.Pointer
+	Value ast.Node
+}
+
+// Node returns the operation as it is written in a JSON Patch, e.g.
+// {"op":"replace","path":"/name","value":"Alice"}
+func (o *Operation) Node() ast.ObjectNode {
+	n := ast.NewObjectNode([]*ast.Property{
+		{Name: "op", Value: ast.NewTextNode(string(o.Op))},
+		{Name: "path", Value: ast.NewTextNode(o.Path.String())},
+	})
+	if o.Op != OpRemove {
+		n.Set("value", o.Value)
+	}
+	return n
+}
+
+// Patch returns the operations as a JSON Patch document
+func Patch(ops []*Operation) ast.ArrayNode {
+	items := make([]ast.Node, 0, len(ops))
+	for _, o := range ops {
+		items = append(items, o.Node())
+	}
+	return ast.NewArrayNode(items)
+}
+
+// Diff returns the operations which turn a into b when applied in order, none if
+// they are equal as of ast.Equal. Objects are compared property by property and
+// arrays item by item, items inserted or removed in the middle of an array show up
+// as changes of all the items after them. Of properties with the same name only the
+// first one is compared.
+func Diff(a, b ast.Node) []*Operation {
+	var ops []*Operation
+	diff(ast.Pointer{}, a, b, &ops)
+	return ops
+}
+
+func diff(path ast.Pointer, a, b ast.Node, ops *[]*Operation) {
+	if ast.Equal(a, b) {
+		return
+	}
+	if a == nil || b == nil || a.Type() != b.Type() {
+		*ops = append(*ops, &Operation{Op: OpReplace, Path: path, Value: b})
+		return
+	}
+
+	switch a.Type() {
+	case ast.NodeTypeObject:
+		diffObjects(path, a.(ast.ObjectNode), b.(ast.ObjectNode), ops)
+	case ast.NodeTypeArray:
+		diffArrays(path, a.(ast.ArrayNode).Items(), b.(ast.ArrayNode).Items(), ops)
+	default:
+		*ops = append(*ops, &Operation{Op: OpReplace, Path: path, Value: b})
+	}
+}
+
+// diffObjects lists removed and changed properties in the order of a, then added
+// ones in the order of b
+func diffObjects(path ast.Pointer, a, b ast.ObjectNode, ops *[]*Operation) {
+	for _, p := range a.Properties() {
+		if v, ok := b.Get(p.Name); ok {
+			diff(path.Append(p.Name), p.Value, v, ops)
+		} else {
+			*ops = append(*ops, &Operation{Op: OpRemove, Path: path.Append(p.Name)})
+		}
+	}
+	for _, p := range b.Properties() {
+		if _, ok := a.Get(p.Name); !ok {
+			*ops = append(*ops, &Operation{Op: OpAdd, Path: path.Append(p.Name), Value: p.Value})
+		}
+	}
+}
+
+// diffArrays compares the common items, then appends the extra items of b or removes
+// those of a starting from the end so the indices of the others stay valid
+func diffArrays(path ast.Pointer, a, b []ast.Node, ops *[]*Operation) {
+	common := min(len(a), len(b))
+	for i := range common {
+		diff(path.Append(strconv.Itoa(i)), a[i], b[i], ops)
+	}
+	for i := common; i < len(b); i++ {
+		*ops = append(*ops, &Operation{Op: OpAdd, Path: path.Append(strconv.Itoa(i)), Value: b[i]})
+	}
+	for i := len(a) - 1; i >= common; i-- {
+		*ops = append(*ops, &Operation{Op: OpRemove, Path: path.Append(strconv.Itoa(i))})
+	}
+}
@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/printer"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected string
+	}{
+		{"equal", `{"a":1,"b":[1,2]}`, `{"b":[1,2],"a":1.0}`, `[]`},
+		{"replace root", `1`, `"1"`, `[{"op":"replace","path":"","value":"1"}]`},
+		{
+			"properties", `{"a":1,"b":{"c":true},"d":null}`, `{"e":2,"b":{"c":false},"a":1}`,
+			`[{"op":"replace","path":"/b/c","value":false},{"op":"remove","path":"/d"},{"op":"add","path":"/e","value":2}]`,
+		},
+		{
+			"array grows", `{"a":[1,2]}`, `{"a":[1,3,4,5]}`,
+			`[{"op":"replace","path":"/a/1","value":3},{"op":"add","path":"/a/2","value":4},{"op":"add","path":"/a/3","value":5}]`,
+		},
+		{
+			"array shrinks", `[1,2,3,4]`, `[0,2]`,
+			`[{"op":"replace","path":"/0","value":0},{"op":"remove","path":"/3"},{"op":"remove","path":"/2"}]`,
+		},
+		{"type changes", `{"a/b":{"c":1}}`, `{"a/b":[1]}`, `[{"op":"replace","path":"/a~1b","value":[1]}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := parse(t, test.a), parse(t, test.b)
+			ops := Diff(a, b)
+			assert.Equal(t, test.expected, format(t, Patch(ops)))
+
+			patched := apply(t, ast.Copy(a), ops)
+			assert.True(t, ast.Equal(b, patched), "patched: %s", format(t, patched))
+		})
+	}
+}
+
+func TestRecords(t *testing.T) {
+	before := `{"id":1,"name":"a"}
+{"id":2,"name":"b","tags":["x"]}
+{"id":3,"name":"c"}
+`
+	after := `{"id":2,"name":"b","tags":["x","y"]}
+{"id":4,"name":"d"}
+{"name":"a","id":1}
+`
+
+	var changes []string
+	err := Records(decoder(before), decoder(after), &Options{Key: []string{"id"}}, func(c *Change) error {
+		changes = append(changes, format(t, c.Node()))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"change":"changed","key":{"id":2},"patch":[{"op":"add","path":"/tags/1","value":"y"}]}`,
+		`{"change":"added","key":{"id":4},"record":{"id":4,"name":"d"}}`,
+		`{"change":"removed","key":{"id":3},"record":{"id":3,"name":"c"}}`,
+	}, changes)
+}
+
+func TestRecords_CompositeKey(t *testing.T) {
+	before := `{"user":{"id":1},"region":"eu","n":1}` + "\n" + `{"user":{"id":1},"region":"us","n":1}`
+	after := `{"user":{"id":1},"region":"us","n":2}` + "\n" + `{"user":{"id":1},"region":"eu","n":1}`
+
+	var changes []string
+	err := Records(decoder(before), decoder(after), &Options{Key: []string{"/user/id", "region"}}, func(c *Change) error {
+		changes = append(changes, format(t, c.Node()))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"change":"changed","key":{"/user/id":1,"region":"us"},"patch":[{"op":"replace","path":"/n","value":2}]}`,
+	}, changes)
+}
+
+func TestRecords_WithError(t *testing.T) {
+	tests := []struct {
+		name             string
+		before, after    string
+		key              []string
+		expectedErrorMsg string
+	}{
+		{"no key", `{}`, `{}`, nil, "no key given"},
+		{"invalid pointer", `{}`, `{}`, []string{"/a~2"}, `invalid pointer "/a~2": '~' must be followed by '0' or '1'`},
+		{"missing key", `{"id":1}`, `{"id":1} {"ID":2}`, []string{"id"}, `new record 2: missing key "id"`},
+		{"duplicate key", `{"id":1} {"id":"1"} {"id":1}`, ``, []string{"id"}, `old record 3: duplicate key {"id":1}`},
+		{"invalid json", `{"id":1}`, `{"id":}`, []string{"id"}, "new record 1: expected value but got '}' at line 1, column 7\n    {\"id\":}\n          ^"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Records(decoder(test.before), decoder(test.after), &Options{Key: test.key}, func(c *Change) error {
+				return nil
+			})
+			assert.EqualError(t, err, test.expectedErrorMsg)
+		})
+	}
+}
+
+func decoder(s string) *jsontree.Decoder {
+	return jsontree.NewDecoder(strings.NewReader(s))
+}
+
+func parse(t *testing.T, s string) ast.Node {
+	n, err := decoder(s).Decode()
+	assert.NoError(t, err)
+	return n
+}
+
+func format(t *testing.T, n ast.Node) string {
+	var buf bytes.Buffer
+	assert.NoError(t, (&printer.Config{}).Fprint(&buf, n))
+	return buf.String()
+}
+
+// apply applies a JSON Patch of the operations Diff returns
+func apply(t *testing.T, root ast.Node, ops []*Operation) ast.Node {
+	for _, o := range ops {
+		var err error
+		switch o.Op {
+		case OpAdd, OpReplace:
+			root, err = o.Path.Set(root, o.Value)
+		case OpRemove:
+			err = o.Path.Delete(root)
+		}
+		assert.NoError(t, err)
+	}
+	return root
+}
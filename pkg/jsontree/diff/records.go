@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/printer"
+)
+
+// Kind is how a record differs between two streams
+type Kind string
+
+const (
+	KindAdded   Kind = "added"
+	KindRemoved Kind = "removed"
+	KindChanged Kind = "changed"
+)
+
+// Change is a record that was added, removed or changed
+type Change struct {
+	Kind Kind
+
+	// Key holds the key properties of the record
+	Key ast.ObjectNode
+
+	// Record is the added or removed record, nil for changed ones
+	Record ast.Node
+
+	// Patch turns the old record into the new one, only for changed records
+	Patch []*Operation
+}
+
+// Node returns the change as it is written by 'tb jsondiff', e.g.
+// {"change":"changed","key":{"id":1},"patch":[{"op":"remove","path":"/a"}]}
+func (c *Change) Node() ast.ObjectNode {
+	n := ast.NewObjectNode([]*ast.Property{
+		{Name: "change", Value: ast.NewTextNode(string(c.Kind))},
+		{Name: "key", Value: c.Key},
+	})
+	if c.Kind == KindChanged {
+		n.Set("patch", Patch(c.Patch))
+	} else {
+		n.Set("record", c.Record)
+	}
+	return n
+}
+
+type Options struct {
+	// Key lists the properties which identify a record, names starting with '/' are
+	// JSON Pointers into the record, e.g. '/user/id'. Keys are compared by their JSON
+	// text, i.e. 1 and 1.0 are different keys.
+	Key []string
+}
+
+// Records matches the old records before and the new ones after by their key and calls
+// emit for every record that was added, removed or changed. The old records are held
+// in memory while the new ones are streamed, changed and added records are reported in
+// the order of the new stream and removed ones at the end in the order of the old
+// stream. Keys must be unique within each stream.
+func Records(before, after *jsontree.Decoder, opts *Options, emit func(*Change) error) error {
+	k, err := newKeyer(opts.Key)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		key    ast.ObjectNode
+		record ast.Node
+		seen   bool
+	}
+	var entries []*entry
+	byKey := map[string]*entry{}
+	err = k.each(before, func(id string, key ast.ObjectNode, n ast.Node) error {
+		if _, ok := byKey[id]; ok {
+			return fmt.Errorf("duplicate key %s", id)
+		}
+		e := &entry{key: key, record: n}
+		byKey[id] = e
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("old %w", err)
+	}
+
+	seen := map[string]bool{}
+	err = k.each(after, func(id string, key ast.ObjectNode, n ast.Node) error {
+		if seen[id] {
+			return fmt.Errorf("duplicate key %s", id)
+		}
+		seen[id] = true
+
+		e, ok := byKey[id]
+		if !ok {
+			return emit(&Change{Kind: KindAdded, Key: key, Record: n})
+		}
+		e.seen = true
+		if ops := Diff(e.record, n); len(ops) > 0 {
+			return emit(&Change{Kind: KindChanged, Key: key, Patch: ops})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("new %w", err)
+	}
+
+	for _, e := range entries {
+		if e.seen {
+			continue
+		}
+		if err := emit(&Change{Kind: KindRemoved, Key: e.key, Record: e.record}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyer extracts the key of records
+type keyer struct {
+	names []string
+	paths []ast.Pointer
+}
+
+func newKeyer(names []string) (*keyer, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no key given")
+	}
+
+	k := &keyer{names: names}
+	for _, name := range names {
+		path := ast.Pointer{name}
+		if strings.HasPrefix(name, "/") {
+			var err error
+			if path, err = ast.ParsePointer(name); err != nil {
+				return nil, err
+			}
+		}
+		k.paths = append(k.paths, path)
+	}
+	return k, nil
+}
+
+// each calls fn with every record and its key, errors are prefixed with the record
+func (k *keyer) each(d *jsontree.Decoder, fn func(id string, key ast.ObjectNode, n ast.Node) error) error {
+	for {
+		n, err := d.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		id, key, err := k.key(n)
+		if err == nil {
+			err = fn(id, key, n)
+		}
+		if err != nil {
+			return fmt.Errorf("record %d: %w", d.Record(), err)
+		}
+	}
+}
+
+// key returns the key properties of a record and their compact JSON text
+func (k *keyer) key(n ast.Node) (string, ast.ObjectNode, error) {
+	key := ast.NewObjectNode(nil)
+	for i, path := range k.paths {
+		v, err := path.Get(n)
+		if errors.Is(err, ast.ErrNotFound) {
+			return "", nil, fmt.Errorf("missing key %q", k.names[i])
+		} else if err != nil {
+			return "", nil, err
+		}
+		key.Set(k.names[i], v)
+	}
+
+	var id bytes.Buffer
+	if err := (&printer.Config{}).Fprint(&id, key); err != nil {
+		return "", nil, err
+	}
+	return id.String(), key, nil
+}
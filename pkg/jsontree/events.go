@@ -0,0 +1,320 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+//go:generate stringer -type=EventType
+type EventType int
+
+const (
+	EventTypeUnknown EventType = iota
+	EventTypeStartObject
+	EventTypeEndObject
+	EventTypeStartArray
+	EventTypeEndArray
+
+	// EventTypeKey is a property name, it is followed by the events of its value
+	EventTypeKey
+
+	// EventTypeValue is a text, number, boolean or null value
+	EventTypeValue
+)
+
+type Event struct {
+	Type EventType
+
+	// Key is the property name of an EventTypeKey
+	Key string
+
+	// Value is the node of an EventTypeValue
+	Value ast.Node
+
+	Pos lexer.Position
+}
+
+type containerState int
+
+const (
+	// stateFirst is right after the opening bracket or brace
+	stateFirst containerState = iota
+	// stateValue is after a property name, where its value is next
+	stateValue
+	// stateAfterValue is where a comma or the end of the container is next
+	stateAfterValue
+	// stateAfterComma is where the next element or property name is next
+	stateAfterComma
+)
+
+type container struct {
+	object bool
+	state  containerState
+}
+
+func (c *container) closing() lexer.TokenType {
+	if c.object {
+		return lexer.TokenTypeClosingBrace
+	}
+	return lexer.TokenTypeClosingBracket
+}
+
+// EventReader pulls a stream of JSON values apart into events, without holding
+// more than the current nesting in memory. Whole values can be read as nodes in
+// between, for example to materialize the elements of a huge array one by one.
+type EventReader struct {
+	l     lexer.Lexer
+	stack []*container
+}
+
+func NewEventReader(l lexer.Lexer) *EventReader {
+	return &EventReader{l: l}
+}
+
+// Next reads the next event, io.EOF is returned once all top-level values are read
+func (r *EventReader) Next() (Event, error) {
+	if err := r.separator(); err != nil {
+		return Event{}, err
+	}
+
+	tok, err := r.l.Token()
+	if err != nil {
+		return Event{}, err
+	}
+
+	top := r.top()
+	if top == nil {
+		if tok.Type == lexer.TokenTypeEOF {
+			return Event{}, io.EOF
+		}
+		return r.value(tok)
+	}
+
+	if tok.Type == top.closing() && (top.state == stateFirst || top.state == stateAfterValue) {
+		r.stack = r.stack[:len(r.stack)-1]
+		r.afterValue()
+		if top.object {
+			return Event{Type: EventTypeEndObject, Pos: tok.Pos}, nil
+		}
+		return Event{Type: EventTypeEndArray, Pos: tok.Pos}, nil
+	}
+
+	if top.object && top.state != stateValue {
+		if tok.Type != lexer.TokenTypeText {
+			return Event{}, r.l.Errorf(tok.Pos, "expected property name but got %v", tok)
+		}
+		if err := skipToken(r.l, lexer.TokenTypeColon); err != nil {
+			return Event{}, err
+		}
+		top.state = stateValue
+		return Event{Type: EventTypeKey, Key: tok.Value, Pos: tok.Pos}, nil
+	}
+
+	return r.value(tok)
+}
+
+// More reports whether another element or property follows in the current array or
+// object, or at the top-level whether there is another value before the end of input
+func (r *EventReader) More() (bool, error) {
+	if err := r.separator(); err != nil {
+		return false, err
+	}
+
+	tok, err := r.l.Peek()
+	if err != nil {
+		return false, err
+	}
+
+	top := r.top()
+	switch {
+	case top == nil:
+		return tok.Type != lexer.TokenTypeEOF, nil
+	case top.state == stateAfterComma:
+		return true, nil
+	}
+	return tok.Type != top.closing(), nil
+}
+
+// Node reads the next value as a whole, e.g. the next element of an array. Within an
+// object the property name must be read with Next before.
+func (r *EventReader) Node() (ast.Node, error) {
+	if err := r.separator(); err != nil {
+		return nil, err
+	}
+
+	top := r.top()
+	if top != nil && top.object && top.state != stateValue {
+		return nil, errors.New("expected a property name, read it with Next first")
+	}
+
+	if top == nil {
+		tok, err := r.l.Peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.Type == lexer.TokenTypeEOF {
+			return nil, io.EOF
+		}
+	}
+
+	n, err := parse(r.l, len(r.stack))
+	if err != nil {
+		return nil, err
+	}
+	r.afterValue()
+	return n, nil
+}
+
+// Depth is the number of arrays and objects the reader currently is in
+func (r *EventReader) Depth() int {
+	return len(r.stack)
+}
+
+func (r *EventReader) top() *container {
+	if len(r.stack) == 0 {
+		return nil
+	}
+	return r.stack[len(r.stack)-1]
+}
+
+// separator consumes the comma that is due before the next element or property
+func (r *EventReader) separator() error {
+	top := r.top()
+	if top == nil || top.state != stateAfterValue {
+		return nil
+	}
+
+	tok, err := r.l.Peek()
+	if err != nil {
+		return err
+	}
+	switch tok.Type {
+	case lexer.TokenTypeComma:
+		top.state = stateAfterComma
+		_, err = r.l.Token()
+		return err
+	case top.closing():
+		return nil
+	}
+	return r.l.Errorf(tok.Pos, "expected ',' or %v but got %v", lexer.Token{Type: top.closing()}, tok)
+}
+
+func (r *EventReader) afterValue() {
+	if top := r.top(); top != nil {
+		top.state = stateAfterValue
+	}
+}
+
+func (r *EventReader) value(tok lexer.Token) (Event, error) {
+	var n ast.Node
+	switch tok.Type {
+	case lexer.TokenTypeOpeningBrace, lexer.TokenTypeOpeningBracket:
+		if len(r.stack) >= MaxDepth {
+			return Event{}, r.l.Errorf(tok.Pos, "exceeded maximum nesting depth of %d", MaxDepth)
+		}
+		object := tok.Type == lexer.TokenTypeOpeningBrace
+		r.stack = append(r.stack, &container{object: object})
+		if object {
+			return Event{Type: EventTypeStartObject, Pos: tok.Pos}, nil
+		}
+		return Event{Type: EventTypeStartArray, Pos: tok.Pos}, nil
+	case lexer.TokenTypeText:
+		n = ast.NewTextNode(tok.Value)
+	case lexer.TokenTypePrimitiveNumber:
+		n = ast.NewNumberNode(tok.Value)
+	case lexer.TokenTypePrimitiveText:
+		switch tok.Value {
+		case "true":
+			n = ast.NewBooleanNode(true)
+		case "false":
+			n = ast.NewBooleanNode(false)
+		default:
+			n = ast.NewNullNode()
+		}
+	default:
+		return Event{}, r.l.Errorf(tok.Pos, "expected value but got %v", tok)
+	}
+
+	ast.SetPos(n, tok.Pos)
+	r.afterValue()
+	return Event{Type: EventTypeValue, Value: n, Pos: tok.Pos}, nil
+}
+
+// ArrayDecoder decodes the elements of a top-level array one at a time, so that an
+// array of millions of records never needs to be held in memory as a whole
+type ArrayDecoder struct {
+	r      *EventReader
+	state  int
+	record int
+}
+
+const (
+	arrayNotStarted = iota
+	arrayStarted
+	arrayDone
+)
+
+func NewArrayDecoder(r io.Reader) *ArrayDecoder {
+	return &ArrayDecoder{r: NewEventReader(lexer.NewLexer(r))}
+}
+
+// NewArrayDecoderFormat creates a decoder for a top-level array in the given format,
+// which must be one of the JSON formats
+func NewArrayDecoderFormat(r io.Reader, format Format) (*ArrayDecoder, error) {
+	mode, ok := format.lexerMode()
+	if !ok {
+		return nil, fmt.Errorf("cannot stream arrays of format %q", format)
+	}
+	return &ArrayDecoder{r: NewEventReader(lexer.NewLexerMode(r, mode))}, nil
+}
+
+// Decode returns the next element of the array, io.EOF is returned after the last one.
+// Errors are prefixed with the number of the element, starting at 1.
+func (d *ArrayDecoder) Decode() (ast.Node, error) {
+	switch d.state {
+	case arrayDone:
+		return nil, io.EOF
+	case arrayNotStarted:
+		ev, err := d.r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if ev.Type != EventTypeStartArray {
+			return nil, d.r.l.Errorf(ev.Pos, "expected an array but got %v", ev.Type)
+		}
+		d.state = arrayStarted
+	}
+
+	more, err := d.r.More()
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", d.record+1, err)
+	}
+	if !more {
+		d.state = arrayDone
+		if _, err := d.r.Next(); err != nil {
+			return nil, err
+		}
+		if tok, err := d.r.l.Token(); err != nil {
+			return nil, err
+		} else if tok.Type != lexer.TokenTypeEOF {
+			return nil, d.r.l.Errorf(tok.Pos, "expected end of input after the array but got %v", tok)
+		}
+		return nil, io.EOF
+	}
+
+	d.record++
+	n, err := d.r.Node()
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", d.record, err)
+	}
+	return n, nil
+}
+
+// Record is the number of the element decoded last
+func (d *ArrayDecoder) Record() int {
+	return d.record
+}
@@ -0,0 +1,209 @@
+package jsontree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+func readEvents(t *testing.T, raw string) ([]string, error) {
+	r := NewEventReader(lexer.NewLexer(strings.NewReader(raw)))
+
+	var events []string
+	for {
+		ev, err := r.Next()
+		if err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return events, err
+		}
+
+		switch ev.Type {
+		case EventTypeKey:
+			events = append(events, "key "+ev.Key)
+		case EventTypeValue:
+			events = append(events, "value "+marshal(t, ev.Value))
+		default:
+			events = append(events, ev.Type.String())
+		}
+	}
+}
+
+func TestEventReader_Next(t *testing.T) {
+	events, err := readEvents(t, `{"a":[1,{"b":null}],"c":"x","d":{}} [true, []]`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"EventTypeStartObject",
+		"key a",
+		"EventTypeStartArray",
+		"value 1",
+		"EventTypeStartObject",
+		"key b",
+		"value null",
+		"EventTypeEndObject",
+		"EventTypeEndArray",
+		"key c",
+		`value "x"`,
+		"key d",
+		"EventTypeStartObject",
+		"EventTypeEndObject",
+		"EventTypeEndObject",
+		"EventTypeStartArray",
+		"value true",
+		"EventTypeStartArray",
+		"EventTypeEndArray",
+		"EventTypeEndArray",
+	}, events)
+}
+
+func TestEventReader_Next_Errors(t *testing.T) {
+	tests := []struct {
+		raw              string
+		expectedErrorMsg string
+	}{
+		{raw: "[1 2]", expectedErrorMsg: "expected ',' or ']' but got number 2 at line 1, column 4"},
+		{raw: "[1,]", expectedErrorMsg: "expected value but got ']' at line 1, column 4"},
+		{raw: `{"a" 1}`, expectedErrorMsg: "expected ':' but got number 1 at line 1, column 6"},
+		{raw: `{,}`, expectedErrorMsg: "expected property name but got ',' at line 1, column 2"},
+		{raw: `{"a":1,}`, expectedErrorMsg: "expected property name but got '}' at line 1, column 8"},
+		{raw: `{"a":1]`, expectedErrorMsg: "expected ',' or '}' but got ']' at line 1, column 7"},
+		{raw: `[`, expectedErrorMsg: "expected value but got end of input at line 1, column 2"},
+		{raw: `]`, expectedErrorMsg: "expected value but got ']' at line 1, column 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			_, err := readEvents(t, tt.raw)
+
+			var syntaxErr *lexer.SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr) {
+				syntaxErr.Snippet = ""
+				assert.EqualError(t, syntaxErr, tt.expectedErrorMsg)
+			}
+		})
+	}
+}
+
+func TestEventReader_Node(t *testing.T) {
+	r := NewEventReader(lexer.NewLexer(strings.NewReader(`{"meta":{"n":2},"items":[{"id":1},{"id":2}]}`)))
+
+	ev, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, EventTypeStartObject, ev.Type)
+
+	_, err = r.Node()
+	assert.Error(t, err, "property name must be read first")
+
+	ev, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "meta", ev.Key)
+
+	meta, err := r.Node()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"n":2}`, marshal(t, meta))
+
+	ev, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "items", ev.Key)
+
+	ev, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, EventTypeStartArray, ev.Type)
+	assert.Equal(t, 2, r.Depth())
+
+	var items []string
+	for {
+		more, err := r.More()
+		assert.NoError(t, err)
+		if !more {
+			break
+		}
+		n, err := r.Node()
+		assert.NoError(t, err)
+		items = append(items, marshal(t, n))
+	}
+	assert.Equal(t, []string{`{"id":1}`, `{"id":2}`}, items)
+
+	for _, expected := range []EventType{EventTypeEndArray, EventTypeEndObject} {
+		ev, err = r.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ev.Type)
+	}
+
+	more, err := r.More()
+	assert.NoError(t, err)
+	assert.False(t, more)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestArrayDecoder_Decode(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expected         []string
+		expectedErrorMsg string
+	}{
+		{
+			name:     "objects",
+			raw:      "[\n  {\"a\":1},\n  {\"a\":[2]}\n]\n",
+			expected: []string{`{"a":1}`, `{"a":[2]}`},
+		},
+		{
+			name: "empty",
+			raw:  "[]",
+		},
+		{
+			name: "no input",
+			raw:  " ",
+		},
+		{
+			name:             "not an array",
+			raw:              `{"a":1}`,
+			expectedErrorMsg: "expected an array but got EventTypeStartObject at line 1, column 1",
+		},
+		{
+			name:             "bad element",
+			raw:              `[{"a":1}, {"a":}]`,
+			expected:         []string{`{"a":1}`},
+			expectedErrorMsg: "record 2: expected value but got '}' at line 1, column 16",
+		},
+		{
+			name:             "trailing input",
+			raw:              `[1] [2]`,
+			expected:         []string{`1`},
+			expectedErrorMsg: "expected end of input after the array but got '[' at line 1, column 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewArrayDecoder(strings.NewReader(tt.raw))
+
+			var elements []string
+			var err error
+			for {
+				var n ast.Node
+				n, err = d.Decode()
+				if err != nil {
+					break
+				}
+				elements = append(elements, marshal(t, n))
+			}
+
+			assert.Equal(t, tt.expected, elements)
+			if tt.expectedErrorMsg == "" {
+				assert.Equal(t, io.EOF, err)
+				return
+			}
+			assert.Equal(t, tt.expectedErrorMsg, strings.SplitN(fmt.Sprint(err), "\n", 2)[0])
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+// ArrayMode controls how Flatten treats arrays
+type ArrayMode int
+
+const (
+	// ArraysBracketed appends the index in brackets, e.g. "a[0]"
+	ArraysBracketed ArrayMode = iota
+
+	// ArraysSeparated treats the index like a property name, e.g. "a.0"
+	ArraysSeparated
+
+	// ArraysAsValues keeps arrays as they are rather than flattening them
+	ArraysAsValues
+)
+
+type FlattenOptions struct {
+	// Separator joins nested property names, "." if empty
+	Separator string
+
+	Arrays ArrayMode
+}
+
+func (o *FlattenOptions) separator() string {
+	if o == nil || o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+func (o *FlattenOptions) arrays() ArrayMode {
+	if o == nil {
+		return ArraysBracketed
+	}
+	return o.Arrays
+}
+
+// Flatten turns nested objects and arrays into a single object with one property per
+// scalar, named by its path, e.g. {"a":{"b":[1]}} becomes {"a.b[0]":1}. Properties keep
+// the order of the input, nested empty objects and arrays are kept as values. A root
+// that is not flattened, such as a scalar, ends up as the property "". Paths that
+// collide, e.g. of {"a.b":1,"a":{"b":2}} or of a property "x[0]" next to the array
+// "x", are an error rather than one value silently replacing the other.
+func Flatten(n ast.Node, opts *FlattenOptions) (ast.ObjectNode, error) {
+	out := ast.NewObjectNode(nil)
+	if n != nil && n.Type() == ast.NodeTypeObject && len(n.(ast.ObjectNode).Properties()) == 0 {
+		return out, nil
+	}
+	if err := flatten(out, map[string]bool{}, "", n, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// flatten adds the scalars of n to out, seen holds the paths added so far
+func flatten(out ast.ObjectNode, seen map[string]bool, prefix string, n ast.Node, opts *FlattenOptions) error {
+	switch {
+	case n != nil && n.Type() == ast.NodeTypeObject && len(n.(ast.ObjectNode).Properties()) > 0:
+		for _, p := range n.(ast.ObjectNode).Properties() {
+			key := p.Name
+			if prefix != "" {
+				key = prefix + opts.separator() + p.Name
+			}
+			if err := flatten(out, seen, key, p.Value, opts); err != nil {
+				return err
+			}
+		}
+	case n != nil && n.Type() == ast.NodeTypeArray && len(n.(ast.ArrayNode).Items()) > 0 && opts.arrays() != ArraysAsValues:
+		for i, item := range n.(ast.ArrayNode).Items() {
+			key := prefix + "[" + strconv.Itoa(i) + "]"
+			if opts.arrays() == ArraysSeparated {
+				key = strconv.Itoa(i)
+				if prefix != "" {
+					key = prefix + opts.separator() + key
+				}
+			}
+			if err := flatten(out, seen, key, item, opts); err != nil {
+				return err
+			}
+		}
+	default:
+		if seen[prefix] {
+			return fmt.Errorf("cannot flatten, the path %q occurs more than once", prefix)
+		}
+		seen[prefix] = true
+		if n == nil {
+			n = ast.NewNullNode()
+		}
+		out.Set(prefix, n)
+	}
+	return nil
+}
+
+// Unflatten reverses Flatten given the same options. Property names which contain the
+// separator, or look like array indices, do not survive the round trip. A lone
+// property "" is taken as the root itself.
+func Unflatten(o ast.ObjectNode, opts *FlattenOptions) (ast.Node, error) {
+	if props := o.Properties(); len(props) == 1 && props[0].Name == "" {
+		return props[0].Value, nil
+	}
+
+	u := &unflattener{opts: opts, padding: map[ast.Node]bool{}}
+
+	var root ast.Node
+	for _, p := range o.Properties() {
+		path := u.parse(p.Name)
+		if root == nil {
+			root = newContainer(path[0])
+		}
+		if err := u.insert(root, path, p.Value); err != nil {
+			return nil, fmt.Errorf("cannot unflatten %q: %w", p.Name, err)
+		}
+	}
+
+	if root == nil {
+		return ast.NewObjectNode(nil), nil
+	}
+	return root, nil
+}
+
+// segment is either a property name or an array index of a flattened path
+type segment struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+type unflattener struct {
+	opts *FlattenOptions
+
+	// padding holds the nulls inserted for array items that were not seen yet
+	padding map[ast.Node]bool
+}
+
+func (u *unflattener) parse(key string) []segment {
+	var path []segment
+	for _, part := range strings.Split(key, u.opts.separator()) {
+		switch u.opts.arrays() {
+		case ArraysSeparated:
+			if i, ok := arrayIndex(part); ok {
+				path = append(path, segment{index: i, isIndex: true})
+				continue
+			}
+		case ArraysBracketed:
+			var indices []segment
+			for strings.HasSuffix(part, "]") {
+				open := strings.LastIndexByte(part, '[')
+				if open < 0 {
+					break
+				}
+				i, ok := arrayIndex(part[open+1 : len(part)-1])
+				if !ok {
+					break
+				}
+				indices = append([]segment{{index: i, isIndex: true}}, indices...)
+				part = part[:open]
+			}
+			if part != "" || len(indices) == 0 {
+				path = append(path, segment{name: part})
+			}
+			path = append(path, indices...)
+			continue
+		}
+		path = append(path, segment{name: part})
+	}
+	return path
+}
+
+func (u *unflattener) insert(parent ast.Node, path []segment, value ast.Node) error {
+	seg := path[0]
+
+	var existing ast.Node
+	var found bool
+	switch p := parent.(type) {
+	case ast.ObjectNode:
+		if seg.isIndex {
+			return fmt.Errorf("expected a property name but got index %d", seg.index)
+		}
+		existing, found = p.Get(seg.name)
+	case ast.ArrayNode:
+		if !seg.isIndex {
+			return fmt.Errorf("expected an index but got property %q", seg.name)
+		}
+		existing, found = p.Get(seg.index)
+	default:
+		return errors.New("conflicts with an existing value")
+	}
+	if u.padding[existing] {
+		delete(u.padding, existing)
+		found = false
+	}
+
+	if len(path) == 1 {
+		if found {
+			return errors.New("conflicts with an existing value")
+		}
+		u.set(parent, seg, value)
+		return nil
+	}
+
+	if !found {
+		existing = newContainer(path[1])
+		u.set(parent, seg, existing)
+	}
+	return u.insert(existing, path[1:], value)
+}
+
+func (u *unflattener) set(parent ast.Node, seg segment, value ast.Node) {
+	if o, ok := parent.(ast.ObjectNode); ok {
+		o.Set(seg.name, value)
+		return
+	}
+
+	a := parent.(ast.ArrayNode)
+	for len(a.Items()) < seg.index {
+		pad := ast.NewNullNode()
+		u.padding[pad] = true
+		a.Append(pad)
+	}
+	if !a.Set(seg.index, value) {
+		a.Append(value)
+	}
+}
+
+func newContainer(seg segment) ast.Node {
+	if seg.isIndex {
+		return ast.NewArrayNode(nil)
+	}
+	return ast.NewObjectNode(nil)
+}
+
+// arrayIndex parses an index without sign or leading zeros
+func arrayIndex(s string) (int, bool) {
+	if s == "" || (len(s) > 1 && s[0] == '0') || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(s)
+	return i, err == nil
+}
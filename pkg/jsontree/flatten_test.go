@@ -0,0 +1,117 @@
+package jsontree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+func parseString(t *testing.T, s string) ast.Node {
+	n, err := Parse(lexer.NewLexer(strings.NewReader(s)))
+	assert.NoError(t, err)
+	return n
+}
+
+func TestFlatten(t *testing.T) {
+	input := `{"z":{"b":[1,{"c":true}],"a":null},"e":{},"f":[],"g.h":"x"}`
+
+	tests := []struct {
+		name     string
+		opts     *FlattenOptions
+		expected string
+	}{
+		{"default", nil, `{"z.b[0]":1,"z.b[1].c":true,"z.a":null,"e":{},"f":[],"g.h":"x"}`},
+		{"separator", &FlattenOptions{Separator: "/"}, `{"z/b[0]":1,"z/b[1]/c":true,"z/a":null,"e":{},"f":[],"g.h":"x"}`},
+		{"separated arrays", &FlattenOptions{Arrays: ArraysSeparated}, `{"z.b.0":1,"z.b.1.c":true,"z.a":null,"e":{},"f":[],"g.h":"x"}`},
+		{"arrays as values", &FlattenOptions{Arrays: ArraysAsValues}, `{"z.b":[1,{"c":true}],"z.a":null,"e":{},"f":[],"g.h":"x"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flat, err := Flatten(parseString(t, input), tt.opts)
+			assert.NoError(t, err)
+
+			actual, err := flat.MarshalJSON()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
+
+func TestFlatten_Roots(t *testing.T) {
+	flat, err := Flatten(parseString(t, `[[1],2]`), nil)
+	assert.NoError(t, err)
+	actual, _ := flat.MarshalJSON()
+	assert.Equal(t, `{"[0][0]":1,"[1]":2}`, string(actual))
+
+	flat, err = Flatten(parseString(t, `"x"`), nil)
+	assert.NoError(t, err)
+	actual, _ = flat.MarshalJSON()
+	assert.Equal(t, `{"":"x"}`, string(actual))
+}
+
+func TestFlatten_Collisions(t *testing.T) {
+	tests := []struct {
+		input            string
+		opts             *FlattenOptions
+		expectedErrorMsg string
+	}{
+		{`{"a.b":1,"a":{"b":2}}`, nil, `cannot flatten, the path "a.b" occurs more than once`},
+		{`{"x":[1],"x[0]":2}`, nil, `cannot flatten, the path "x[0]" occurs more than once`},
+		{`{"x.0":1,"x":[2]}`, &FlattenOptions{Arrays: ArraysSeparated}, `cannot flatten, the path "x.0" occurs more than once`},
+		{`{"a":1,"a":2}`, nil, `cannot flatten, the path "a" occurs more than once`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Flatten(parseString(t, tt.input), tt.opts)
+			assert.EqualError(t, err, tt.expectedErrorMsg)
+		})
+	}
+}
+
+func TestUnflatten_RoundTrip(t *testing.T) {
+	inputs := []string{
+		`{"z":{"b":[1,{"c":[[true]]}],"a":null},"e":{},"f":[]}`,
+		`[[1],{"a":2}]`,
+		`{}`,
+		`[]`,
+		`"x"`,
+	}
+	for _, mode := range []ArrayMode{ArraysBracketed, ArraysSeparated, ArraysAsValues} {
+		for _, input := range inputs {
+			opts := &FlattenOptions{Separator: "::", Arrays: mode}
+			flat, err := Flatten(parseString(t, input), opts)
+			assert.NoError(t, err)
+			n, err := Unflatten(flat, opts)
+			assert.NoError(t, err)
+
+			actual, _ := n.MarshalJSON()
+			assert.Equal(t, input, string(actual))
+		}
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	n, err := Unflatten(parseString(t, `{"a[1]":2,"b.c":3,"a[0]":1,"b.d[0]":4}`).(ast.ObjectNode), nil)
+	assert.NoError(t, err)
+
+	actual, _ := n.MarshalJSON()
+	assert.Equal(t, `{"a":[1,2],"b":{"c":3,"d":[4]}}`, string(actual))
+}
+
+func TestUnflatten_Conflicts(t *testing.T) {
+	inputs := []string{
+		`{"a":1,"a.b":2}`,
+		`{"a.b":1,"a":2}`,
+		`{"a[0]":1,"a.b":2}`,
+		`{"a.b":1,"a[0]":2}`,
+		`{"a[0]":1,"a[0]":2}`,
+	}
+	for _, input := range inputs {
+		_, err := Unflatten(parseString(t, input).(ast.ObjectNode), nil)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,135 @@
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+	"github.com/trichner/tb/pkg/jsontree/printer"
+)
+
+// addSeeds adds the documents of JSONTestSuite and a few NDJSON streams to the corpus
+func addSeeds(f *testing.F) {
+	files, err := filepath.Glob("testdata/JSONTestSuite/*.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(raw)
+	}
+
+	f.Add([]byte("{\"a\":1}\n{\"a\":[true,null,\"ü\\u00fc\"]}\n"))
+	f.Add([]byte(`{"a": 1, "a": 2, "b": {"c": -0.0e-0}}`))
+	f.Add([]byte(`"𝄞 \ud834 \udd1e"`))
+}
+
+// FuzzParse checks that Parse accepts exactly the documents encoding/json accepts
+// and that the tree, marshalled or printed, holds the same values
+func FuzzParse(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		err := parseDocument(raw)
+
+		// encoding/json does not validate UTF-8 in strings, nor limit nesting to MaxDepth
+		if !utf8.Valid(raw) || (err != nil && strings.Contains(err.Error(), "maximum nesting depth")) {
+			return
+		}
+		if valid := json.Valid(raw); valid != (err == nil) {
+			t.Fatalf("encoding/json says valid=%v but got error: %v", valid, err)
+		}
+		if err != nil {
+			return
+		}
+
+		n, err := Parse(lexer.NewLexer(bytes.NewReader(raw)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		marshalled, err := n.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var printed bytes.Buffer
+		if err := printer.Fprint(&printed, n); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := unmarshal(t, raw)
+		if actual := unmarshal(t, marshalled); !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("marshalled %s, expected %#v but got %#v", marshalled, expected, actual)
+		}
+		if actual := unmarshal(t, printed.Bytes()); !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("printed %s, expected %#v but got %#v", printed.Bytes(), expected, actual)
+		}
+	})
+}
+
+// FuzzDecoderFormat checks that JSONC and JSON5 read JSON just like JSON does, and
+// that no input makes any of the formats fail other than with an error
+func FuzzDecoderFormat(f *testing.F) {
+	addSeeds(f)
+	f.Add([]byte("// c\n[1, /* c */ 2,]"))
+	f.Add([]byte("{a: 'b\\\nc', d: 0x1F, e: +.5, f: 5.,}"))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		expected, expectedErr := decodeRecords(NewDecoder(bytes.NewReader(raw)))
+
+		for _, format := range []Format{FormatJSONC, FormatJSON5} {
+			dec, err := NewDecoderFormat(bytes.NewReader(raw), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := decodeRecords(dec)
+			if expectedErr != nil {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s rejects valid JSON: %v", format, err)
+			}
+			if len(expected) != len(actual) {
+				t.Fatalf("%s decoded %d records but JSON %d", format, len(actual), len(expected))
+			}
+			for i := range expected {
+				if !ast.Equal(expected[i], actual[i]) {
+					t.Fatalf("%s decoded record %d differently", format, i+1)
+				}
+			}
+		}
+	})
+}
+
+// decodeRecords decodes all records up to the end of the stream or the first error
+func decodeRecords(d *Decoder) ([]ast.Node, error) {
+	var records []ast.Node
+	for {
+		n, err := d.Decode()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, n)
+	}
+}
+
+func unmarshal(t *testing.T, raw []byte) any {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("cannot unmarshal %s: %v", raw, err)
+	}
+	return v
+}
@@ -0,0 +1,48 @@
+package jsontree
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+// TestJSONTestSuite runs the parsing tests of https://github.com/nst/JSONTestSuite,
+// files starting with 'y_' must be accepted, 'n_' must be rejected and 'i_' are
+// up to the implementation.
+func TestJSONTestSuite(t *testing.T) {
+	files, err := filepath.Glob("testdata/JSONTestSuite/*.json")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, f := range files {
+		name := filepath.Base(f)
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(f)
+			assert.NoError(t, err)
+
+			err = parseDocument(raw)
+			switch {
+			case strings.HasPrefix(name, "y_"):
+				assert.NoError(t, err)
+			case strings.HasPrefix(name, "n_"):
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+// parseDocument parses exactly one JSON value
+func parseDocument(raw []byte) error {
+	l := lexer.NewLexer(bytes.NewReader(raw))
+	_, err := Parse(l)
+	if err != nil {
+		return err
+	}
+
+	return skipToken(l, lexer.TokenTypeEOF)
+}
@@ -1,11 +1,15 @@
 package lexer
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"math/big"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 //go:generate stringer -type=TokenType
@@ -20,10 +24,10 @@ const (
 	TokenTypeComma
 	TokenTypeColon
 
-	// TokenTypeText is a token of escaped text in double quotes
+	// TokenTypeText is a token of text in double quotes, the value is unescaped
 	TokenTypeText
 
-	// TokenTypePrimitiveText is a token of unescaped, lower-case alpha text
+	// TokenTypePrimitiveText is a token of one of the literals true, false or null
 	TokenTypePrimitiveText
 
 	// TokenTypePrimitiveNumber is a token of a json number
@@ -32,11 +36,56 @@ const (
 	TokenTypeEOF
 )
 
-const delimiters = ",{}[]:"
+// Mode selects the syntax accepted by the lexer
+type Mode int
+
+const (
+	// ModeJSON accepts strict JSON as of RFC 8259
+	ModeJSON Mode = iota
+
+	// ModeJSONC additionally accepts '//' and '/* */' comments and trailing commas
+	ModeJSONC
+
+	// ModeJSON5 accepts JSON5, i.e. JSONC plus single quoted strings, unquoted
+	// property names, more escapes and whitespace, and hexadecimal numbers or numbers
+	// with a leading plus or decimal point. Numbers are converted to equivalent JSON
+	// numbers, Infinity and NaN are rejected as they have none.
+	ModeJSON5
+)
 
 type Token struct {
 	Type  TokenType
 	Value string
+
+	// Pos is where the token starts in the input
+	Pos Position
+}
+
+var punctuation = map[TokenType]string{
+	TokenTypeOpeningBrace:   "'{'",
+	TokenTypeClosingBrace:   "'}'",
+	TokenTypeOpeningBracket: "'['",
+	TokenTypeClosingBracket: "']'",
+	TokenTypeComma:          "','",
+	TokenTypeColon:          "':'",
+}
+
+// String describes a token for error messages
+func (t Token) String() string {
+	switch t.Type {
+	case TokenTypeEOF:
+		return "end of input"
+	case TokenTypeText:
+		return "text " + strconv.Quote(t.Value)
+	case TokenTypePrimitiveText:
+		return t.Value
+	case TokenTypePrimitiveNumber:
+		return "number " + t.Value
+	}
+	if p, ok := punctuation[t.Type]; ok {
+		return p
+	}
+	return t.Type.String()
 }
 
 var (
@@ -60,191 +109,1013 @@ type Lexer interface {
 	Tokenizer
 
 	Peek() (Token, error)
+
+	// Errorf creates a SyntaxError at pos, quoting the input around it if pos is on
+	// the line the lexer is currently at
+	Errorf(pos Position, format string, a ...any) error
+
+	// Recover clears a previous error and resumes lexing at the start of the line
+	// following the given line, on which the failed record started. It returns the
+	// line lexing resumes at. A truncated NDJSON record does not take the record on
+	// the next line down with it, as long as no more than 64KiB of the input were
+	// read past that line. Otherwise the rest of the current line is skipped.
+	Recover(line int) (int, error)
+}
+
+// Position is a location within the input, Line and Column start at 1 and
+// columns count runes rather than bytes
+type Position struct {
+	Offset int64
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
 }
 
+// SyntaxError is returned for input that is not valid JSON as of RFC 8259
+type SyntaxError struct {
+	Pos Position
+	Msg string
+
+	// Snippet quotes the offending line with a caret below Pos, it is empty
+	// if the line was not available anymore
+	Snippet string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Snippet == "" {
+		return fmt.Sprintf("%s at %s", e.Msg, e.Pos)
+	}
+	return fmt.Sprintf("%s at %s\n%s", e.Msg, e.Pos, e.Snippet)
+}
+
+const (
+	// bufferSize is the size of the chunks the input is read in
+	bufferSize = 32 << 10
+
+	// maxLineKeep limits how much of the current line is kept for snippets and Recover,
+	// of longer lines only the part around the current token is kept
+	maxLineKeep = 64 << 10
+
+	// snippetKeep is how much of a truncated line is kept before the current token,
+	// enough to quote it in a snippet
+	snippetKeep = snippetWidth * utf8.UTFMax
+
+	// maxEmptyReads is how often the reader may return nothing before giving up
+	maxEmptyReads = 100
+)
+
+const (
+	// maxInterned limits the number of distinct texts which are interned
+	maxInterned = 4096
+
+	// maxInternedLen is the length up to which texts are interned
+	maxInternedLen = 64
+)
+
+// lexer works on the bytes of the input, which is read in chunks into a buffer. Tokens
+// are sliced from the buffer, only text with escape sequences is copied rune by rune.
 type lexer struct {
-	r *bufio.Reader
+	r    io.Reader
+	mode Mode
+
+	// err ended the input, io.EOF once all of it was read
+	err error
+
+	// buf holds the input which was read but not discarded yet, buf[pos:] is not
+	// lexed yet
+	buf []byte
+	pos int
+
+	// mark is the index in buf of the token being read, it is kept when the buffer
+	// is compacted, negative if there is none
+	mark int
+
+	// offset is the offset of buf[0] in the input
+	offset int64
+
+	// line is the zero based line of buf[pos], lineStart the index in buf where it
+	// starts, negative if that part of the line was discarded
+	line      int
+	lineStart int
+
+	// column is the zero based column of buf[colPos], columns are only counted
+	// when a position is needed
+	column int
+	colPos int
+
+	// prevType is the type of the last token, to tell trailing commas apart
+	prevType TokenType
+
+	// depth is the nesting of arrays and objects, a record starts at depth 0
+	depth int
+
+	// resume is the index in buf of the line following the one the current record
+	// started on, resumeLine its zero based line. It is kept for Recover while the
+	// record is read, resume is negative if the record did not reach the line yet or
+	// it was discarded, resumeLine until the record reached it.
+	resume     int
+	resumeLine int
+
+	// scratch holds text while its escape sequences are resolved
+	scratch bytes.Buffer
+
+	// interned holds short texts, in particular property names repeat in every
+	// record of NDJSON
+	interned map[string]string
 }
 
 func NewLexer(r io.Reader) Lexer {
-	return &peekable{
-		tokenizer: &lexer{
-			r: bufio.NewReader(r),
-		},
-	}
+	return NewLexerMode(r, ModeJSON)
+}
+
+func NewLexerMode(r io.Reader, mode Mode) Lexer {
+	return &peekable{tokenizer: newLexer(r, mode)}
+}
+
+func newLexer(r io.Reader, mode Mode) *lexer {
+	return &lexer{r: r, mode: mode, mark: -1, resume: -1, resumeLine: -1}
 }
 
 func (l *lexer) Token() (Token, error) {
-	err := l.skipWhitespace()
-	if err != nil {
-		if err == io.EOF {
-			return eofToken, nil
+	token, err := l.token()
+	if err == nil && token.Type == TokenTypeComma && l.mode != ModeJSON {
+		var trailing bool
+		trailing, err = l.isTrailingComma()
+		if err == nil && trailing {
+			token, err = l.token()
+		}
+	}
+	if err == nil {
+		l.prevType = token.Type
+		l.nest(token.Type)
+	}
+	return token, err
+}
+
+// nest keeps track of the depth, the line to resume at is forgotten once a record
+// is complete
+func (l *lexer) nest(t TokenType) {
+	switch t {
+	case TokenTypeOpeningBrace, TokenTypeOpeningBracket:
+		l.depth++
+	case TokenTypeClosingBrace, TokenTypeClosingBracket:
+		l.depth = max(l.depth-1, 0)
+		if l.depth == 0 {
+			l.resume, l.resumeLine = -1, -1
 		}
+	}
+}
+
+// isTrailingComma reports whether the comma just read follows a value and closes an
+// array or object, it is dropped in that case
+func (l *lexer) isTrailingComma() (bool, error) {
+	switch l.prevType {
+	case TokenTypeUnknown, TokenTypeOpeningBrace, TokenTypeOpeningBracket, TokenTypeComma, TokenTypeColon:
+		return false, nil
+	}
+
+	if err := l.skipWhitespace(); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	next, err := l.peekByte()
+	if err != nil {
+		return false, nil
+	}
+	return next == '}' || next == ']', nil
+}
+
+func (l *lexer) token() (Token, error) {
+	err := l.skipWhitespace()
+	if err != nil && err != io.EOF {
 		return unknownToken, err
 	}
 
-	r, _, err := l.r.ReadRune()
-	switch r {
-	case '"':
-		return l.lexText()
+	start := l.position()
+	token, err := l.lexToken(start)
+	token.Pos = start
+	return token, err
+}
+
+func (l *lexer) lexToken(start Position) (Token, error) {
+	b, err := l.peekByte()
+	if err == io.EOF {
+		return eofToken, nil
+	} else if err != nil {
+		return unknownToken, err
+	}
+
+	switch b {
 	case '{':
+		l.pos++
 		return openingBraceToken, nil
 	case '}':
+		l.pos++
 		return closingBraceToken, nil
 	case '[':
+		l.pos++
 		return openingBracketToken, nil
 	case ']':
+		l.pos++
 		return closingBracketToken, nil
 	case ':':
+		l.pos++
 		return colonToken, nil
 	case ',':
+		l.pos++
 		return commaToken, nil
+	case '"':
+		l.pos++
+		return l.lexText(b)
+	case '\'':
+		if l.mode == ModeJSON5 {
+			l.pos++
+			return l.lexText(b)
+		}
 	}
 
-	err = l.r.UnreadRune()
-	if err != nil {
-		return unknownToken, err
+	if isNumberLiteral(rune(b)) || (l.mode == ModeJSON5 && (b == '+' || b == '.')) {
+		return l.lexPrimitiveNumber()
 	}
 
-	if isNumberLiteral(r) {
-		return l.lexPrimitiveNumber()
+	r, size := utf8.DecodeRune(l.peek(utf8.UTFMax))
+	if r == utf8.RuneError && size == 1 {
+		l.pos++
+		return unknownToken, l.errorf(start, "invalid UTF-8")
+	}
+
+	if l.mode == ModeJSON5 && isIdentifierStart(r) {
+		return l.lexIdentifier()
 	}
 
 	if isAlpha(r) {
 		return l.lexPrimitiveText()
 	}
 
-	return unknownToken, fmt.Errorf("unrecognized token: '%c'", r)
+	return unknownToken, l.errorf(start, "unexpected character %q", r)
+}
+
+func (l *lexer) position() Position {
+	l.syncColumn()
+	return Position{Offset: l.offset + int64(l.pos), Line: l.line + 1, Column: l.column + 1}
+}
+
+// syncColumn counts the runes up to pos into the column
+func (l *lexer) syncColumn() {
+	l.column += utf8.RuneCount(l.buf[l.colPos:l.pos])
+	l.colPos = l.pos
+}
+
+// newline moves on to the line which starts at pos
+func (l *lexer) newline() {
+	l.line++
+	l.lineStart = l.pos
+	l.column = 0
+	l.colPos = l.pos
+	if l.depth > 0 && l.resumeLine < 0 {
+		l.resume, l.resumeLine = l.pos, l.line
+	}
+}
+
+func (l *lexer) errorf(pos Position, format string, a ...any) error {
+	return l.Errorf(pos, format, a...)
+}
+
+func (l *lexer) Errorf(pos Position, format string, a ...any) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, a...), Snippet: l.snippet(pos)}
 }
 
+// snippetWidth is the maximum number of runes quoted from a line
+const snippetWidth = 72
+
+// snippet quotes the line of pos with a caret below the column, if that is the
+// current line
+func (l *lexer) snippet(pos Position) string {
+	if pos.Line != l.line+1 {
+		return ""
+	}
+
+	// of a truncated line only the part that is left in the buffer is quoted
+	start, startColumn := l.lineStart, 0
+	if start < 0 {
+		l.syncColumn()
+		start, startColumn = 0, l.column-utf8.RuneCount(l.buf[:l.pos])
+	}
+	col := pos.Column - 1 - startColumn
+	if col < 0 {
+		return ""
+	}
+
+	// complete the line with whatever is already buffered, without blocking on more input
+	line := l.buf[start:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+
+	runes := []rune(string(line))
+	for i, r := range runes {
+		if r < 0x20 {
+			runes[i] = ' '
+		}
+	}
+
+	first, prefix := 0, ""
+	if startColumn > 0 {
+		prefix = "..."
+	}
+	if col > snippetWidth*2/3 {
+		first, prefix = col-snippetWidth*2/3, "..."
+	}
+	end, suffix := len(runes), ""
+	if end-first > snippetWidth {
+		end, suffix = first+snippetWidth, "..."
+	}
+	if first > end {
+		return ""
+	}
+
+	caret := strings.Repeat(" ", len(prefix)+col-first) + "^"
+	return "    " + prefix + string(runes[first:end]) + suffix + "\n    " + caret
+}
+
+// Recover goes back to the line following the one the record started on if it is
+// still buffered, otherwise it skips to the end of the current line
+func (l *lexer) Recover(line int) (int, error) {
+	resume, resumeLine := l.resume, l.resumeLine
+	l.mark, l.depth, l.prevType = -1, 0, TokenTypeUnknown
+	l.resume, l.resumeLine = -1, -1
+
+	if resume >= 0 && resumeLine == line {
+		l.pos, l.line, l.lineStart = resume, resumeLine, resume
+		l.column, l.colPos = 0, resume
+		return l.line + 1, nil
+	}
+
+	for {
+		b, err := l.peekByte()
+		if err == io.EOF {
+			return l.line + 1, nil
+		} else if err != nil {
+			return 0, err
+		}
+		l.pos++
+		if b == '\n' {
+			l.newline()
+			return l.line + 1, nil
+		}
+	}
+}
+
+// fill reads more input into the buffer, it returns false if there is none left and
+// l.err tells why
+func (l *lexer) fill() bool {
+	if l.err != nil {
+		return false
+	}
+
+	if l.buf == nil {
+		l.buf = make([]byte, 0, bufferSize)
+	}
+	if len(l.buf) == cap(l.buf) {
+		l.compact()
+	}
+	if len(l.buf) > cap(l.buf)/2 {
+		buf := make([]byte, len(l.buf), 2*cap(l.buf))
+		copy(buf, l.buf)
+		l.buf = buf
+	}
+
+	for range maxEmptyReads {
+		n, err := l.r.Read(l.buf[len(l.buf):cap(l.buf)])
+		l.buf = l.buf[:len(l.buf)+n]
+		if err != nil {
+			l.err = err
+			return n > 0
+		}
+		if n > 0 {
+			return true
+		}
+	}
+	l.err = io.ErrNoProgress
+	return false
+}
+
+// compact discards the input before the current line, or of long lines all but
+// what is needed for a snippet, the token being read and the line to resume at
+func (l *lexer) compact() {
+	l.syncColumn()
+
+	if l.lineStart >= 0 && l.pos-l.lineStart > maxLineKeep {
+		l.lineStart = -1
+	}
+	if l.resume >= 0 && l.pos-l.resume > maxLineKeep {
+		l.resume = -1
+	}
+	keep := l.lineStart
+	if keep < 0 {
+		keep = max(l.pos-snippetKeep, 0)
+		for keep < l.pos && !utf8.RuneStart(l.buf[keep]) {
+			keep++
+		}
+	}
+	if l.mark >= 0 {
+		keep = min(keep, l.mark)
+	}
+	if l.resume >= 0 {
+		keep = min(keep, l.resume)
+	}
+	if keep == 0 {
+		return
+	}
+
+	l.buf = l.buf[:copy(l.buf, l.buf[keep:])]
+	l.offset += int64(keep)
+	l.pos -= keep
+	l.colPos -= keep
+	if l.lineStart >= 0 {
+		l.lineStart -= keep
+	}
+	if l.mark >= 0 {
+		l.mark -= keep
+	}
+	if l.resume >= 0 {
+		l.resume -= keep
+	}
+}
+
+// peekByte returns the next byte without consuming it, l.err at the end of the input
+func (l *lexer) peekByte() (byte, error) {
+	if l.pos == len(l.buf) && !l.fill() {
+		return 0, l.err
+	}
+	return l.buf[l.pos], nil
+}
+
+// peek returns the next n bytes without consuming them, fewer at the end of the input
+func (l *lexer) peek(n int) []byte {
+	for len(l.buf)-l.pos < n && l.fill() {
+	}
+	return l.buf[l.pos:min(l.pos+n, len(l.buf))]
+}
+
+// readRune reads the next rune and keeps track of lines, invalid UTF-8 is an error
+func (l *lexer) readRune() (rune, error) {
+	b, err := l.peekByte()
+	if err != nil {
+		return 0, err
+	}
+	if b < utf8.RuneSelf {
+		l.pos++
+		if b == '\n' {
+			l.newline()
+		}
+		return rune(b), nil
+	}
+
+	r, size := utf8.DecodeRune(l.peek(utf8.UTFMax))
+	if r == utf8.RuneError && size == 1 {
+		pos := l.position()
+		l.pos++
+		return r, l.errorf(pos, "invalid UTF-8")
+	}
+	l.pos += size
+	return r, nil
+}
+
+// skipWhitespace skips whitespace and, unless in ModeJSON, comments
 func (l *lexer) skipWhitespace() error {
 	for {
-		r, _, err := l.r.ReadRune()
+		b, err := l.peekByte()
 		if err != nil {
 			return err
 		}
-		if !unicode.IsSpace(r) {
-			return l.r.UnreadRune()
+
+		switch {
+		case b == ' ' || b == '\t' || b == '\r':
+			l.pos++
+		case b == '\n':
+			l.pos++
+			l.newline()
+		case b == '/' && l.mode != ModeJSON:
+			if next := l.peek(2); len(next) < 2 || (next[1] != '/' && next[1] != '*') {
+				return nil
+			}
+			if err := l.skipComment(); err != nil {
+				return err
+			}
+		case l.mode == ModeJSON5 && (b == '\v' || b == '\f' || b >= utf8.RuneSelf):
+			r, size := utf8.DecodeRune(l.peek(utf8.UTFMax))
+			if !l.isWhitespace(r) {
+				return nil
+			}
+			l.pos += size
+		default:
+			return nil
 		}
 	}
 }
 
+// skipComment skips a '//' comment up to and including the end of the line, or a
+// '/* */' comment
+func (l *lexer) skipComment() error {
+	start := l.position()
+	_, _ = l.readRune()
+	kind, err := l.readRune()
+	if err != nil {
+		return err
+	}
+
+	star := false
+	for {
+		r, err := l.readRune()
+		if err == io.EOF && kind == '/' {
+			return nil
+		} else if err == io.EOF {
+			return l.errorf(start, "unterminated comment")
+		} else if err != nil {
+			return err
+		}
+
+		if kind == '/' && r == '\n' {
+			return nil
+		}
+		if kind == '*' && star && r == '/' {
+			return nil
+		}
+		star = r == '*'
+	}
+}
+
 func (l *lexer) lexPrimitiveNumber() (Token, error) {
-	v, err := l.readPrimitiveNumber()
+	start := l.position()
+	w, err := l.readWord()
 	if err != nil {
 		return unknownToken, err
 	}
+	v := string(w)
 
-	// TODO: Validate literal?
+	if l.mode == ModeJSON5 {
+		if unsigned := strings.TrimLeft(v, "+-"); unsigned == "Infinity" || unsigned == "NaN" {
+			return unknownToken, l.errorf(start, "%s cannot be represented in JSON", v)
+		}
+		if n, ok := json5Number(v); ok {
+			return Token{Type: TokenTypePrimitiveNumber, Value: n}, nil
+		}
+	}
+
+	if !IsValidNumber(v) {
+		return unknownToken, l.errorf(start, "invalid number %q", v)
+	}
 
 	return Token{Type: TokenTypePrimitiveNumber, Value: v}, nil
 }
 
-func (l *lexer) readPrimitiveNumber() (string, error) {
-	var s strings.Builder
+// lexIdentifier lexes an unquoted property name or a literal in ModeJSON5
+func (l *lexer) lexIdentifier() (Token, error) {
+	start := l.position()
+	w, err := l.readWord()
+	if err != nil {
+		return unknownToken, err
+	}
+	v := l.text(w)
+	if !isIdentifier(v) {
+		return unknownToken, l.errorf(start, "invalid identifier %q", v)
+	}
+
+	// a property name is followed by a colon, even literals such as null may be one
+	if err := l.skipWhitespace(); err != nil && err != io.EOF {
+		return unknownToken, err
+	}
+	if next, err := l.peekByte(); err == nil && next == ':' {
+		return Token{Type: TokenTypeText, Value: v}, nil
+	}
+
+	switch v {
+	case "true", "false", "null":
+		return Token{Type: TokenTypePrimitiveText, Value: v}, nil
+	case "Infinity", "NaN":
+		return unknownToken, l.errorf(start, "%s cannot be represented in JSON", v)
+	}
+	return unknownToken, l.errorf(start, "invalid literal %q", v)
+}
+
+var (
+	trueToken  = Token{Type: TokenTypePrimitiveText, Value: "true"}
+	falseToken = Token{Type: TokenTypePrimitiveText, Value: "false"}
+	nullToken  = Token{Type: TokenTypePrimitiveText, Value: "null"}
+)
+
+func (l *lexer) lexPrimitiveText() (Token, error) {
+	start := l.position()
+	w, err := l.readWord()
+	if err != nil {
+		return unknownToken, err
+	}
+
+	switch string(w) {
+	case "true":
+		return trueToken, nil
+	case "false":
+		return falseToken, nil
+	case "null":
+		return nullToken, nil
+	}
+	return unknownToken, l.errorf(start, "invalid literal %q", w)
+}
+
+// readWord reads up to the next delimiter, whitespace or EOF. Words are numbers or
+// literals, their content is validated by the caller. The word is only valid until
+// the lexer reads on.
+func (l *lexer) readWord() ([]byte, error) {
+	l.mark = l.pos
 	for {
-		r, _, err := l.r.ReadRune()
-		if err != nil {
-			if err == io.EOF {
-				return s.String(), nil
+		if l.pos == len(l.buf) && !l.fill() {
+			if l.err != io.EOF {
+				return nil, l.err
 			}
-			return "", err
+			break
 		}
 
-		if isDelimiter(r) {
-			return s.String(), l.r.UnreadRune()
+		b := l.buf[l.pos]
+		if b < utf8.RuneSelf {
+			if l.isDelimiter(rune(b)) {
+				break
+			}
+			l.pos++
+			continue
 		}
 
-		_, err = s.WriteRune(r)
-		if err != nil {
-			return "", err
+		r, size := utf8.DecodeRune(l.peek(utf8.UTFMax))
+		if r == utf8.RuneError && size == 1 {
+			pos := l.position()
+			l.pos++
+			return nil, l.errorf(pos, "invalid UTF-8")
+		}
+		if l.isDelimiter(r) {
+			break
 		}
+		l.pos += size
 	}
+
+	w := l.buf[l.mark:l.pos]
+	l.mark = -1
+	return w, nil
 }
 
-func (l *lexer) lexPrimitiveText() (Token, error) {
-	v, err := l.readPrimitiveText()
+func (l *lexer) lexText(quote byte) (Token, error) {
+	v, err := l.readText(quote)
 	if err != nil {
 		return unknownToken, err
 	}
 
-	return Token{Type: TokenTypePrimitiveText, Value: v}, nil
+	return Token{Type: TokenTypeText, Value: v}, nil
 }
 
-func (l *lexer) readPrimitiveText() (string, error) {
-	var s strings.Builder
+// readText reads the remainder of a string after the opening quote. Text without
+// escape sequences or control characters is taken from the buffer as it is.
+func (l *lexer) readText(quote byte) (string, error) {
+	l.mark = l.pos
 	for {
-		r, _, err := l.r.ReadRune()
+		if l.pos == len(l.buf) && !l.fill() {
+			break
+		}
+
+		b := l.buf[l.pos]
+		switch {
+		case b == quote:
+			v := l.text(l.buf[l.mark:l.pos])
+			l.pos++
+			l.mark = -1
+			return v, nil
+		case b == '\\' || b < 0x20:
+			// the slow path resolves escapes and reports control characters
+		case b >= utf8.RuneSelf:
+			r, size := utf8.DecodeRune(l.peek(utf8.UTFMax))
+			if r == utf8.RuneError && size == 1 {
+				break
+			}
+			l.pos += size
+			continue
+		default:
+			l.pos++
+			continue
+		}
+		break
+	}
+
+	l.scratch.Reset()
+	l.scratch.Write(l.buf[l.mark:l.pos])
+	l.mark = -1
+	return l.readEscapedText(quote)
+}
+
+// readEscapedText reads the rest of a string rune by rune and resolves all escape
+// sequences
+func (l *lexer) readEscapedText(quote byte) (string, error) {
+	s := &l.scratch
+	for {
+		pos := l.position()
+		r, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
-				return s.String(), nil
+				return "", l.errorf(pos, "unexpected EOF in text")
 			}
 			return "", err
 		}
 
-		if isDelimiter(r) {
-			return s.String(), l.r.UnreadRune()
+		switch {
+		case r == rune(quote):
+			return l.text(s.Bytes()), nil
+		case l.mode == ModeJSON5 && (r == '\n' || r == '\r'):
+			return "", l.errorf(pos, "unescaped line break in text")
+		case r < 0x20 && l.mode != ModeJSON5:
+			return "", l.errorf(pos, "unescaped control character %U in text", r)
+		case r == '\\':
+			err = l.readEscape(s, pos)
+			if err != nil {
+				return "", err
+			}
+		default:
+			s.WriteRune(r)
 		}
+	}
+}
 
-		if !isAlpha(r) {
-			return "", fmt.Errorf("invalid primitive text: '%s%c'", s.String(), r)
+// text returns b as a string, short ones are interned as they tend to repeat
+func (l *lexer) text(b []byte) string {
+	if len(b) > maxInternedLen {
+		return string(b)
+	}
+	if s, ok := l.interned[string(b)]; ok {
+		return s
+	}
+
+	s := string(b)
+	if l.interned == nil {
+		l.interned = map[string]string{}
+	}
+	if len(l.interned) < maxInterned {
+		l.interned[s] = s
+	}
+	return s
+}
+
+func (l *lexer) readEscape(s *bytes.Buffer, pos Position) error {
+	r, err := l.readRune()
+	if err != nil {
+		if err == io.EOF {
+			return l.errorf(pos, "unexpected EOF in escape sequence")
 		}
+		return err
+	}
 
-		_, err = s.WriteRune(r)
+	switch r {
+	case '"', '\\', '/':
+		s.WriteRune(r)
+	case 'b':
+		s.WriteByte('\b')
+	case 'f':
+		s.WriteByte('\f')
+	case 'n':
+		s.WriteByte('\n')
+	case 'r':
+		s.WriteByte('\r')
+	case 't':
+		s.WriteByte('\t')
+	case 'u':
+		return l.readUnicodeEscape(s, pos)
+	default:
+		if l.mode == ModeJSON5 {
+			return l.readJSON5Escape(s, r, pos)
+		}
+		return l.errorf(pos, "invalid escape sequence '\\%c'", r)
+	}
+	return nil
+}
+
+// readJSON5Escape resolves the escapes JSON5 has on top of JSON, any other
+// character but a digit escapes itself
+func (l *lexer) readJSON5Escape(s *bytes.Buffer, r rune, pos Position) error {
+	switch r {
+	case 'v':
+		s.WriteByte('\v')
+	case '0':
+		if next, err := l.peekByte(); err == nil && '0' <= next && next <= '9' {
+			return l.errorf(pos, "invalid escape sequence '\\0%c'", next)
+		}
+		s.WriteByte(0)
+	case 'x':
+		v, err := l.readHex(pos, 2)
 		if err != nil {
-			return "", err
+			return err
 		}
+		s.WriteRune(v)
+	case '\r':
+		// a line continuation, which may be a CRLF
+		if next, err := l.peekByte(); err == nil && next == '\n' {
+			_, _ = l.readRune()
+		}
+	case '\n', '\u2028', '\u2029':
+		// a line continuation
+	default:
+		if '1' <= r && r <= '9' {
+			return l.errorf(pos, "invalid escape sequence '\\%c'", r)
+		}
+		s.WriteRune(r)
 	}
+	return nil
 }
 
-func (l *lexer) lexText() (Token, error) {
-	v, err := l.readText()
+// readUnicodeEscape reads the hex digits of a '\u' escape. A high surrogate
+// followed by an escaped low surrogate forms a single rune, unpaired surrogates
+// are replaced by U+FFFD like encoding/json does.
+func (l *lexer) readUnicodeEscape(s *bytes.Buffer, pos Position) error {
+	r, err := l.readHex(pos, 4)
 	if err != nil {
-		return unknownToken, err
+		return err
 	}
 
-	return Token{Type: TokenTypeText, Value: v}, nil
+	// like encoding/json, a surrogate which does not pair with the next escape is replaced and
+	// that escape may start a pair of its own
+	for utf16.IsSurrogate(r) {
+		if string(l.peek(2)) != `\u` {
+			s.WriteRune(utf8.RuneError)
+			return nil
+		}
+
+		lowPos := l.position()
+		l.pos += 2
+		low, err := l.readHex(lowPos, 4)
+		if err != nil {
+			return err
+		}
+		if combined := utf16.DecodeRune(r, low); combined != utf8.RuneError {
+			s.WriteRune(combined)
+			return nil
+		}
+
+		s.WriteRune(utf8.RuneError)
+		r = low
+	}
+
+	s.WriteRune(r)
+	return nil
 }
 
-func (l *lexer) readText() (string, error) {
-	var s strings.Builder
-	for {
-		r, _, err := l.r.ReadRune()
+// readHex reads a rune of n hex digits
+func (l *lexer) readHex(pos Position, n int) (rune, error) {
+	var v rune
+	for i := 0; i < n; i++ {
+		r, err := l.readRune()
 		if err != nil {
 			if err == io.EOF {
-				return "", fmt.Errorf("unexpected EOF in text")
+				return 0, l.errorf(pos, "unexpected EOF in unicode escape")
 			}
-			return "", err
+			return 0, err
 		}
 
-		if r == '"' {
-			return s.String(), nil
+		switch {
+		case '0' <= r && r <= '9':
+			v = v<<4 | (r - '0')
+		case 'a' <= r && r <= 'f':
+			v = v<<4 | (r - 'a' + 10)
+		case 'A' <= r && r <= 'F':
+			v = v<<4 | (r - 'A' + 10)
+		default:
+			return 0, l.errorf(pos, "invalid unicode escape")
 		}
-		if r == '\\' {
-			_, err = s.WriteRune(r)
-			if err != nil {
-				return "", err
-			}
+	}
+	return v, nil
+}
 
-			r, _, err = l.r.ReadRune()
-			if err != nil {
-				return "", err
-			}
+// IsValidNumber matches the number grammar of RFC 8259:
+//
+//	number = [ minus ] int [ frac ] [ exp ]
+func IsValidNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+
+	// int = zero / ( digit1-9 *DIGIT )
+	switch {
+	case i < len(s) && s[i] == '0':
+		i++
+	case i < len(s) && '1' <= s[i] && s[i] <= '9':
+		i = skipDigits(s, i)
+	default:
+		return false
+	}
+
+	// frac = decimal-point 1*DIGIT
+	if i < len(s) && s[i] == '.' {
+		i++
+		end := skipDigits(s, i)
+		if end == i {
+			return false
 		}
+		i = end
+	}
 
-		_, err = s.WriteRune(r)
-		if err != nil {
-			return "", err
+	// exp = e [ minus / plus ] 1*DIGIT
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		end := skipDigits(s, i)
+		if end == i {
+			return false
+		}
+		i = end
+	}
+
+	return i == len(s)
+}
+
+func skipDigits(s string, i int) int {
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// json5Number converts a JSON5 number to an equivalent JSON number, e.g. '0x1F'
+// becomes '31', '+.5' becomes '0.5' and '5.' becomes '5'
+func json5Number(v string) (string, bool) {
+	sign, s := "", v
+	if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	} else if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		digits := s[2:]
+		if digits == "" || strings.TrimLeft(digits, "0123456789abcdefABCDEF") != "" {
+			return "", false
 		}
+		n, _ := new(big.Int).SetString(digits, 16)
+		return sign + n.String(), true
+	}
+
+	if strings.HasPrefix(s, ".") {
+		s = "0" + s
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 && (i+1 == len(s) || s[i+1] == 'e' || s[i+1] == 'E') {
+		s = s[:i] + s[i+1:]
+	}
+	return sign + s, IsValidNumber(sign + s)
+}
+
+func (l *lexer) isDelimiter(r rune) bool {
+	switch r {
+	case ',', '{', '}', '[', ']', ':', '"':
+		return true
+	case '/':
+		return l.mode != ModeJSON
+	case '\'':
+		return l.mode == ModeJSON5
 	}
+	return l.isWhitespace(r)
 }
 
-func isDelimiter(r rune) bool {
-	return unicode.IsSpace(r) || strings.ContainsRune(delimiters, r)
+// isWhitespace accepts the four whitespace characters allowed by RFC 8259, and
+// the ones of ECMAScript in ModeJSON5
+func (l *lexer) isWhitespace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r':
+		return true
+	case '\v', '\f', '\u00a0', '\ufeff', '\u2028', '\u2029':
+		return l.mode == ModeJSON5
+	}
+	return l.mode == ModeJSON5 && unicode.Is(unicode.Zs, r)
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '$' || r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentifier matches ECMAScript identifier names, without unicode escapes
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if isIdentifierStart(r) {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) || r == '\u200c' || r == '\u200d') {
+			continue
+		}
+		return false
+	}
+	return s != ""
 }
 
 func isNumberLiteral(r rune) bool {
-	return unicode.IsDigit(r) || r == '-'
+	return ('0' <= r && r <= '9') || r == '-'
 }
 
 func isAlpha(r rune) bool {
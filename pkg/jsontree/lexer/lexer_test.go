@@ -1,16 +1,20 @@
 package lexer
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestLexer_Token_SkipWhitespace(t *testing.T) {
 	raw := "   \t "
-	lex := &lexer{r: bufio.NewReader(strings.NewReader(raw))}
+	lex := newLexer(strings.NewReader(raw), ModeJSON)
 
 	_, err := lex.Token()
 	assert.NoError(t, err)
@@ -34,8 +38,8 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 		},
 		{
 			name:     "whitespace and primitive text 1",
-			raw:      "  hello  ",
-			expected: []Token{{Type: TokenTypePrimitiveText, Value: "hello"}, {Type: TokenTypeEOF}},
+			raw:      "  null  ",
+			expected: []Token{{Type: TokenTypePrimitiveText, Value: "null"}, {Type: TokenTypeEOF}},
 		},
 		{
 			name:     "whitespace and primitive text 2",
@@ -96,7 +100,7 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 			name: "string 3",
 			raw:  `   "hi\n"`,
 			expected: []Token{
-				{Type: TokenTypeText, Value: "hi\\n"},
+				{Type: TokenTypeText, Value: "hi\n"},
 				{Type: TokenTypeEOF},
 			},
 		},
@@ -104,15 +108,15 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 			name: "string 4 - escaping",
 			raw:  `   "hi\""`,
 			expected: []Token{
-				{Type: TokenTypeText, Value: "hi\\\""},
+				{Type: TokenTypeText, Value: "hi\""},
 				{Type: TokenTypeEOF},
 			},
 		},
 		{
 			name: "string 5 - escaping",
-			raw:  `   "hi\n"`,
+			raw:  `   "\u00e4\/\\\t\ud83d\ude00"`,
 			expected: []Token{
-				{Type: TokenTypeText, Value: "hi\\n"},
+				{Type: TokenTypeText, Value: "ä/\\\t😀"},
 				{Type: TokenTypeEOF},
 			},
 		},
@@ -141,10 +145,10 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 			},
 		},
 		{
-			name: "number 2",
-			raw:  "   -01.23 ",
+			name: "number 3",
+			raw:  "   -0.23e+10 ",
 			expected: []Token{
-				{Type: TokenTypePrimitiveNumber, Value: "-01.23"},
+				{Type: TokenTypePrimitiveNumber, Value: "-0.23e+10"},
 				{Type: TokenTypeEOF},
 			},
 		},
@@ -171,7 +175,7 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			lex := &lexer{r: bufio.NewReader(strings.NewReader(test.raw))}
+			lex := newLexer(strings.NewReader(test.raw), ModeJSON)
 			var tokens []Token
 			for {
 				token, err := lex.Token()
@@ -179,6 +183,9 @@ func TestLexer_Token_WithSuccess(t *testing.T) {
 					assert.FailNow(t, "error lexing token", err)
 				}
 				assert.NoError(t, err)
+
+				// positions are covered by TestLexer_Token_Positions
+				token.Pos = Position{}
 				tokens = append(tokens, token)
 				if token.Type == TokenTypeEOF {
 					break
@@ -228,10 +235,458 @@ func TestLexer_LexPrimitiveText(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			lex := &lexer{r: bufio.NewReader(strings.NewReader(test.raw))}
+			lex := newLexer(strings.NewReader(test.raw), ModeJSON)
 			token, err := lex.lexPrimitiveText()
 			assert.NoError(t, err)
 			assert.Equal(t, test.expected, token.Value)
 		})
 	}
 }
+
+func TestLexer_Token_WithError(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expectedErrorMsg string
+	}{
+		{
+			name:             "leading zero",
+			raw:              "[01]",
+			expectedErrorMsg: `invalid number "01" at line 1, column 2`,
+		},
+		{
+			name:             "minus within number",
+			raw:              "1-2",
+			expectedErrorMsg: `invalid number "1-2" at line 1, column 1`,
+		},
+		{
+			name:             "missing fraction digits",
+			raw:              "\n  1.",
+			expectedErrorMsg: `invalid number "1." at line 2, column 3`,
+		},
+		{
+			name:             "unknown literal",
+			raw:              "[truth]",
+			expectedErrorMsg: `invalid literal "truth" at line 1, column 2`,
+		},
+		{
+			name:             "control character",
+			raw:              "\"a\tb\"",
+			expectedErrorMsg: `unescaped control character U+0009 in text at line 1, column 3`,
+		},
+		{
+			name:             "invalid escape",
+			raw:              `"\x"`,
+			expectedErrorMsg: `invalid escape sequence '\x' at line 1, column 2`,
+		},
+		{
+			name:             "invalid unicode escape",
+			raw:              `"\u12G4"`,
+			expectedErrorMsg: `invalid unicode escape at line 1, column 2`,
+		},
+		{
+			name:             "invalid utf-8",
+			raw:              "\"\xff\"",
+			expectedErrorMsg: `invalid UTF-8 at line 1, column 2`,
+		},
+		{
+			name:             "unterminated text",
+			raw:              `"abc`,
+			expectedErrorMsg: `unexpected EOF in text at line 1, column 5`,
+		},
+		{
+			name:             "non json whitespace",
+			raw:              "\f1",
+			expectedErrorMsg: `unexpected character '\f' at line 1, column 1`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := newLexer(strings.NewReader(test.raw), ModeJSON)
+			var err error
+			for {
+				var token Token
+				token, err = lex.Token()
+				if err != nil || token.Type == TokenTypeEOF {
+					break
+				}
+			}
+
+			// snippets are covered by TestLexer_Token_Snippet
+			var syntaxErr *SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr) {
+				syntaxErr.Snippet = ""
+				assert.EqualError(t, syntaxErr, test.expectedErrorMsg)
+			}
+		})
+	}
+}
+
+func TestLexer_Token_Surrogates(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{raw: `"𝄞"`, expected: "\U0001D11E"},
+		{raw: `"\uD834"`, expected: "�"},
+		{raw: `"\uDD1E"`, expected: "�"},
+		{raw: `"\uD834x"`, expected: "�x"},
+		{raw: `"\uD834A"`, expected: "�A"},
+		{raw: `"\uD834\u0041"`, expected: "�A"},
+		{raw: `"\uD800\uD800\uDC00"`, expected: "�\U00010000"},
+		{raw: `"\uDC00\uD834\uDD1E"`, expected: "�\U0001D11E"},
+		{raw: `"\uD800\uDC00\uDC00"`, expected: "\U00010000�"},
+		{raw: `"\uDC00\uDC00"`, expected: "��"},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			lex := newLexer(strings.NewReader(test.raw), ModeJSON)
+			token, err := lex.Token()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, token.Value)
+		})
+	}
+}
+
+func TestLexer_Token_Positions(t *testing.T) {
+	raw := "{\"a\": [1,\n  \"ü\", null]}"
+	lex := newLexer(strings.NewReader(raw), ModeJSON)
+
+	var positions []Position
+	for {
+		token, err := lex.Token()
+		assert.NoError(t, err)
+		positions = append(positions, token.Pos)
+		if token.Type == TokenTypeEOF {
+			break
+		}
+	}
+
+	expected := []Position{
+		{Offset: 0, Line: 1, Column: 1},   // {
+		{Offset: 1, Line: 1, Column: 2},   // "a"
+		{Offset: 4, Line: 1, Column: 5},   // :
+		{Offset: 6, Line: 1, Column: 7},   // [
+		{Offset: 7, Line: 1, Column: 8},   // 1
+		{Offset: 8, Line: 1, Column: 9},   // ,
+		{Offset: 12, Line: 2, Column: 3},  // "ü"
+		{Offset: 16, Line: 2, Column: 6},  // ,
+		{Offset: 18, Line: 2, Column: 8},  // null
+		{Offset: 22, Line: 2, Column: 12}, // ]
+		{Offset: 23, Line: 2, Column: 13}, // }
+		{Offset: 24, Line: 2, Column: 14}, // EOF
+	}
+	assert.Equal(t, expected, positions)
+}
+
+func TestLexer_Token_Snippet(t *testing.T) {
+	tests := []struct {
+		name             string
+		raw              string
+		expectedErrorMsg string
+	}{
+		{
+			name: "short line",
+			raw:  "{\"a\":1}\n{\"a\": 01, \"b\": 2}\n{}",
+			expectedErrorMsg: `invalid number "01" at line 2, column 7
+    {"a": 01, "b": 2}
+          ^`,
+		},
+		{
+			name: "long line",
+			raw:  `{"` + strings.Repeat("a", 100) + `": tru, "` + strings.Repeat("b", 80) + `": 1}`,
+			expectedErrorMsg: `invalid literal "tru" at line 1, column 106
+    ...aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa": tru, "bbbbbbbbbbbbbbbbbb...
+                                                       ^`,
+		},
+		{
+			name: "tabs",
+			raw:  "\t[\tX]",
+			expectedErrorMsg: `unexpected character 'X' at line 1, column 4
+     [ X]
+       ^`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lex := newLexer(strings.NewReader(test.raw), ModeJSON)
+			var err error
+			for err == nil {
+				_, err = lex.Token()
+			}
+			assert.EqualError(t, err, test.expectedErrorMsg)
+		})
+	}
+}
+
+func TestLexer_Recover(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		line     int
+		resumed  int
+		expected string
+	}{
+		{
+			name:     "skips rest of line",
+			raw:      "[1, tru, 3]\n[4]",
+			line:     1,
+			resumed:  2,
+			expected: "[4]",
+		},
+		{
+			name:     "resumes at next line",
+			raw:      "[1, 2\n[3, 4]",
+			line:     1,
+			resumed:  2,
+			expected: "[3, 4]",
+		},
+		{
+			name:     "resumes at next line of nested record",
+			raw:      "[[1,\n[2, 3]",
+			line:     1,
+			resumed:  2,
+			expected: "[2, 3]",
+		},
+		{
+			name:     "skips rest of current line if next line was discarded",
+			raw:      "[1,\n" + strings.Repeat("2, ", maxLineKeep) + "tru]\n[4]",
+			line:     1,
+			resumed:  3,
+			expected: "[4]",
+		},
+		{
+			name:     "end of input",
+			raw:      "[1, tru",
+			line:     1,
+			resumed:  1,
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := NewLexer(strings.NewReader(test.raw))
+			for {
+				token, err := l.Token()
+				if err != nil || token.Type == TokenTypeEOF {
+					break
+				}
+			}
+
+			resumed, err := l.Recover(test.line)
+			assert.NoError(t, err)
+			assert.Equal(t, test.resumed, resumed)
+
+			var rest strings.Builder
+			for {
+				token, err := l.Token()
+				if !assert.NoError(t, err) || token.Type == TokenTypeEOF {
+					break
+				}
+				assert.Equal(t, test.resumed, token.Pos.Line)
+				rest.WriteString(tokenText(token))
+			}
+			assert.Equal(t, strings.ReplaceAll(test.expected, " ", ""), rest.String())
+		})
+	}
+}
+
+func tokenText(t Token) string {
+	switch t.Type {
+	case TokenTypePrimitiveNumber, TokenTypePrimitiveText:
+		return t.Value
+	}
+	return strings.Trim(t.String(), "'")
+}
+
+// lexAll lexes all tokens up to EOF or the first error, positions are cleared
+func lexAll(mode Mode, raw string) ([]Token, error) {
+	lex := newLexer(strings.NewReader(raw), mode)
+	var tokens []Token
+	for {
+		token, err := lex.Token()
+		if err != nil {
+			return tokens, err
+		}
+		token.Pos = Position{}
+		tokens = append(tokens, token)
+		if token.Type == TokenTypeEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func TestLexer_Token_JSONC(t *testing.T) {
+	raw := `// leading comment
+	{
+	  "a": [1, 2,], /* block
+	  comment */ "b": true, // trailing comma
+	}`
+
+	tokens, err := lexAll(ModeJSONC, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Type: TokenTypeOpeningBrace},
+		{Type: TokenTypeText, Value: "a"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypeOpeningBracket},
+		{Type: TokenTypePrimitiveNumber, Value: "1"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: "2"},
+		{Type: TokenTypeClosingBracket},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeText, Value: "b"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypePrimitiveText, Value: "true"},
+		{Type: TokenTypeClosingBrace},
+		{Type: TokenTypeEOF},
+	}, tokens)
+}
+
+func TestLexer_Token_JSON5(t *testing.T) {
+	raw := "{unquoted: 'single \"quoted\"', $a_1: [0x1F, +.5, 5., -0XA, 1e3,], null: '\\x41\\v\\0\\\n\\q', ü: null}"
+
+	tokens, err := lexAll(ModeJSON5, raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Type: TokenTypeOpeningBrace},
+		{Type: TokenTypeText, Value: "unquoted"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypeText, Value: `single "quoted"`},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeText, Value: "$a_1"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypeOpeningBracket},
+		{Type: TokenTypePrimitiveNumber, Value: "31"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: "0.5"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: "5"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: "-10"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: "1e3"},
+		{Type: TokenTypeClosingBracket},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeText, Value: "null"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypeText, Value: "A\v\x00q"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeText, Value: "ü"},
+		{Type: TokenTypeColon},
+		{Type: TokenTypePrimitiveText, Value: "null"},
+		{Type: TokenTypeClosingBrace},
+		{Type: TokenTypeEOF},
+	}, tokens)
+}
+
+func TestLexer_Token_ModesWithError(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             Mode
+		raw              string
+		expectedErrorMsg string
+	}{
+		{"comment in JSON", ModeJSON, `// no`, `unexpected character '/' at line 1, column 1`},
+		{"unterminated comment", ModeJSONC, "1 /* no", `unterminated comment at line 1, column 3`},
+		{"single quotes in JSONC", ModeJSONC, `'a'`, `unexpected character '\'' at line 1, column 1`},
+		{"unquoted value", ModeJSON5, `[abc]`, `invalid literal "abc" at line 1, column 2`},
+		{"infinity", ModeJSON5, `[-Infinity]`, `-Infinity cannot be represented in JSON at line 1, column 2`},
+		{"nan", ModeJSON5, `NaN`, `NaN cannot be represented in JSON at line 1, column 1`},
+		{"line break", ModeJSON5, "'a\nb'", `unescaped line break in text at line 1, column 3`},
+		{"octal escape", ModeJSON5, `'\1'`, `invalid escape sequence '\1' at line 1, column 2`},
+		{"invalid hex", ModeJSON5, `0x1G`, `invalid number "0x1G" at line 1, column 1`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := lexAll(test.mode, test.raw)
+
+			var syntaxErr *SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr) {
+				syntaxErr.Snippet = ""
+				assert.EqualError(t, syntaxErr, test.expectedErrorMsg)
+			}
+		})
+	}
+}
+
+func TestLexer_Token_LeadingCommaIsKept(t *testing.T) {
+	tokens, err := lexAll(ModeJSON5, `[,]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{
+		{Type: TokenTypeOpeningBracket},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeClosingBracket},
+		{Type: TokenTypeEOF},
+	}, tokens)
+}
+
+// lexTokens lexes all tokens up to EOF or the first error
+func lexTokens(l Lexer) ([]Token, error) {
+	var tokens []Token
+	for {
+		token, err := l.Token()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+		if token.Type == TokenTypeEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func TestLexer_Token_SmallReads(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		raw  string
+	}{
+		{ModeJSON, "{\"a\\u00fc\": [1.5e3, \"ü€𝄞\", true]}\n{\"b\": null, \"tab\\t\": \"\\ud834\\udd1e\"}\n"},
+		{ModeJSONC, "// comment\n[1, /* ü */ 2,]\n"},
+		{ModeJSON5, "{'a': 'b\\\nc', d: 0x1F, e: +.5,}\n[tru"},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			expected, expectedErr := lexTokens(NewLexerMode(strings.NewReader(test.raw), test.mode))
+			actual, err := lexTokens(NewLexerMode(iotest.OneByteReader(strings.NewReader(test.raw)), test.mode))
+
+			assert.Equal(t, expected, actual)
+			assert.Equal(t, expectedErr, err)
+		})
+	}
+}
+
+func TestLexer_Token_LongerThanBuffer(t *testing.T) {
+	text := strings.Repeat("abü", bufferSize)
+	number := strings.Repeat("1", 2*bufferSize)
+	prefix := `["` + text + `", "` + text + `\n", ` + number + ", "
+	raw := prefix + "x]"
+
+	tokens, err := lexAll(ModeJSON, raw)
+	assert.Equal(t, []Token{
+		{Type: TokenTypeOpeningBracket},
+		{Type: TokenTypeText, Value: text},
+		{Type: TokenTypeComma},
+		{Type: TokenTypeText, Value: text + "\n"},
+		{Type: TokenTypeComma},
+		{Type: TokenTypePrimitiveNumber, Value: number},
+		{Type: TokenTypeComma},
+	}, tokens)
+
+	// the start of the line is gone, only its end is quoted
+	column := utf8.RuneCountInString(prefix) + 1
+	assert.EqualError(t, err, fmt.Sprintf(`invalid literal "x" at line 1, column %d
+    ...%s, x]
+    %s^`, column, strings.Repeat("1", 46), strings.Repeat(" ", 51)))
+}
+
+func TestLexer_Token_ReadError(t *testing.T) {
+	l := NewLexer(io.MultiReader(strings.NewReader("[1, "), iotest.ErrReader(errors.New("connection reset"))))
+
+	tokens, err := lexTokens(l)
+	assert.Len(t, tokens, 3)
+	assert.EqualError(t, err, "cannot lex token: connection reset")
+
+	_, err = l.Token()
+	assert.EqualError(t, err, "cannot lex token: connection reset")
+}
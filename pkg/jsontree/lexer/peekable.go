@@ -1,10 +1,14 @@
 package lexer
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type peekable struct {
 	tokenizer Tokenizer
-	peeked    *Token
+	peeked    Token
+	hasPeeked bool
 	err       error
 }
 
@@ -13,12 +17,12 @@ func (p *peekable) Peek() (Token, error) {
 		return unknownToken, p.err
 	}
 
-	if p.peeked != nil {
-		return *p.peeked, nil
+	if p.hasPeeked {
+		return p.peeked, nil
 	}
 
 	token, err := p.Token()
-	p.peeked = &token
+	p.peeked, p.hasPeeked = token, err == nil
 
 	return token, err
 }
@@ -28,18 +32,44 @@ func (p *peekable) Token() (Token, error) {
 		return unknownToken, p.err
 	}
 
-	if p.peeked != nil {
-		t := p.peeked
-		p.peeked = nil
-		return *t, nil
+	if p.hasPeeked {
+		p.hasPeeked = false
+		return p.peeked, nil
 	}
 
 	token, err := p.tokenizer.Token()
 	if err != nil {
-		err = fmt.Errorf("cannot lex token: %w", err)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			err = fmt.Errorf("cannot lex token: %w", err)
+		}
 		p.err = err
 		return unknownToken, err
 	}
 
 	return token, nil
 }
+
+// positioned is implemented by tokenizers which know about the input they read
+type positioned interface {
+	Errorf(pos Position, format string, a ...any) error
+	Recover(line int) (int, error)
+}
+
+func (p *peekable) Errorf(pos Position, format string, a ...any) error {
+	if t, ok := p.tokenizer.(positioned); ok {
+		return t.Errorf(pos, format, a...)
+	}
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (p *peekable) Recover(line int) (int, error) {
+	t, ok := p.tokenizer.(positioned)
+	if !ok {
+		return 0, fmt.Errorf("cannot recover from error: %w", p.err)
+	}
+
+	p.hasPeeked = false
+	p.err = nil
+	return t.Recover(line)
+}
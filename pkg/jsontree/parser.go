@@ -1,37 +1,64 @@
 package jsontree
 
 import (
-	"fmt"
 	"io"
 
 	"github.com/trichner/tb/pkg/jsontree/ast"
 	"github.com/trichner/tb/pkg/jsontree/lexer"
 )
 
+// MaxDepth limits how deeply arrays and objects may be nested
+const MaxDepth = 1000
+
+// Parse parses the next JSON value, io.EOF is returned if there is none left. Invalid
+// input results in a *lexer.SyntaxError.
 func Parse(l lexer.Lexer) (ast.Node, error) {
 	token, err := l.Peek()
 	if err != nil {
 		return nil, err
 	}
+	if token.Type == lexer.TokenTypeEOF {
+		return nil, io.EOF
+	}
+	return parse(l, 0)
+}
+
+func parse(l lexer.Lexer, depth int) (ast.Node, error) {
+	token, err := l.Peek()
+	if err != nil {
+		return nil, err
+	}
 
+	var n ast.Node
 	switch token.Type {
-	case lexer.TokenTypeEOF:
-		return nil, io.EOF
 	case lexer.TokenTypeOpeningBrace:
-		return parseObject(l)
+		if depth >= MaxDepth {
+			return nil, l.Errorf(token.Pos, "exceeded maximum nesting depth of %d", MaxDepth)
+		}
+		n, err = parseObject(l, depth+1)
 	case lexer.TokenTypeOpeningBracket:
-		return parseArray(l)
+		if depth >= MaxDepth {
+			return nil, l.Errorf(token.Pos, "exceeded maximum nesting depth of %d", MaxDepth)
+		}
+		n, err = parseArray(l, depth+1)
 	case lexer.TokenTypePrimitiveNumber:
-		return parseNumber(l)
+		n, err = parseNumber(l)
 	case lexer.TokenTypePrimitiveText:
-		return parsePrimitiveText(l)
+		n, err = parsePrimitiveText(l)
 	case lexer.TokenTypeText:
-		return parseText(l)
+		n, err = parseText(l)
+	default:
+		return nil, l.Errorf(token.Pos, "expected value but got %v", token)
 	}
-	return nil, fmt.Errorf("unexpected token: %v", token)
+	if err != nil {
+		return nil, err
+	}
+
+	ast.SetPos(n, token.Pos)
+	return n, nil
 }
 
-func parseArray(l lexer.Lexer) (ast.Node, error) {
+func parseArray(l lexer.Lexer, depth int) (ast.Node, error) {
 	err := skipToken(l, lexer.TokenTypeOpeningBracket)
 	if err != nil {
 		return nil, err
@@ -40,7 +67,7 @@ func parseArray(l lexer.Lexer) (ast.Node, error) {
 	// check for empty array
 	peeked, err := l.Peek()
 	if err != nil {
-		return nil, fmt.Errorf("unexpected error parsing array: %w", err)
+		return nil, err
 	}
 	if peeked.Type == lexer.TokenTypeClosingBracket {
 		// discard closing bracket
@@ -50,25 +77,25 @@ func parseArray(l lexer.Lexer) (ast.Node, error) {
 
 	var items []ast.Node
 	for {
-		item, err := Parse(l)
+		item, err := parse(l, depth)
 		if err != nil {
-			return nil, fmt.Errorf("unexpected error parsing array item: %w", err)
+			return nil, err
 		}
 		items = append(items, item)
 
 		sep, err := l.Token()
 		if err != nil {
-			return nil, fmt.Errorf("unexpected error parsing array: %w", err)
+			return nil, err
 		}
 		if sep.Type == lexer.TokenTypeClosingBracket {
 			break
 		}
 		if sep.Type != lexer.TokenTypeComma {
-			return nil, fmt.Errorf("unexpected error parsing array, expected comma but got: %v", sep.Type)
+			return nil, l.Errorf(sep.Pos, "expected ',' or ']' but got %v", sep)
 		}
 	}
 
-	return ast.NewArrayNode(items), err
+	return ast.NewArrayNode(items), nil
 }
 
 func parseText(l lexer.Lexer) (ast.TextNode, error) {
@@ -94,29 +121,29 @@ func parsePrimitiveText(l lexer.Lexer) (ast.Node, error) {
 	case "null":
 		return ast.NewNullNode(), nil
 	}
-	return nil, fmt.Errorf("unrecognized literal: %q", token.Value)
+	return nil, l.Errorf(token.Pos, "unrecognized literal: %q", token.Value)
 }
 
 func parseNumber(l lexer.Lexer) (ast.Node, error) {
 	tkn, err := l.Token()
 	if err != nil {
-		return nil, fmt.Errorf("unexpected token parsing number: %w", err)
+		return nil, err
 	}
 	if tkn.Type != lexer.TokenTypePrimitiveNumber {
-		return nil, fmt.Errorf("unexpected token, expected %s: %s", lexer.TokenTypePrimitiveNumber, tkn.Type)
+		return nil, l.Errorf(tkn.Pos, "expected number but got %v", tkn)
 	}
 	return ast.NewNumberNode(tkn.Value), nil
 }
 
-func parseObject(l lexer.Lexer) (ast.Node, error) {
+func parseObject(l lexer.Lexer, depth int) (ast.Node, error) {
 	err := skipToken(l, lexer.TokenTypeOpeningBrace)
 	if err != nil {
-		return nil, wrapUnexpectedObjectParseException(err)
+		return nil, err
 	}
 
 	tkn, err := l.Peek()
 	if err != nil {
-		return nil, wrapUnexpectedObjectParseException(err)
+		return nil, err
 	}
 	if tkn.Type == lexer.TokenTypeClosingBrace {
 		err := skipToken(l, lexer.TokenTypeClosingBrace)
@@ -133,12 +160,12 @@ func parseObject(l lexer.Lexer) (ast.Node, error) {
 
 		err = skipToken(l, lexer.TokenTypeColon)
 		if err != nil {
-			return nil, wrapUnexpectedObjectParseException(err)
+			return nil, err
 		}
 
-		val, err := Parse(l)
+		val, err := parse(l, depth)
 		if err != nil {
-			return nil, fmt.Errorf("unexpected error parsing object value: %w", err)
+			return nil, err
 		}
 
 		properties = append(properties, &ast.Property{
@@ -148,13 +175,13 @@ func parseObject(l lexer.Lexer) (ast.Node, error) {
 
 		tkn, err = l.Token()
 		if err != nil {
-			return nil, wrapUnexpectedObjectParseException(err)
+			return nil, err
 		}
 		if tkn.Type == lexer.TokenTypeClosingBrace {
 			break
 		}
 		if tkn.Type != lexer.TokenTypeComma {
-			return nil, fmt.Errorf("unexpected token parsing object, expected %q but got: %v", lexer.TokenTypeComma, tkn)
+			return nil, l.Errorf(tkn.Pos, "expected ',' or '}' but got %v", tkn)
 		}
 	}
 
@@ -164,10 +191,10 @@ func parseObject(l lexer.Lexer) (ast.Node, error) {
 func parseObjectPropertyName(l lexer.Lexer) (string, error) {
 	tkn, err := l.Token()
 	if err != nil {
-		return "", wrapUnexpectedObjectParseException(err)
+		return "", err
 	}
 	if tkn.Type != lexer.TokenTypeText {
-		return "", fmt.Errorf("unexpected token parsing object, expected %q but got: %q", lexer.TokenTypeText, tkn.Type)
+		return "", l.Errorf(tkn.Pos, "expected property name but got %v", tkn)
 	}
 	return tkn.Value, nil
 }
@@ -175,14 +202,10 @@ func parseObjectPropertyName(l lexer.Lexer) (string, error) {
 func skipToken(l lexer.Lexer, t lexer.TokenType) error {
 	tkn, err := l.Token()
 	if err != nil {
-		return fmt.Errorf("expected token %q but got: %w", t, err)
+		return err
 	}
 	if tkn.Type != t {
-		return fmt.Errorf("unexpected token, expected %q but got: %v", t, tkn)
+		return l.Errorf(tkn.Pos, "expected %v but got %v", lexer.Token{Type: t}, tkn)
 	}
 	return nil
 }
-
-func wrapUnexpectedObjectParseException(err error) error {
-	return fmt.Errorf("unexpected error parsing object: %w", err)
-}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/jsontree/ast"
 	"github.com/trichner/tb/pkg/jsontree/lexer"
 )
 
@@ -62,32 +63,32 @@ func TestParse(t *testing.T) {
 		{
 			name:             "bad 1",
 			raw:              "{",
-			expectedErrorMsg: "unexpected token parsing object, expected \"TokenTypeText\" but got: \"TokenTypeEOF\"",
+			expectedErrorMsg: "expected property name but got end of input at line 1, column 2\n    {\n     ^",
 		},
 		{
 			name:             "bad 2",
 			raw:              "[",
-			expectedErrorMsg: "unexpected error parsing array item: EOF",
+			expectedErrorMsg: "expected value but got end of input at line 1, column 2\n    [\n     ^",
 		},
 		{
 			name:             "bad 3",
 			raw:              "hello",
-			expectedErrorMsg: "unrecognized literal: \"hello\"",
+			expectedErrorMsg: "invalid literal \"hello\" at line 1, column 1\n    hello\n    ^",
 		},
 		{
 			name:             "bad 4",
 			raw:              "True",
-			expectedErrorMsg: "cannot lex token: unrecognized token: 'T'",
+			expectedErrorMsg: "unexpected character 'T' at line 1, column 1\n    True\n    ^",
 		},
 		{
 			name:             "bad 5",
 			raw:              "[[],[]",
-			expectedErrorMsg: "unexpected error parsing array, expected comma but got: TokenTypeEOF",
+			expectedErrorMsg: "expected ',' or ']' but got end of input at line 1, column 7\n    [[],[]\n          ^",
 		},
 		{
 			name:             "bad 6",
 			raw:              "{\"hi\":{},,",
-			expectedErrorMsg: "unexpected token parsing object, expected \"TokenTypeText\" but got: \"TokenTypeComma\"",
+			expectedErrorMsg: "expected property name but got ',' at line 1, column 10\n    {\"hi\":{},,\n             ^",
 		},
 	}
 	for _, test := range tests {
@@ -117,6 +118,21 @@ func TestRoundTrip(t *testing.T) {
 	assert.Equal(t, expected, string(actual))
 }
 
+func TestRoundTrip_Escapes(t *testing.T) {
+	raw := `{"a\"b":"\u00e4\ud83d\ude00\n\/"}`
+
+	l := lexer.NewLexer(strings.NewReader(raw))
+	n, err := Parse(l)
+	assert.NoError(t, err)
+
+	props := n.(ast.ObjectNode).Properties()
+	assert.Equal(t, `a"b`, props[0].Name)
+	assert.Equal(t, "ä😀\n/", props[0].Value.(ast.TextNode).Value())
+
+	actual, _ := n.MarshalJSON()
+	assert.Equal(t, `{"a\"b":"ä😀\n/"}`, string(actual))
+}
+
 func TestStream(t *testing.T) {
 	expected := `{"a":"hello"}{"b":"world"}`
 
@@ -0,0 +1,254 @@
+// Package printer formats jsontree nodes. Unlike encoding/json it keeps the order of
+// properties and the exact text of numbers unless asked otherwise.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+// Config controls the output of the printer
+type Config struct {
+	// Indent is repeated once per nesting level, the output is compact if it is empty
+	Indent string
+
+	// SortKeys sorts the properties of objects by their names, otherwise they are
+	// printed in their original order
+	SortKeys bool
+
+	// CompactArrays prints arrays which only hold scalars on a single line when indenting
+	CompactArrays bool
+
+	// ASCII escapes all non-ASCII characters in strings
+	ASCII bool
+
+	// Canonical prints the JSON Canonicalization Scheme of RFC 8785, i.e. compact
+	// output with sorted keys and numbers as ECMAScript prints them. All other
+	// options are ignored.
+	Canonical bool
+}
+
+// Fprint prints a node indented by two spaces
+func Fprint(w io.Writer, n ast.Node) error {
+	c := &Config{Indent: "  "}
+	return c.Fprint(w, n)
+}
+
+// Fprint prints a node without a trailing newline
+func (c *Config) Fprint(w io.Writer, n ast.Node) error {
+	p := &printer{Config: *c}
+	if p.Canonical {
+		p.Config = Config{SortKeys: true, Canonical: true}
+	}
+	if err := p.node(n, 0); err != nil {
+		return err
+	}
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+type printer struct {
+	Config
+	buf bytes.Buffer
+}
+
+func (p *printer) node(n ast.Node, depth int) error {
+	if n == nil {
+		p.buf.WriteString("null")
+		return nil
+	}
+
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		return p.object(n.(ast.ObjectNode), depth)
+	case ast.NodeTypeArray:
+		return p.array(n.(ast.ArrayNode), depth)
+	case ast.NodeTypeText:
+		p.text(n.(ast.TextNode).Value())
+	case ast.NodeTypeNumber:
+		return p.number(n.(ast.NumberNode).Value())
+	case ast.NodeTypeBoolean:
+		p.buf.WriteString(strconv.FormatBool(n.(ast.BooleanNode).Value()))
+	case ast.NodeTypeNull:
+		p.buf.WriteString("null")
+	default:
+		return fmt.Errorf("cannot print node of type %s", n.Type())
+	}
+	return nil
+}
+
+func (p *printer) object(o ast.ObjectNode, depth int) error {
+	props := o.Properties()
+	if len(props) == 0 {
+		p.buf.WriteString("{}")
+		return nil
+	}
+	if p.SortKeys {
+		props = slices.Clone(props)
+		slices.SortStableFunc(props, func(a, b *ast.Property) int {
+			return compareUTF16(a.Name, b.Name)
+		})
+	}
+
+	p.buf.WriteByte('{')
+	for i, prop := range props {
+		if i > 0 {
+			p.buf.WriteByte(',')
+		}
+		p.newline(depth + 1)
+		p.text(prop.Name)
+		p.buf.WriteByte(':')
+		if p.Indent != "" {
+			p.buf.WriteByte(' ')
+		}
+		if err := p.node(prop.Value, depth+1); err != nil {
+			return err
+		}
+	}
+	p.newline(depth)
+	p.buf.WriteByte('}')
+	return nil
+}
+
+func (p *printer) array(a ast.ArrayNode, depth int) error {
+	items := a.Items()
+	if len(items) == 0 {
+		p.buf.WriteString("[]")
+		return nil
+	}
+
+	inline := p.Indent == "" || (p.CompactArrays && scalars(items))
+
+	p.buf.WriteByte('[')
+	for i, item := range items {
+		if i > 0 {
+			p.buf.WriteByte(',')
+			if inline && p.Indent != "" {
+				p.buf.WriteByte(' ')
+			}
+		}
+		if !inline {
+			p.newline(depth + 1)
+		}
+		if err := p.node(item, depth+1); err != nil {
+			return err
+		}
+	}
+	if !inline {
+		p.newline(depth)
+	}
+	p.buf.WriteByte(']')
+	return nil
+}
+
+func scalars(items []ast.Node) bool {
+	for _, item := range items {
+		if item != nil && (item.Type() == ast.NodeTypeObject || item.Type() == ast.NodeTypeArray) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *printer) newline(depth int) {
+	if p.Indent == "" {
+		return
+	}
+	p.buf.WriteByte('\n')
+	for range depth {
+		p.buf.WriteString(p.Indent)
+	}
+}
+
+func (p *printer) number(s string) error {
+	if !p.Canonical {
+		p.buf.WriteString(s)
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("cannot canonicalize number %s: %w", s, err)
+	}
+	p.buf.WriteString(formatNumber(f))
+	return nil
+}
+
+// formatNumber formats a number like ECMAScript's Number.prototype.toString, which
+// RFC 8785 mandates for canonical JSON
+func formatNumber(f float64) string {
+	if f == 0 {
+		// also turns -0 into 0
+		return "0"
+	}
+
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// ECMAScript has no leading zeros in the exponent, e.g. 1e-7 rather than 1e-07
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(s, "e")
+	sign, digits := exp[:1], strings.TrimLeft(exp[1:], "0")
+	return mantissa + "e" + sign + digits
+}
+
+var hex = "0123456789abcdef"
+
+// text writes a quoted string, escaping only what JSON requires plus all non-ASCII
+// characters if asked to
+func (p *printer) text(s string) {
+	p.buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			p.buf.WriteByte('\\')
+			p.buf.WriteRune(r)
+		case r == '\b':
+			p.buf.WriteString(`\b`)
+		case r == '\f':
+			p.buf.WriteString(`\f`)
+		case r == '\n':
+			p.buf.WriteString(`\n`)
+		case r == '\r':
+			p.buf.WriteString(`\r`)
+		case r == '\t':
+			p.buf.WriteString(`\t`)
+		case r < 0x20:
+			p.unicodeEscape(r)
+		case r >= utf8.RuneSelf && p.ASCII:
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				p.unicodeEscape(r1)
+				p.unicodeEscape(r2)
+			} else {
+				p.unicodeEscape(r)
+			}
+		default:
+			// invalid UTF-8 is written as U+FFFD by WriteRune
+			p.buf.WriteRune(r)
+		}
+	}
+	p.buf.WriteByte('"')
+}
+
+func (p *printer) unicodeEscape(r rune) {
+	p.buf.WriteString(`\u`)
+	for shift := 12; shift >= 0; shift -= 4 {
+		p.buf.WriteByte(hex[(r>>shift)&0xF])
+	}
+}
+
+// compareUTF16 orders strings by their UTF-16 code units as RFC 8785 requires
+func compareUTF16(a, b string) int {
+	return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+}
@@ -0,0 +1,115 @@
+package printer
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+func parseJson(t *testing.T, s string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(s)))
+	assert.NoError(t, err)
+	return n
+}
+
+func format(t *testing.T, c *Config, s string) string {
+	var sb strings.Builder
+	assert.NoError(t, c.Fprint(&sb, parseJson(t, s)))
+	return sb.String()
+}
+
+func TestFprint(t *testing.T) {
+	var sb strings.Builder
+	err := Fprint(&sb, parseJson(t, `{"z":1.50,"a":[1,{"b":null}],"e":{},"f":[]}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "z": 1.50,
+  "a": [
+    1,
+    {
+      "b": null
+    }
+  ],
+  "e": {},
+  "f": []
+}`, sb.String())
+}
+
+func TestConfig_Fprint(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		input    string
+		expected string
+	}{
+		{"compact", Config{}, ` { "b" : [ 1 , 2 ] , "a" : true } `, `{"b":[1,2],"a":true}`},
+		{"sort keys", Config{SortKeys: true}, `{"b":1,"a":{"d":2,"c":3}}`, `{"a":{"c":3,"d":2},"b":1}`},
+		{"exact numbers", Config{}, `[1.0,1e2,-0,12345678901234567890123]`, `[1.0,1e2,-0,12345678901234567890123]`},
+		{
+			"compact arrays",
+			Config{Indent: "\t", CompactArrays: true},
+			`{"a":[1,"x",null],"b":[[1]]}`,
+			"{\n\t\"a\": [1, \"x\", null],\n\t\"b\": [\n\t\t[1]\n\t]\n}",
+		},
+		{"ascii", Config{ASCII: true}, `"grüße 😀"`, `"gr\u00fc\u00dfe \ud83d\ude00"`},
+		{"escapes", Config{}, `"\"\\\/\b\f\n\r\t\u0001 "`, "\"\\\"\\\\/\\b\\f\\n\\r\\t\\u0001 \""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, format(t, &tt.config, tt.input))
+		})
+	}
+}
+
+func TestConfig_Fprint_Canonical(t *testing.T) {
+	c := &Config{Canonical: true, Indent: "  ", ASCII: true}
+
+	// examples from RFC 8785 section 3.2.2 and 3.2.3
+	actual := format(t, c, `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`)
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, actual)
+
+	actual = format(t, c, `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`)
+	assert.Equal(t, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\","+
+		"\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}", actual)
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "0"},
+		{1, "1"},
+		{-1.5, "-1.5"},
+		{1e20, "100000000000000000000"},
+		{1e21, "1e+21"},
+		{1e-6, "0.000001"},
+		{1e-7, "1e-7"},
+		{5e-324, "5e-324"},
+		{1.7976931348623157e308, "1.7976931348623157e+308"},
+		{9007199254740992, "9007199254740992"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatNumber(tt.input))
+		})
+	}
+}
+
+func TestConfig_Fprint_CanonicalOutOfRange(t *testing.T) {
+	var sb strings.Builder
+	err := (&Config{Canonical: true}).Fprint(&sb, parseJson(t, `[1e400]`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,547 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+// expr is a compiled filter, it maps one input to zero or more outputs
+type expr interface {
+	eval(in ast.Node) ([]ast.Node, error)
+}
+
+type identityExpr struct{}
+
+func (e *identityExpr) eval(in ast.Node) ([]ast.Node, error) {
+	return []ast.Node{in}, nil
+}
+
+type recurseExpr struct{}
+
+func (e *recurseExpr) eval(in ast.Node) ([]ast.Node, error) {
+	var out []ast.Node
+	var walk func(n ast.Node)
+	walk = func(n ast.Node) {
+		out = append(out, n)
+		for _, c := range children(n) {
+			walk(c)
+		}
+	}
+	walk(in)
+	return out, nil
+}
+
+type literalExpr struct {
+	value ast.Node
+}
+
+func (e *literalExpr) eval(_ ast.Node) ([]ast.Node, error) {
+	return []ast.Node{e.value}, nil
+}
+
+type pipeExpr struct {
+	left, right expr
+}
+
+func (e *pipeExpr) eval(in ast.Node) ([]ast.Node, error) {
+	lefts, err := e.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []ast.Node
+	for _, l := range lefts {
+		rights, err := e.right.eval(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rights...)
+	}
+	return out, nil
+}
+
+type commaExpr struct {
+	left, right expr
+}
+
+func (e *commaExpr) eval(in ast.Node) ([]ast.Node, error) {
+	lefts, err := e.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	rights, err := e.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	return append(lefts, rights...), nil
+}
+
+type indexExpr struct {
+	target, index expr
+}
+
+func (e *indexExpr) eval(in ast.Node) ([]ast.Node, error) {
+	targets, err := e.target.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	indices, err := e.index.eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Node
+	for _, t := range targets {
+		for _, i := range indices {
+			v, err := index(t, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func index(n ast.Node, i ast.Node) (ast.Node, error) {
+	switch {
+	case n.Type() == ast.NodeTypeNull && (i.Type() == ast.NodeTypeText || i.Type() == ast.NodeTypeNumber):
+		return ast.NewNullNode(), nil
+	case n.Type() == ast.NodeTypeObject && i.Type() == ast.NodeTypeText:
+		if v := property(n.(ast.ObjectNode), i.(ast.TextNode).Value()); v != nil {
+			return v, nil
+		}
+		return ast.NewNullNode(), nil
+	case n.Type() == ast.NodeTypeArray && i.Type() == ast.NodeTypeNumber:
+		f, err := i.(ast.NumberNode).ToFloat64()
+		if err != nil {
+			return nil, err
+		}
+		items := n.(ast.ArrayNode).Items()
+		idx := int(math.Floor(f))
+		if idx < 0 {
+			idx += len(items)
+		}
+		if idx < 0 || idx >= len(items) {
+			return ast.NewNullNode(), nil
+		}
+		return items[idx], nil
+	}
+	return nil, fmt.Errorf("cannot index %s with %s", typeName(n), describe(i))
+}
+
+func property(o ast.ObjectNode, name string) ast.Node {
+	v, ok := o.Get(name)
+	if ok && v == nil {
+		return ast.NewNullNode()
+	}
+	return v
+}
+
+type sliceExpr struct {
+	target, from, to expr
+}
+
+func (e *sliceExpr) eval(in ast.Node) ([]ast.Node, error) {
+	targets, err := e.target.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	from, err := e.bound(in, e.from)
+	if err != nil {
+		return nil, err
+	}
+	to, err := e.bound(in, e.to)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Node
+	for _, t := range targets {
+		switch t.Type() {
+		case ast.NodeTypeNull:
+			out = append(out, t)
+		case ast.NodeTypeArray:
+			items := t.(ast.ArrayNode).Items()
+			lo, hi := sliceBounds(from, to, len(items))
+			out = append(out, ast.NewArrayNode(items[lo:hi]))
+		case ast.NodeTypeText:
+			runes := []rune(t.(ast.TextNode).Value())
+			lo, hi := sliceBounds(from, to, len(runes))
+			out = append(out, ast.NewTextNode(string(runes[lo:hi])))
+		default:
+			return nil, fmt.Errorf("cannot slice %s", typeName(t))
+		}
+	}
+	return out, nil
+}
+
+// bound evaluates a slice bound, nil stands for an omitted bound
+func (e *sliceExpr) bound(in ast.Node, b expr) (*int, error) {
+	if b == nil {
+		return nil, nil
+	}
+	values, err := b.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 1 || values[0].Type() != ast.NodeTypeNumber {
+		return nil, fmt.Errorf("slice bounds must be single numbers")
+	}
+	f, err := values[0].(ast.NumberNode).ToFloat64()
+	if err != nil {
+		return nil, err
+	}
+	i := int(math.Floor(f))
+	return &i, nil
+}
+
+func sliceBounds(from, to *int, length int) (int, int) {
+	clamp := func(b *int, def int) int {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += length
+		}
+		return max(0, min(i, length))
+	}
+	lo, hi := clamp(from, 0), clamp(to, length)
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+type iterateExpr struct {
+	target expr
+}
+
+func (e *iterateExpr) eval(in ast.Node) ([]ast.Node, error) {
+	targets, err := e.target.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []ast.Node
+	for _, t := range targets {
+		if t.Type() != ast.NodeTypeArray && t.Type() != ast.NodeTypeObject {
+			return nil, fmt.Errorf("cannot iterate over %s", describe(t))
+		}
+		out = append(out, children(t)...)
+	}
+	return out, nil
+}
+
+type compareExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e *compareExpr) eval(in ast.Node) ([]ast.Node, error) {
+	lefts, err := e.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	rights, err := e.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Node
+	for _, l := range lefts {
+		for _, r := range rights {
+			c := compare(l, r)
+			var b bool
+			switch e.op {
+			case "==":
+				b = c == 0
+			case "!=":
+				b = c != 0
+			case "<":
+				b = c < 0
+			case "<=":
+				b = c <= 0
+			case ">":
+				b = c > 0
+			case ">=":
+				b = c >= 0
+			default:
+				return nil, fmt.Errorf("unknown operator '%s'", e.op)
+			}
+			out = append(out, ast.NewBooleanNode(b))
+		}
+	}
+	return out, nil
+}
+
+type andExpr struct {
+	left, right expr
+}
+
+func (e *andExpr) eval(in ast.Node) ([]ast.Node, error) {
+	return shortCircuit(in, e.left, e.right, false)
+}
+
+type orExpr struct {
+	left, right expr
+}
+
+func (e *orExpr) eval(in ast.Node) ([]ast.Node, error) {
+	return shortCircuit(in, e.left, e.right, true)
+}
+
+// shortCircuit evaluates 'and' as well as 'or', the right side is only
+// evaluated if the left side is not already decisive
+func shortCircuit(in ast.Node, left, right expr, decisive bool) ([]ast.Node, error) {
+	lefts, err := left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	var out []ast.Node
+	for _, l := range lefts {
+		if truthy(l) == decisive {
+			out = append(out, ast.NewBooleanNode(decisive))
+			continue
+		}
+		rights, err := right.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rights {
+			out = append(out, ast.NewBooleanNode(truthy(r)))
+		}
+	}
+	return out, nil
+}
+
+type arrayExpr struct {
+	items expr
+}
+
+func (e *arrayExpr) eval(in ast.Node) ([]ast.Node, error) {
+	if e.items == nil {
+		return []ast.Node{ast.NewArrayNode(nil)}, nil
+	}
+	items, err := e.items.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	return []ast.Node{ast.NewArrayNode(items)}, nil
+}
+
+type objectEntry struct {
+	key, value expr
+}
+
+type objectExpr struct {
+	entries []objectEntry
+}
+
+func (e *objectExpr) eval(in ast.Node) ([]ast.Node, error) {
+	// every combination of key and value outputs yields an object
+	objects := [][]*ast.Property{nil}
+	for _, entry := range e.entries {
+		keys, err := entry.key.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		values, err := entry.value.eval(in)
+		if err != nil {
+			return nil, err
+		}
+
+		var next [][]*ast.Property
+		for _, props := range objects {
+			for _, k := range keys {
+				if k.Type() != ast.NodeTypeText {
+					return nil, fmt.Errorf("object keys must be strings, got %s", describe(k))
+				}
+				name := k.(ast.TextNode).Value()
+				for _, v := range values {
+					next = append(next, withProperty(props, name, v))
+				}
+			}
+		}
+		objects = next
+	}
+
+	out := make([]ast.Node, 0, len(objects))
+	for _, props := range objects {
+		out = append(out, ast.NewObjectNode(props))
+	}
+	return out, nil
+}
+
+// withProperty returns a copy of props with name set to value, an existing
+// property of the same name is replaced
+func withProperty(props []*ast.Property, name string, value ast.Node) []*ast.Property {
+	out := make([]*ast.Property, 0, len(props)+1)
+	replaced := false
+	for _, p := range props {
+		if p.Name == name {
+			out = append(out, &ast.Property{Name: name, Value: value})
+			replaced = true
+			continue
+		}
+		out = append(out, p)
+	}
+	if !replaced {
+		out = append(out, &ast.Property{Name: name, Value: value})
+	}
+	return out
+}
+
+type callExpr struct {
+	name string
+	fn   func(in ast.Node, args []expr) ([]ast.Node, error)
+	args []expr
+}
+
+func (e *callExpr) eval(in ast.Node) ([]ast.Node, error) {
+	out, err := e.fn(in, e.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.name, err)
+	}
+	return out, nil
+}
+
+func children(n ast.Node) []ast.Node {
+	switch n.Type() {
+	case ast.NodeTypeArray:
+		return n.(ast.ArrayNode).Items()
+	case ast.NodeTypeObject:
+		props := n.(ast.ObjectNode).Properties()
+		values := make([]ast.Node, 0, len(props))
+		for _, p := range props {
+			if p.Value == nil {
+				values = append(values, ast.NewNullNode())
+				continue
+			}
+			values = append(values, p.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+// truthy follows jq, only false and null are falsy
+func truthy(n ast.Node) bool {
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		return false
+	case ast.NodeTypeBoolean:
+		return n.(ast.BooleanNode).Value()
+	}
+	return true
+}
+
+func typeName(n ast.Node) string {
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		return "null"
+	case ast.NodeTypeBoolean:
+		return "boolean"
+	case ast.NodeTypeNumber:
+		return "number"
+	case ast.NodeTypeText:
+		return "string"
+	case ast.NodeTypeArray:
+		return "array"
+	case ast.NodeTypeObject:
+		return "object"
+	}
+	return "unknown"
+}
+
+// describe renders a node for error messages, e.g. 'number (42)'
+func describe(n ast.Node) string {
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return typeName(n)
+	}
+	s := string(b)
+	if len(s) > 16 {
+		s = s[:13] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", typeName(n), s)
+}
+
+// typeOrder ranks types the way jq sorts them
+var typeOrder = map[ast.NodeType]int{
+	ast.NodeTypeNull:    0,
+	ast.NodeTypeBoolean: 1,
+	ast.NodeTypeNumber:  2,
+	ast.NodeTypeText:    3,
+	ast.NodeTypeArray:   4,
+	ast.NodeTypeObject:  5,
+}
+
+// compare orders any two nodes, objects compare equal regardless of the
+// order of their properties
+func compare(a, b ast.Node) int {
+	if ta, tb := typeOrder[a.Type()], typeOrder[b.Type()]; ta != tb {
+		return ta - tb
+	}
+
+	switch a.Type() {
+	case ast.NodeTypeBoolean:
+		va, vb := a.(ast.BooleanNode).Value(), b.(ast.BooleanNode).Value()
+		switch {
+		case va == vb:
+			return 0
+		case vb:
+			return -1
+		}
+		return 1
+	case ast.NodeTypeNumber:
+		fa, _ := a.(ast.NumberNode).ToFloat64()
+		fb, _ := b.(ast.NumberNode).ToFloat64()
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case ast.NodeTypeText:
+		return strings.Compare(a.(ast.TextNode).Value(), b.(ast.TextNode).Value())
+	case ast.NodeTypeArray:
+		ia, ib := a.(ast.ArrayNode).Items(), b.(ast.ArrayNode).Items()
+		for i := 0; i < len(ia) && i < len(ib); i++ {
+			if c := compare(ia[i], ib[i]); c != 0 {
+				return c
+			}
+		}
+		return len(ia) - len(ib)
+	case ast.NodeTypeObject:
+		oa, ob := a.(ast.ObjectNode), b.(ast.ObjectNode)
+		ka, kb := sortedKeys(oa), sortedKeys(ob)
+		for i := 0; i < len(ka) && i < len(kb); i++ {
+			if c := strings.Compare(ka[i], kb[i]); c != 0 {
+				return c
+			}
+		}
+		if len(ka) != len(kb) {
+			return len(ka) - len(kb)
+		}
+		for _, k := range ka {
+			if c := compare(property(oa, k), property(ob, k)); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func sortedKeys(o ast.ObjectNode) []string {
+	keys := make([]string, 0, len(o.Properties()))
+	for _, p := range o.Properties() {
+		keys = append(keys, p.Name)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+type function struct {
+	arity int
+	call  func(in ast.Node, args []expr) ([]ast.Node, error)
+}
+
+// functions lists the builtins by name, only a subset of what jq offers
+var functions = map[string]function{
+	"empty":    {arity: 0, call: fnEmpty},
+	"not":      {arity: 0, call: fnNot},
+	"length":   {arity: 0, call: fnLength},
+	"keys":     {arity: 0, call: fnKeys},
+	"type":     {arity: 0, call: fnType},
+	"tostring": {arity: 0, call: fnToString},
+	"has":      {arity: 1, call: fnHas},
+	"select":   {arity: 1, call: fnSelect},
+	"map":      {arity: 1, call: fnMap},
+}
+
+func fnEmpty(_ ast.Node, _ []expr) ([]ast.Node, error) {
+	return nil, nil
+}
+
+func fnNot(in ast.Node, _ []expr) ([]ast.Node, error) {
+	return []ast.Node{ast.NewBooleanNode(!truthy(in))}, nil
+}
+
+func fnLength(in ast.Node, _ []expr) ([]ast.Node, error) {
+	var n int
+	switch in.Type() {
+	case ast.NodeTypeNull:
+		n = 0
+	case ast.NodeTypeText:
+		n = utf8.RuneCountInString(in.(ast.TextNode).Value())
+	case ast.NodeTypeArray:
+		n = len(in.(ast.ArrayNode).Items())
+	case ast.NodeTypeObject:
+		n = len(in.(ast.ObjectNode).Properties())
+	case ast.NodeTypeNumber:
+		f, err := in.(ast.NumberNode).ToFloat64()
+		if err != nil {
+			return nil, err
+		}
+		return []ast.Node{ast.NewNumberNode(strconv.FormatFloat(math.Abs(f), 'g', -1, 64))}, nil
+	default:
+		return nil, fmt.Errorf("%s has no length", describe(in))
+	}
+	return []ast.Node{ast.NewNumberNode(strconv.Itoa(n))}, nil
+}
+
+func fnKeys(in ast.Node, _ []expr) ([]ast.Node, error) {
+	var keys []ast.Node
+	switch in.Type() {
+	case ast.NodeTypeObject:
+		for _, k := range sortedKeys(in.(ast.ObjectNode)) {
+			keys = append(keys, ast.NewTextNode(k))
+		}
+	case ast.NodeTypeArray:
+		for i := range in.(ast.ArrayNode).Items() {
+			keys = append(keys, ast.NewNumberNode(strconv.Itoa(i)))
+		}
+	default:
+		return nil, fmt.Errorf("%s has no keys", describe(in))
+	}
+	return []ast.Node{ast.NewArrayNode(keys)}, nil
+}
+
+func fnType(in ast.Node, _ []expr) ([]ast.Node, error) {
+	return []ast.Node{ast.NewTextNode(typeName(in))}, nil
+}
+
+func fnToString(in ast.Node, _ []expr) ([]ast.Node, error) {
+	if in.Type() == ast.NodeTypeText {
+		return []ast.Node{in}, nil
+	}
+	b, err := in.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return []ast.Node{ast.NewTextNode(string(b))}, nil
+}
+
+func fnHas(in ast.Node, args []expr) ([]ast.Node, error) {
+	keys, err := args[0].eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Node
+	for _, k := range keys {
+		switch {
+		case in.Type() == ast.NodeTypeObject && k.Type() == ast.NodeTypeText:
+			found := property(in.(ast.ObjectNode), k.(ast.TextNode).Value()) != nil
+			out = append(out, ast.NewBooleanNode(found))
+		case in.Type() == ast.NodeTypeArray && k.Type() == ast.NodeTypeNumber:
+			f, err := k.(ast.NumberNode).ToFloat64()
+			if err != nil {
+				return nil, err
+			}
+			found := f >= 0 && f < float64(len(in.(ast.ArrayNode).Items()))
+			out = append(out, ast.NewBooleanNode(found))
+		default:
+			return nil, fmt.Errorf("cannot check whether %s has a key %s", typeName(in), describe(k))
+		}
+	}
+	return out, nil
+}
+
+func fnSelect(in ast.Node, args []expr) ([]ast.Node, error) {
+	conds, err := args[0].eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ast.Node
+	for _, c := range conds {
+		if truthy(c) {
+			out = append(out, in)
+		}
+	}
+	return out, nil
+}
+
+func fnMap(in ast.Node, args []expr) ([]ast.Node, error) {
+	if in.Type() != ast.NodeTypeArray && in.Type() != ast.NodeTypeObject {
+		return nil, fmt.Errorf("cannot iterate over %s", describe(in))
+	}
+
+	var items []ast.Node
+	for _, c := range children(in) {
+		mapped, err := args[0].eval(c)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, mapped...)
+	}
+	return []ast.Node{ast.NewArrayNode(items)}, nil
+}
@@ -0,0 +1,376 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(s string) (expr, error) {
+	tokens, err := scan(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	e, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(typ tokenType) bool {
+	if p.peek().typ == typ {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptKeyword(keyword string) bool {
+	if t := p.peek(); t.typ == tokenIdent && t.value == keyword {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(typ tokenType, what string) error {
+	if t := p.peek(); t.typ != typ {
+		return fmt.Errorf("expected %s but got %v at offset %d", what, t, t.pos)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) unexpected(t token) error {
+	return fmt.Errorf("unexpected %v at offset %d", t, t.pos)
+}
+
+// parsePipe parses the lowest precedence level: 'a, b | c'
+func (p *parser) parsePipe() (expr, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(tokenPipe) {
+		return left, nil
+	}
+	right, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	return &pipeExpr{left: left, right: right}, nil
+}
+
+func (p *parser) parseComma() (expr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenComma) {
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = &commaExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and") {
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseCompare() (expr, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.typ != tokenOp {
+		return left, nil
+	}
+	p.next()
+	right, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	return &compareExpr{op: t.value, left: left, right: right}, nil
+}
+
+func (p *parser) parsePostfix() (expr, error) {
+	e, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().typ {
+		case tokenDot:
+			p.next()
+			e, err = p.parseAccess(e)
+		case tokenOpenBracket:
+			e, err = p.parseBracket(e)
+		default:
+			return e, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseAccess parses whatever follows a '.', i.e. a field name, a quoted
+// field name or a bracket suffix.
+func (p *parser) parseAccess(target expr) (expr, error) {
+	switch t := p.peek(); t.typ {
+	case tokenIdent:
+		p.next()
+		return &indexExpr{target: target, index: &literalExpr{value: ast.NewTextNode(t.value)}}, nil
+	case tokenString:
+		p.next()
+		return &indexExpr{target: target, index: &literalExpr{value: ast.NewTextNode(t.value)}}, nil
+	case tokenOpenBracket:
+		return p.parseBracket(target)
+	default:
+		return nil, fmt.Errorf("expected field name after '.' but got %v at offset %d", t, t.pos)
+	}
+}
+
+// parseBracket parses '[]', '[index]' and '[from:to]'
+func (p *parser) parseBracket(target expr) (expr, error) {
+	if err := p.expect(tokenOpenBracket, "'['"); err != nil {
+		return nil, err
+	}
+	if p.accept(tokenCloseBracket) {
+		return &iterateExpr{target: target}, nil
+	}
+
+	var from, to expr
+	var err error
+	if p.peek().typ != tokenColon {
+		from, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if p.accept(tokenCloseBracket) {
+			return &indexExpr{target: target, index: from}, nil
+		}
+	}
+	if err := p.expect(tokenColon, "':' or ']'"); err != nil {
+		return nil, err
+	}
+	if p.peek().typ != tokenCloseBracket {
+		to, err = p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(tokenCloseBracket, "']'"); err != nil {
+		return nil, err
+	}
+	return &sliceExpr{target: target, from: from, to: to}, nil
+}
+
+func (p *parser) parseTerm() (expr, error) {
+	t := p.next()
+	switch t.typ {
+	case tokenDot:
+		next := p.peek()
+		switch {
+		case next.typ == tokenDot && next.pos == t.pos+1:
+			p.next()
+			return &recurseExpr{}, nil
+		case next.typ == tokenIdent && next.pos == t.pos+1, next.typ == tokenString, next.typ == tokenOpenBracket:
+			return p.parseAccess(&identityExpr{})
+		}
+		return &identityExpr{}, nil
+	case tokenNumber:
+		if _, err := strconv.ParseFloat(t.value, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %v at offset %d", t, t.pos)
+		}
+		return &literalExpr{value: ast.NewNumberNode(t.value)}, nil
+	case tokenString:
+		return &literalExpr{value: ast.NewTextNode(t.value)}, nil
+	case tokenOpenParen:
+		e, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, "')'"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokenOpenBracket:
+		if p.accept(tokenCloseBracket) {
+			return &arrayExpr{}, nil
+		}
+		e, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "']'"); err != nil {
+			return nil, err
+		}
+		return &arrayExpr{items: e}, nil
+	case tokenOpenBrace:
+		return p.parseObject()
+	case tokenIdent:
+		return p.parseIdent(t)
+	}
+	return nil, p.unexpected(t)
+}
+
+func (p *parser) parseIdent(t token) (expr, error) {
+	switch t.value {
+	case "true":
+		return &literalExpr{value: ast.NewBooleanNode(true)}, nil
+	case "false":
+		return &literalExpr{value: ast.NewBooleanNode(false)}, nil
+	case "null":
+		return &literalExpr{value: ast.NewNullNode()}, nil
+	case "and", "or":
+		return nil, p.unexpected(t)
+	}
+
+	var args []expr
+	if p.accept(tokenOpenParen) {
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.accept(tokenSemicolon) {
+				break
+			}
+		}
+		if err := p.expect(tokenCloseParen, "')'"); err != nil {
+			return nil, err
+		}
+	}
+
+	fn, ok := functions[t.value]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s/%d at offset %d", t.value, len(args), t.pos)
+	}
+	if fn.arity != len(args) {
+		return nil, fmt.Errorf("function %s takes %d arguments but got %d at offset %d", t.value, fn.arity, len(args), t.pos)
+	}
+	return &callExpr{name: t.value, fn: fn.call, args: args}, nil
+}
+
+// parseObject parses an object construction such as '{a, "b": .x, (.k): .v}'
+func (p *parser) parseObject() (expr, error) {
+	var entries []objectEntry
+	if p.accept(tokenCloseBrace) {
+		return &objectExpr{}, nil
+	}
+	for {
+		var key expr
+		var shorthand expr
+
+		t := p.next()
+		switch t.typ {
+		case tokenIdent, tokenString:
+			name := &literalExpr{value: ast.NewTextNode(t.value)}
+			key = name
+			shorthand = &indexExpr{target: &identityExpr{}, index: name}
+		case tokenOpenParen:
+			e, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenCloseParen, "')'"); err != nil {
+				return nil, err
+			}
+			key = e
+		default:
+			return nil, fmt.Errorf("expected object key but got %v at offset %d", t, t.pos)
+		}
+
+		value := shorthand
+		if p.accept(tokenColon) {
+			e, err := p.parseObjectValue()
+			if err != nil {
+				return nil, err
+			}
+			value = e
+		} else if value == nil {
+			return nil, fmt.Errorf("expected ':' after computed object key at offset %d", p.peek().pos)
+		}
+		entries = append(entries, objectEntry{key: key, value: value})
+
+		if p.accept(tokenCloseBrace) {
+			return &objectExpr{entries: entries}, nil
+		}
+		if err := p.expect(tokenComma, "',' or '}'"); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseObjectValue parses a value within an object construction, commas
+// separate entries there and therefore need parentheses to be used in a value.
+func (p *parser) parseObjectValue() (expr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(tokenPipe) {
+		return left, nil
+	}
+	right, err := p.parseObjectValue()
+	if err != nil {
+		return nil, err
+	}
+	return &pipeExpr{left: left, right: right}, nil
+}
@@ -0,0 +1,66 @@
+// Package query evaluates a subset of the jq filter language on jsontree nodes.
+//
+// Supported are paths ('.', '.a.b', '."a b"', '.[0]', '.[-1]', '.[2:4]', '.[]', '..'),
+// pipes and commas, comparisons combined with 'and' and 'or', literals, array and
+// object construction ('[.[] | .id]', '{id, name: .user.name}') and the builtins
+// empty, not, length, keys, type, tostring, has(k), select(f) and map(f).
+package query
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+type Query struct {
+	source string
+	root   expr
+}
+
+// Compile parses a filter such as '.items[] | select(.price > 10) | {id, price}'
+func Compile(filter string) (*Query, error) {
+	root, err := parse(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query '%s': %w", filter, err)
+	}
+	return &Query{source: filter, root: root}, nil
+}
+
+// Run applies the query to a single input and returns all of its outputs
+func (q *Query) Run(in ast.Node) ([]ast.Node, error) {
+	return q.root.eval(in)
+}
+
+func (q *Query) String() string {
+	return q.source
+}
+
+// Apply runs the query on every JSON document read from r, e.g. a stream of
+// NDJSON records, and passes all outputs to emit
+func (q *Query) Apply(r io.Reader, emit func(ast.Node) error) error {
+	return q.ApplyDecoder(jsontree.NewDecoder(r), emit)
+}
+
+// ApplyDecoder is like Apply for records of any format
+func (q *Query) ApplyDecoder(d *jsontree.Decoder, emit func(ast.Node) error) error {
+	for {
+		n, err := d.Decode()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		out, err := q.Run(n)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", d.Record(), err)
+		}
+		for _, o := range out {
+			if err := emit(o); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+const input = `{"id":7,"name":"widget","quote":"say \"hi\"","tags":["a","b","c"],"price":12.5,"owner":{"name":"bob","active":true},"items":[{"sku":"x","qty":1},{"sku":"y","qty":5},{"sku":"z","qty":3}],"note":null}`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: ".", expected: []string{input}},
+		{query: ".id", expected: []string{`7`}},
+		{query: ".owner.name", expected: []string{`"bob"`}},
+		{query: `."owner"["active"]`, expected: []string{`true`}},
+		{query: ".missing", expected: []string{`null`}},
+		{query: ".missing.deeper", expected: []string{`null`}},
+		{query: ".tags[0]", expected: []string{`"a"`}},
+		{query: ".tags[-1]", expected: []string{`"c"`}},
+		{query: ".tags[5]", expected: []string{`null`}},
+		{query: ".tags[1:]", expected: []string{`["b","c"]`}},
+		{query: ".tags[:-1]", expected: []string{`["a","b"]`}},
+		{query: ".tags[]", expected: []string{`"a"`, `"b"`, `"c"`}},
+		{query: ".items[].sku", expected: []string{`"x"`, `"y"`, `"z"`}},
+		{query: ".items[] | .qty", expected: []string{`1`, `5`, `3`}},
+		{query: ".id, .name", expected: []string{`7`, `"widget"`}},
+		{query: ".items[] | select(.qty > 2) | .sku", expected: []string{`"y"`, `"z"`}},
+		{query: `.items[] | select(.sku == "x" or .qty >= 5) | .sku`, expected: []string{`"x"`, `"y"`}},
+		{query: `.items[] | select(.qty > 1 and .sku != "z") | .sku`, expected: []string{`"y"`}},
+		{query: ".items[] | select(.qty > 1 | not) | .sku", expected: []string{`"x"`}},
+		{query: "{id, owner: .owner.name}", expected: []string{`{"id":7,"owner":"bob"}`}},
+		{query: `{"the name": .name, (.owner.name): .id}`, expected: []string{`{"the name":"widget","bob":7}`}},
+		{query: "{sku: .items[].sku}", expected: []string{`{"sku":"x"}`, `{"sku":"y"}`, `{"sku":"z"}`}},
+		{query: "[.items[].qty]", expected: []string{`[1,5,3]`}},
+		{query: "[]", expected: []string{`[]`}},
+		{query: ".items | map(.qty)", expected: []string{`[1,5,3]`}},
+		{query: ".items | map(select(.qty < 5) | .sku) | length", expected: []string{`2`}},
+		{query: ".owner | keys", expected: []string{`["active","name"]`}},
+		{query: `has("note"), has("nope")`, expected: []string{`true`, `false`}},
+		{query: ".note, .price, .tags, .owner | type", expected: []string{`"null"`, `"number"`, `"array"`, `"object"`}},
+		{query: ".id | tostring", expected: []string{`"7"`}},
+		{query: ".tags[] | select(. == \"b\" | not)", expected: []string{`"a"`, `"c"`}},
+		{query: "empty", expected: nil},
+		{query: `.owner == {"active": true, "name": "bob"}`, expected: []string{`true`}},
+		{query: `[.. | select(type == "number")]`, expected: []string{`[7,12.5,1,5,3]`}},
+		{query: `.quote == "say \"hi\"", (."quote" | length)`, expected: []string{`true`, `8`}},
+		{query: "1, \"s\", true, null", expected: []string{`1`, `"s"`, `true`, `null`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Compile(tt.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			out, err := q.Run(parseJson(t, input))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.expected, marshal(t, out))
+		})
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		query            string
+		expectedErrorMsg string
+	}{
+		{query: ".id.x", expectedErrorMsg: `cannot index number with string ("x")`},
+		{query: ".id[]", expectedErrorMsg: `cannot iterate over number (7)`},
+		{query: ".tags.x", expectedErrorMsg: `cannot index array with string ("x")`},
+		{query: "{(.id): 1}", expectedErrorMsg: `object keys must be strings, got number (7)`},
+		{query: ".owner.active | length", expectedErrorMsg: `length: boolean (true) has no length`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Compile(tt.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			_, err = q.Run(parseJson(t, input))
+			assert.EqualError(t, err, tt.expectedErrorMsg)
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := []struct {
+		query            string
+		expectedErrorMsg string
+	}{
+		{query: ".a |", expectedErrorMsg: "invalid query '.a |': unexpected end of query at offset 4"},
+		{query: ".[1", expectedErrorMsg: "invalid query '.[1': expected ':' or ']' but got end of query at offset 3"},
+		{query: "foo", expectedErrorMsg: "invalid query 'foo': unknown function foo/0 at offset 0"},
+		{query: "select", expectedErrorMsg: "invalid query 'select': function select takes 1 arguments but got 0 at offset 0"},
+		{query: `"abc`, expectedErrorMsg: `invalid query '"abc': unterminated string at offset 0`},
+		{query: `."a\x"`, expectedErrorMsg: `invalid query '."a\x"': invalid escape sequence '\x' at offset 3`},
+		{query: "{(.a)}", expectedErrorMsg: "invalid query '{(.a)}': expected ':' after computed object key at offset 5"},
+		{query: ".a ^ .b", expectedErrorMsg: "invalid query '.a ^ .b': unexpected character '^' at offset 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := Compile(tt.query)
+			assert.EqualError(t, err, tt.expectedErrorMsg)
+		})
+	}
+}
+
+func parseJson(t *testing.T, s string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(s)))
+	assert.NoError(t, err)
+	return n
+}
+
+func marshal(t *testing.T, nodes []ast.Node) []string {
+	var out []string
+	for _, n := range nodes {
+		b, err := n.MarshalJSON()
+		assert.NoError(t, err)
+		out = append(out, string(b))
+	}
+	return out
+}
+
+func TestApply(t *testing.T) {
+	q, err := Compile("select(.qty > 1) | .sku")
+	assert.NoError(t, err)
+
+	var out []ast.Node
+	err = q.Apply(strings.NewReader("{\"sku\":\"x\",\"qty\":1}\n{\"sku\":\"y\",\"qty\":5}\n{\"sku\":\"z\",\"qty\":3}\n"), func(n ast.Node) error {
+		out = append(out, n)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`"y"`, `"z"`}, marshal(t, out))
+}
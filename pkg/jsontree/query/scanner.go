@@ -0,0 +1,171 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenDot
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenPipe
+	tokenComma
+	tokenColon
+	tokenSemicolon
+	tokenOpenParen
+	tokenCloseParen
+	tokenOpenBracket
+	tokenCloseBracket
+	tokenOpenBrace
+	tokenCloseBrace
+	tokenOp
+)
+
+type token struct {
+	typ   tokenType
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.typ == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("'%s'", t.value)
+}
+
+var punctuation = map[byte]tokenType{
+	'.': tokenDot,
+	'|': tokenPipe,
+	',': tokenComma,
+	':': tokenColon,
+	';': tokenSemicolon,
+	'(': tokenOpenParen,
+	')': tokenCloseParen,
+	'[': tokenOpenBracket,
+	']': tokenCloseBracket,
+	'{': tokenOpenBrace,
+	'}': tokenCloseBrace,
+}
+
+// scan splits a query into tokens
+func scan(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!=") ||
+			strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			tokens = append(tokens, token{typ: tokenOp, value: s[i : i+2], pos: i})
+			i += 2
+		case c == '<' || c == '>':
+			tokens = append(tokens, token{typ: tokenOp, value: s[i : i+1], pos: i})
+			i++
+		case c == '"':
+			end, err := scanString(s, i)
+			if err != nil {
+				return nil, err
+			}
+			value, err := unquote(s[i:end], i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{typ: tokenString, value: value, pos: i})
+			i = end
+		case c == '-' || isDigit(c):
+			end := scanNumber(s, i)
+			if end == i+1 && c == '-' {
+				return nil, fmt.Errorf("unexpected '-' at offset %d", i)
+			}
+			tokens = append(tokens, token{typ: tokenNumber, value: s[i:end], pos: i})
+			i = end
+		case isIdentStart(c):
+			end := i + 1
+			for end < len(s) && isIdentPart(s[end]) {
+				end++
+			}
+			tokens = append(tokens, token{typ: tokenIdent, value: s[i:end], pos: i})
+			i = end
+		default:
+			typ, ok := punctuation[c]
+			if !ok {
+				r := []rune(s[i:])[0]
+				return nil, fmt.Errorf("unexpected character '%c' at offset %d", r, i)
+			}
+			tokens = append(tokens, token{typ: typ, value: s[i : i+1], pos: i})
+			i++
+		}
+	}
+	return append(tokens, token{typ: tokenEOF, pos: len(s)}), nil
+}
+
+func scanString(s string, start int) (int, error) {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// unquote resolves the escape sequences of a quoted string the same way they
+// are resolved in JSON input, offset is where the string starts in the query
+func unquote(quoted string, offset int) (string, error) {
+	t, err := lexer.NewLexer(strings.NewReader(quoted)).Token()
+	var syntaxErr *lexer.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return "", fmt.Errorf("%s at offset %d", syntaxErr.Msg, offset+int(syntaxErr.Pos.Offset))
+	} else if err != nil {
+		return "", err
+	}
+	return t.Value, nil
+}
+
+func scanNumber(s string, start int) int {
+	i := start
+	if s[i] == '-' {
+		i++
+	}
+	for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+		i++
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
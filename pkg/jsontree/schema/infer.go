@@ -0,0 +1,236 @@
+// Package schema infers JSON Schema from jsontree records and validates records
+// against a subset of it.
+package schema
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+// Dialect is the JSON Schema version of inferred schemas
+const Dialect = "https://json-schema.org/draft/2020-12/schema"
+
+// DefaultMaxEnum is the default limit of distinct strings that are listed as an enum
+const DefaultMaxEnum = 10
+
+type kind int
+
+// kinds in the order they are listed in the 'type' keyword
+const (
+	kindNull kind = 1 << iota
+	kindBoolean
+	kindInteger
+	kindNumber
+	kindString
+	kindObject
+	kindArray
+)
+
+var kindNames = []struct {
+	kind kind
+	name string
+}{
+	{kindObject, "object"},
+	{kindArray, "array"},
+	{kindString, "string"},
+	{kindInteger, "integer"},
+	{kindNumber, "number"},
+	{kindBoolean, "boolean"},
+	{kindNull, "null"},
+}
+
+type InferOptions struct {
+	// MaxEnum is how many distinct strings a value may have to be listed as an enum,
+	// 0 means DefaultMaxEnum and a negative value disables enums. Strings are only
+	// listed if values repeat, i.e. there are fewer distinct strings than strings.
+	MaxEnum int
+}
+
+// Inferrer merges the shapes of records into a schema that all of them match
+type Inferrer struct {
+	opts InferOptions
+	root *shape
+}
+
+func NewInferrer(opts *InferOptions) *Inferrer {
+	i := &Inferrer{root: newShape()}
+	if opts != nil {
+		i.opts = *opts
+	}
+	if i.opts.MaxEnum == 0 {
+		i.opts.MaxEnum = DefaultMaxEnum
+	}
+	return i
+}
+
+// Add merges a record into the schema
+func (i *Inferrer) Add(n ast.Node) {
+	i.root.add(n, i.opts.MaxEnum)
+}
+
+// Schema returns the JSON Schema of all records added so far. Properties are listed
+// in order of their first appearance and are required if every object had them.
+func (i *Inferrer) Schema() ast.ObjectNode {
+	s := i.root.schema()
+	props := append([]*ast.Property{{Name: "$schema", Value: ast.NewTextNode(Dialect)}}, s.Properties()...)
+	return ast.NewObjectNode(props)
+}
+
+// shape is the union of all values seen at a location
+type shape struct {
+	kinds kind
+
+	// objects is the number of objects seen, properties that were seen less often
+	// are optional
+	objects    int
+	properties []*property
+	byName     map[string]*property
+
+	items *shape
+
+	strings    int
+	values     []string
+	seen       map[string]bool
+	tooManyStr bool
+}
+
+type property struct {
+	name  string
+	count int
+	shape *shape
+}
+
+func newShape() *shape {
+	return &shape{byName: map[string]*property{}, seen: map[string]bool{}}
+}
+
+func (s *shape) add(n ast.Node, maxEnum int) {
+	if n == nil {
+		n = ast.NewNullNode()
+	}
+
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		s.kinds |= kindNull
+	case ast.NodeTypeBoolean:
+		s.kinds |= kindBoolean
+	case ast.NodeTypeNumber:
+		if isInteger(n.(ast.NumberNode).Value()) {
+			s.kinds |= kindInteger
+		} else {
+			s.kinds |= kindNumber
+		}
+	case ast.NodeTypeText:
+		s.kinds |= kindString
+		s.addString(n.(ast.TextNode).Value(), maxEnum)
+	case ast.NodeTypeObject:
+		s.kinds |= kindObject
+		s.objects++
+		for _, p := range n.(ast.ObjectNode).Properties() {
+			prop, ok := s.byName[p.Name]
+			if !ok {
+				prop = &property{name: p.Name, shape: newShape()}
+				s.byName[p.Name] = prop
+				s.properties = append(s.properties, prop)
+			}
+			prop.count++
+			prop.shape.add(p.Value, maxEnum)
+		}
+	case ast.NodeTypeArray:
+		s.kinds |= kindArray
+		for _, item := range n.(ast.ArrayNode).Items() {
+			if s.items == nil {
+				s.items = newShape()
+			}
+			s.items.add(item, maxEnum)
+		}
+	}
+}
+
+func (s *shape) addString(v string, maxEnum int) {
+	s.strings++
+	if s.tooManyStr || s.seen[v] {
+		return
+	}
+	if maxEnum < 0 || len(s.values) >= maxEnum {
+		s.tooManyStr = true
+		s.values, s.seen = nil, nil
+		return
+	}
+	s.seen[v] = true
+	s.values = append(s.values, v)
+}
+
+func (s *shape) schema() ast.ObjectNode {
+	out := ast.NewObjectNode(nil)
+
+	kinds := s.kinds
+	if kinds&kindNumber != 0 {
+		// integers are numbers too
+		kinds &^= kindInteger
+	}
+	var types []ast.Node
+	for _, k := range kindNames {
+		if kinds&k.kind != 0 {
+			types = append(types, ast.NewTextNode(k.name))
+		}
+	}
+	switch len(types) {
+	case 0:
+		// nothing seen, e.g. the items of empty arrays, anything goes
+		return out
+	case 1:
+		out.Set("type", types[0])
+	default:
+		out.Set("type", ast.NewArrayNode(types))
+	}
+
+	if s.isEnum(kinds) {
+		var values []ast.Node
+		for _, v := range s.values {
+			values = append(values, ast.NewTextNode(v))
+		}
+		if kinds&kindNull != 0 {
+			values = append(values, ast.NewNullNode())
+		}
+		out.Set("enum", ast.NewArrayNode(values))
+	}
+
+	if kinds&kindObject != 0 {
+		props := ast.NewObjectNode(nil)
+		var required []ast.Node
+		for _, p := range s.properties {
+			props.Set(p.name, p.shape.schema())
+			if p.count == s.objects {
+				required = append(required, ast.NewTextNode(p.name))
+			}
+		}
+		out.Set("properties", props)
+		if len(required) > 0 {
+			out.Set("required", ast.NewArrayNode(required))
+		}
+	}
+
+	if kinds&kindArray != 0 && s.items != nil {
+		out.Set("items", s.items.schema())
+	}
+	return out
+}
+
+// isEnum reports whether the strings are listed, which is only done for plain strings
+// with repeating values
+func (s *shape) isEnum(kinds kind) bool {
+	return kinds&^kindNull == kindString && !s.tooManyStr && len(s.values) < s.strings
+}
+
+// isInteger reports whether a number has no fractional part, e.g. 1.0 and 1e2 are
+// integers in JSON Schema
+func isInteger(v string) bool {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return err == nil && !math.IsInf(f, 0) && f == math.Trunc(f)
+}
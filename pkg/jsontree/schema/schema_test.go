@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/trichner/tb/pkg/jsontree"
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+func parseJson(t *testing.T, s string) ast.Node {
+	n, err := jsontree.Parse(lexer.NewLexer(strings.NewReader(s)))
+	assert.NoError(t, err)
+	return n
+}
+
+func infer(t *testing.T, opts *InferOptions, records ...string) string {
+	i := NewInferrer(opts)
+	for _, r := range records {
+		i.Add(parseJson(t, r))
+	}
+	b, err := i.Schema().MarshalJSON()
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestInferrer_Schema(t *testing.T) {
+	actual := infer(t, nil,
+		`{"id":1,"name":"a","status":"open","score":1.5,"tags":["x"],"address":{"city":"Zurich"}}`,
+		`{"id":2,"name":"b","status":"closed","score":2,"tags":[],"address":null}`,
+		`{"id":3,"name":"c","status":"open","score":null,"tags":[1],"extra":true}`,
+	)
+
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{`+
+		`"id":{"type":"integer"},`+
+		`"name":{"type":"string"},`+
+		`"status":{"type":"string","enum":["open","closed"]},`+
+		`"score":{"type":["number","null"]},`+
+		`"tags":{"type":"array","items":{"type":["string","integer"]}},`+
+		`"address":{"type":["object","null"],"properties":{"city":{"type":"string"}},"required":["city"]},`+
+		`"extra":{"type":"boolean"}},`+
+		`"required":["id","name","status","score","tags"]}`, actual)
+}
+
+func TestInferrer_Schema_Enums(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *InferOptions
+		records  []string
+		expected string
+	}{
+		{"nullable", nil, []string{`"a"`, `null`, `"a"`}, `{"type":["string","null"],"enum":["a",null]}`},
+		{"no repetition", nil, []string{`"a"`, `"b"`}, `{"type":"string"}`},
+		{"too many", &InferOptions{MaxEnum: 1}, []string{`"a"`, `"b"`, `"a"`}, `{"type":"string"}`},
+		{"disabled", &InferOptions{MaxEnum: -1}, []string{`"a"`, `"a"`}, `{"type":"string"}`},
+		{"mixed types", nil, []string{`"a"`, `"a"`, `1`}, `{"type":["string","integer"]}`},
+		{"integral numbers", nil, []string{`1.0`, `1e2`}, `{"type":"integer"}`},
+		{"empty array", nil, []string{`[]`}, `{"type":"array"}`},
+		{"array items", nil, []string{`[{}]`, `[]`}, `{"type":"array","items":{"type":"object","properties":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := infer(t, tt.opts, tt.records...)
+			assert.Equal(t, `{"$schema":"`+Dialect+`",`+tt.expected[1:], actual)
+		})
+	}
+}
+
+func TestInferrer_SchemaValidatesRecords(t *testing.T) {
+	records := []string{
+		`{"id":1,"tags":["x"],"nested":{"a":[1,2.5]}}`,
+		`{"id":2,"tags":[],"nested":null,"kind":"a"}`,
+		`{"id":3,"kind":"a"}`,
+	}
+	i := NewInferrer(nil)
+	for _, r := range records {
+		i.Add(parseJson(t, r))
+	}
+
+	s, err := Compile(i.Schema())
+	assert.NoError(t, err)
+	for _, r := range records {
+		assert.Empty(t, s.Validate(parseJson(t, r)), r)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := Compile(parseJson(t, `{
+	  "type": "object",
+	  "properties": {
+	    "id": {"type": "integer"},
+	    "status": {"enum": ["open", "closed"]},
+	    "version": {"const": 2},
+	    "tags": {"type": "array", "items": {"type": "string"}},
+	    "address": {"type": ["object", "null"], "properties": {"zip": {"type": "string"}}, "additionalProperties": false}
+	  },
+	  "required": ["id", "status"]
+	}`))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		record   string
+		expected []string
+	}{
+		{`{"id":1,"status":"open","version":2.0,"tags":["a"],"address":null,"other":1}`, nil},
+		{`{"id":1.0,"status":"closed","address":{"zip":"8000"}}`, nil},
+		{`{"id":1.5,"status":"done"}`, []string{
+			`/id: expected integer but got number 1.5`,
+			`/status: expected one of ["open","closed"] but got string "done"`,
+		}},
+		{`{"tags":["a",2],"version":3,"address":{"zip":8000,"city":"Zurich"}}`, []string{
+			`missing required property "id"`,
+			`missing required property "status"`,
+			`/tags/1: expected string but got integer 2`,
+			`/version: expected 2 but got integer 3`,
+			`/address/zip: expected string but got integer 8000`,
+			`/address: unexpected property "city"`,
+		}},
+		{`[]`, []string{`expected object but got array`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.record, func(t *testing.T) {
+			var actual []string
+			for _, err := range s.Validate(parseJson(t, tt.record)) {
+				actual = append(actual, err.Error())
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := []struct {
+		schema   string
+		expected string
+	}{
+		{`1`, `invalid schema: expected an object or boolean but got integer 1`},
+		{`{"type":"date"}`, `invalid schema: type: unknown type string "date"`},
+		{`{"properties":{"a":{"items":"x"}}}`, `invalid schema: properties: a: items: expected an object or boolean but got string "x"`},
+		{`{"required":[1]}`, `invalid schema: required: expected a property name but got integer 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			_, err := Compile(parseJson(t, tt.schema))
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+)
+
+// Schema is a compiled JSON Schema. Only the keywords type, enum, const, properties,
+// required, additionalProperties and items are validated, others are ignored.
+type Schema struct {
+	// never is the schema false, which no value matches
+	never bool
+
+	// kinds are the allowed types, zero if any type is allowed
+	kinds kind
+
+	enum     []ast.Node
+	constant ast.Node
+
+	properties map[string]*Schema
+	required   []string
+
+	// additional is the schema of properties not listed in properties, nil if
+	// they are not restricted
+	additional *Schema
+
+	items *Schema
+}
+
+// ValidationError is a mismatch between a value and a schema
+type ValidationError struct {
+	// Path points to the mismatching value within the record
+	Path    ast.Pointer
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	return e.Path.String() + ": " + e.Message
+}
+
+// Compile parses a JSON Schema, either an object or a boolean
+func Compile(n ast.Node) (*Schema, error) {
+	s, err := compile(n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return s, nil
+}
+
+func compile(n ast.Node) (*Schema, error) {
+	if n == nil {
+		return nil, errors.New("expected a schema but got nothing")
+	}
+	if n.Type() == ast.NodeTypeBoolean {
+		return &Schema{never: !n.(ast.BooleanNode).Value()}, nil
+	}
+	if n.Type() != ast.NodeTypeObject {
+		return nil, fmt.Errorf("expected an object or boolean but got %s", describe(n))
+	}
+
+	s := &Schema{}
+	o := n.(ast.ObjectNode)
+	for _, p := range o.Properties() {
+		var err error
+		switch p.Name {
+		case "type":
+			s.kinds, err = compileTypes(p.Value)
+		case "enum":
+			if p.Value.Type() != ast.NodeTypeArray {
+				return nil, fmt.Errorf("enum: expected an array but got %s", describe(p.Value))
+			}
+			s.enum = p.Value.(ast.ArrayNode).Items()
+		case "const":
+			s.constant = p.Value
+		case "properties":
+			s.properties, err = compileProperties(p.Value)
+		case "required":
+			s.required, err = compileRequired(p.Value)
+		case "additionalProperties":
+			s.additional, err = compile(p.Value)
+		case "items":
+			s.items, err = compile(p.Value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(n ast.Node) (kind, error) {
+	var names []ast.Node
+	switch n.Type() {
+	case ast.NodeTypeText:
+		names = []ast.Node{n}
+	case ast.NodeTypeArray:
+		names = n.(ast.ArrayNode).Items()
+	default:
+		return 0, fmt.Errorf("expected a string or array but got %s", describe(n))
+	}
+
+	var kinds kind
+	for _, name := range names {
+		k, ok := kindOfName(name)
+		if !ok {
+			return 0, fmt.Errorf("unknown type %s", describe(name))
+		}
+		kinds |= k
+	}
+	return kinds, nil
+}
+
+func kindOfName(n ast.Node) (kind, bool) {
+	if n.Type() != ast.NodeTypeText {
+		return 0, false
+	}
+	for _, k := range kindNames {
+		if k.name == n.(ast.TextNode).Value() {
+			return k.kind, true
+		}
+	}
+	return 0, false
+}
+
+func compileProperties(n ast.Node) (map[string]*Schema, error) {
+	if n.Type() != ast.NodeTypeObject {
+		return nil, fmt.Errorf("expected an object but got %s", describe(n))
+	}
+	props := map[string]*Schema{}
+	for _, p := range n.(ast.ObjectNode).Properties() {
+		s, err := compile(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+		props[p.Name] = s
+	}
+	return props, nil
+}
+
+func compileRequired(n ast.Node) ([]string, error) {
+	if n.Type() != ast.NodeTypeArray {
+		return nil, fmt.Errorf("expected an array but got %s", describe(n))
+	}
+	var names []string
+	for _, item := range n.(ast.ArrayNode).Items() {
+		if item.Type() != ast.NodeTypeText {
+			return nil, fmt.Errorf("expected a property name but got %s", describe(item))
+		}
+		names = append(names, item.(ast.TextNode).Value())
+	}
+	return names, nil
+}
+
+// Validate returns all mismatches between a value and the schema, none if it matches
+func (s *Schema) Validate(n ast.Node) []*ValidationError {
+	var errs []*ValidationError
+	s.validate(ast.Pointer{}, n, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path ast.Pointer, n ast.Node, errs *[]*ValidationError) {
+	report := func(format string, a ...any) {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if n == nil {
+		n = ast.NewNullNode()
+	}
+	if s.never {
+		report("no value is allowed but got %s", describe(n))
+		return
+	}
+
+	if s.kinds != 0 && s.kinds&kindOf(n) == 0 {
+		report("expected %s but got %s", typeNames(s.kinds), describe(n))
+		return
+	}
+	if s.enum != nil && !contains(s.enum, n) {
+		report("expected one of %s but got %s", marshal(ast.NewArrayNode(s.enum)), describe(n))
+	}
+	if s.constant != nil && !ast.Equal(s.constant, n) {
+		report("expected %s but got %s", marshal(s.constant), describe(n))
+	}
+
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		o := n.(ast.ObjectNode)
+		for _, name := range s.required {
+			if _, ok := o.Get(name); !ok {
+				report("missing required property %q", name)
+			}
+		}
+		for _, p := range o.Properties() {
+			ps, ok := s.properties[p.Name]
+			if !ok {
+				ps = s.additional
+			}
+			if ps == nil {
+				continue
+			}
+			if ps.never && !ok {
+				report("unexpected property %q", p.Name)
+				continue
+			}
+			ps.validate(path.Append(p.Name), p.Value, errs)
+		}
+	case ast.NodeTypeArray:
+		if s.items == nil {
+			return
+		}
+		for i, item := range n.(ast.ArrayNode).Items() {
+			s.items.validate(path.Append(strconv.Itoa(i)), item, errs)
+		}
+	}
+}
+
+// kindOf returns the kinds a value matches, integers are numbers too
+func kindOf(n ast.Node) kind {
+	switch n.Type() {
+	case ast.NodeTypeNull:
+		return kindNull
+	case ast.NodeTypeBoolean:
+		return kindBoolean
+	case ast.NodeTypeNumber:
+		if isInteger(n.(ast.NumberNode).Value()) {
+			return kindInteger | kindNumber
+		}
+		return kindNumber
+	case ast.NodeTypeText:
+		return kindString
+	case ast.NodeTypeObject:
+		return kindObject
+	case ast.NodeTypeArray:
+		return kindArray
+	}
+	return 0
+}
+
+func typeNames(kinds kind) string {
+	var names []string
+	for _, k := range kindNames {
+		if kinds&k.kind != 0 {
+			names = append(names, k.name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+func contains(values []ast.Node, n ast.Node) bool {
+	for _, v := range values {
+		if ast.Equal(v, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names the type of a value and shows scalars, e.g. 'string "abc"'
+func describe(n ast.Node) string {
+	switch n.Type() {
+	case ast.NodeTypeObject:
+		return "object"
+	case ast.NodeTypeArray:
+		return "array"
+	case ast.NodeTypeNull:
+		return "null"
+	}
+
+	name := "boolean"
+	switch kindOf(n) {
+	case kindString:
+		name = "string"
+	case kindNumber:
+		name = "number"
+	case kindInteger | kindNumber:
+		name = "integer"
+	}
+	return name + " " + marshal(n)
+}
+
+// marshal returns the JSON text of a value, shortened to keep messages readable
+func marshal(n ast.Node) string {
+	const maxLen = 60
+
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return n.Type().String()
+	}
+	if s := []rune(string(b)); len(s) > maxLen {
+		return string(s[:maxLen-3]) + "..."
+	}
+	return string(b)
+}
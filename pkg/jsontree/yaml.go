@@ -0,0 +1,183 @@
+package jsontree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/trichner/tb/pkg/jsontree/ast"
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+// maxAliasNodes limits how many nodes may be produced by expanding aliases in a YAML
+// document, to not blow up on documents such as the 'billion laughs'
+const maxAliasNodes = 1 << 20
+
+type yamlDecoder struct {
+	d *yaml.Decoder
+}
+
+func newYAMLDecoder(r io.Reader) *yamlDecoder {
+	return &yamlDecoder{d: yaml.NewDecoder(r)}
+}
+
+// decode reads the next document and converts it to the same tree the JSON parser
+// produces. Mappings keep the order of their keys, scalars are converted according
+// to their resolved tag, e.g. an unquoted 'true' becomes a boolean.
+func (d *yamlDecoder) decode() (ast.Node, error) {
+	var doc yaml.Node
+	if err := d.d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	c := &yamlConverter{}
+	return c.convert(&doc, 0)
+}
+
+type yamlConverter struct {
+	// aliasDepth is how many aliases are being expanded
+	aliasDepth int
+	aliasNodes int
+}
+
+func (c *yamlConverter) convert(n *yaml.Node, depth int) (ast.Node, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("exceeded maximum nesting depth of %d at line %d, column %d", MaxDepth, n.Line, n.Column)
+	}
+	if c.aliasDepth > 0 {
+		c.aliasNodes++
+		if c.aliasNodes > maxAliasNodes {
+			return nil, errors.New("document expands too many aliases")
+		}
+	}
+
+	var out ast.Node
+	var err error
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return ast.NewNullNode(), nil
+		}
+		return c.convert(n.Content[0], depth)
+	case yaml.AliasNode:
+		c.aliasDepth++
+		defer func() { c.aliasDepth-- }()
+		return c.convert(n.Alias, depth)
+	case yaml.SequenceNode:
+		items := make([]ast.Node, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, err := c.convert(item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		out = ast.NewArrayNode(items)
+	case yaml.MappingNode:
+		out, err = c.mapping(n, depth)
+	case yaml.ScalarNode:
+		out, err = scalar(n)
+	default:
+		err = fmt.Errorf("unsupported YAML node at line %d, column %d", n.Line, n.Column)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ast.SetPos(out, lexer.Position{Line: n.Line, Column: n.Column})
+	return out, nil
+}
+
+// mapping converts a mapping, keys merged with '<<' come after the mapping's own keys
+// unless the mapping has them itself
+func (c *yamlConverter) mapping(n *yaml.Node, depth int) (ast.Node, error) {
+	o := ast.NewObjectNode(nil)
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Kind == yaml.AliasNode {
+			key = key.Alias
+		}
+		if key.ShortTag() == "!!merge" {
+			merges = append(merges, value)
+			continue
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("unsupported non-scalar key at line %d, column %d", key.Line, key.Column)
+		}
+
+		v, err := c.convert(value, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		o.Set(key.Value, v)
+	}
+
+	for _, m := range merges {
+		merged, err := c.convert(m, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		var sources []ast.Node
+		switch merged.Type() {
+		case ast.NodeTypeObject:
+			sources = []ast.Node{merged}
+		case ast.NodeTypeArray:
+			sources = merged.(ast.ArrayNode).Items()
+		}
+		for _, src := range sources {
+			if src.Type() != ast.NodeTypeObject {
+				return nil, fmt.Errorf("cannot merge a %s at line %d, column %d", src.Type(), m.Line, m.Column)
+			}
+			for _, p := range src.(ast.ObjectNode).Properties() {
+				if _, ok := o.Get(p.Name); !ok {
+					o.Set(p.Name, p.Value)
+				}
+			}
+		}
+	}
+	return o, nil
+}
+
+func scalar(n *yaml.Node) (ast.Node, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return ast.NewNullNode(), nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, err
+		}
+		return ast.NewBooleanNode(b), nil
+	case "!!int", "!!float":
+		if lexer.IsValidNumber(n.Value) {
+			// keep the exact text
+			return ast.NewNumberNode(n.Value), nil
+		}
+
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case int:
+			return ast.NewNumberNode(strconv.Itoa(v)), nil
+		case int64:
+			return ast.NewNumberNode(strconv.FormatInt(v, 10)), nil
+		case uint64:
+			return ast.NewNumberNode(strconv.FormatUint(v, 10)), nil
+		case float64:
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				return nil, fmt.Errorf("%s cannot be represented in JSON at line %d, column %d", n.Value, n.Line, n.Column)
+			}
+			return ast.NewNumberNode(strconv.FormatFloat(v, 'g', -1, 64)), nil
+		}
+		return nil, fmt.Errorf("unsupported number %q at line %d, column %d", n.Value, n.Line, n.Column)
+	}
+
+	// strings as well as timestamps, binary and custom tags are kept as text
+	return ast.NewTextNode(n.Value), nil
+}
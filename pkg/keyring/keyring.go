@@ -25,7 +25,11 @@ func Open(service string) (*Ring, error) {
 
 func (r *Ring) Put(name string, item *Item) error {
 	if item == nil {
-		return zk.Delete(r.serviceName, name)
+		err := zk.Delete(r.serviceName, name)
+		if errors.Is(err, zk.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
 	}
 
 	err := zk.Set(r.serviceName, name, item.Secret)
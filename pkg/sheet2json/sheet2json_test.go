@@ -0,0 +1,29 @@
+package sheet2json
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/sheets"
+	"github.com/trichner/tb/pkg/sheets/sheetstest"
+)
+
+func TestReadFromSheet(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+
+	ss := fake.AddSpreadSheet("abc123", "existing")
+	err := ss.Sheets[0].UpdateValues([][]string{
+		{"name", "age"},
+		{"alice", "42"},
+		{"bob"},
+	})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = ReadFromSheet(ctx, "abc123", 0, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"age\":\"42\",\"name\":\"alice\"}\n{\"name\":\"bob\"}\n", buf.String())
+}
@@ -0,0 +1,373 @@
+package sheets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	googlesheets "google.golang.org/api/sheets/v4"
+)
+
+// Unbounded marks an open end of a Range, e.g. the rows of 'A:B'
+const Unbounded = -1
+
+// Range is a rectangular range of cells, the indices are zero-based and the ends are exclusive
+// just like in a googlesheets.GridRange. The range is within the sheet with the given title, an
+// empty title refers to the sheet the range is used with.
+type Range struct {
+	Sheet string
+
+	StartRow    int
+	StartColumn int
+	EndRow      int
+	EndColumn   int
+}
+
+var (
+	a1CellPattern   = regexp.MustCompile(`^([A-Za-z]*)([0-9]*)$`)
+	r1c1CellPattern = regexp.MustCompile(`^(?:[Rr]([0-9]+))?(?:[Cc]([0-9]+))?$`)
+)
+
+// WholeSheet returns the range covering all cells of a sheet
+func WholeSheet(sheet string) *Range {
+	return &Range{Sheet: sheet, EndRow: Unbounded, EndColumn: Unbounded}
+}
+
+// Cell returns the range of the single cell at the zero-based row and column
+func Cell(row, column int) *Range {
+	return &Range{StartRow: row, StartColumn: column, EndRow: row + 1, EndColumn: column + 1}
+}
+
+// ParseA1 parses ranges in A1 notation such as 'A1', 'A1:B2', 'A:A', '2:4' or 'Sheet1!B2:C'.
+// Quoted sheet titles escape apostrophes by doubling them. A plain sheet title refers to the whole sheet.
+func ParseA1(s string) (*Range, error) {
+	return parseRange(s, parseA1Cell)
+}
+
+// ParseR1C1 parses ranges in absolute R1C1 notation such as 'R1C1', 'R1C1:R2C2', 'R1:R3' or 'Sheet1!C2:C4'
+func ParseR1C1(s string) (*Range, error) {
+	return parseRange(s, parseR1C1Cell)
+}
+
+// Validate reports ranges which cannot be written in A1 notation, i.e. ends before starts and
+// ranges open in both directions which do not cover the whole sheet such as rows '3:' onwards
+func (r *Range) Validate() error {
+	if r.StartRow < 0 || r.StartColumn < 0 {
+		return fmt.Errorf("invalid range %s: negative start", r)
+	}
+	if (r.EndRow != Unbounded && r.EndRow <= r.StartRow) || (r.EndColumn != Unbounded && r.EndColumn <= r.StartColumn) {
+		return fmt.Errorf("invalid range %s: end before start", r)
+	}
+	if r.EndRow == Unbounded && r.EndColumn == Unbounded && !r.isWholeSheet() {
+		return fmt.Errorf("invalid range %s: either the end row or the end column must be bounded", r)
+	}
+	return nil
+}
+
+// Fits returns an error if that many rows and columns written at the start of the range exceed it
+func (r *Range) Fits(rows, columns int) error {
+	if r.EndRow != Unbounded && r.StartRow+rows > r.EndRow {
+		return fmt.Errorf("data with %d rows exceeds range %s", rows, r)
+	}
+	if r.EndColumn != Unbounded && r.StartColumn+columns > r.EndColumn {
+		return fmt.Errorf("data with %d columns exceeds range %s", columns, r)
+	}
+	return nil
+}
+
+// A1 formats the range in A1 notation, the sheet title is always quoted. Ranges which do not
+// Validate have no valid A1 notation.
+func (r *Range) A1() string {
+	if r.isWholeSheet() {
+		return r.quotedSheet()
+	}
+
+	cellRef := func(row, column int) string {
+		ref := ""
+		if column >= 0 {
+			ref += ColumnLetters(column)
+		}
+		if row >= 0 {
+			ref += strconv.Itoa(row + 1)
+		}
+		return ref
+	}
+	return r.format(cellRef)
+}
+
+// R1C1 formats the range in absolute R1C1 notation, the sheet title is always quoted
+func (r *Range) R1C1() string {
+	if r.isWholeSheet() {
+		return r.quotedSheet()
+	}
+
+	cellRef := func(row, column int) string {
+		ref := ""
+		if row >= 0 {
+			ref += "R" + strconv.Itoa(row+1)
+		}
+		if column >= 0 {
+			ref += "C" + strconv.Itoa(column+1)
+		}
+		return ref
+	}
+	return r.format(cellRef)
+}
+
+func (r *Range) String() string {
+	return r.A1()
+}
+
+// InSheet returns a copy of the range within the given sheet
+func (r *Range) InSheet(sheet string) *Range {
+	c := *r
+	c.Sheet = sheet
+	return &c
+}
+
+func (r *Range) format(cellRef func(row, column int) string) string {
+	// omit the start of an unbounded dimension, i.e. 'A:B' rather than 'A1:B'
+	startRow, startColumn := r.StartRow, r.StartColumn
+	if startRow == 0 && r.EndRow == Unbounded {
+		startRow = -1
+	}
+	if startColumn == 0 && r.EndColumn == Unbounded {
+		startColumn = -1
+	}
+
+	ref := cellRef(startRow, startColumn)
+	if r.EndRow != r.StartRow+1 || r.EndColumn != r.StartColumn+1 {
+		endRow, endColumn := r.EndRow-1, r.EndColumn-1
+		if r.EndRow == Unbounded {
+			endRow = -1
+		}
+		if r.EndColumn == Unbounded {
+			endColumn = -1
+		}
+		ref += ":" + cellRef(endRow, endColumn)
+	}
+
+	if r.Sheet == "" {
+		return ref
+	}
+	return r.quotedSheet() + "!" + ref
+}
+
+func (r *Range) quotedSheet() string {
+	if r.Sheet == "" {
+		return ""
+	}
+	return QuoteSheetTitle(r.Sheet)
+}
+
+func (r *Range) isWholeSheet() bool {
+	return r.StartRow == 0 && r.StartColumn == 0 && r.EndRow == Unbounded && r.EndColumn == Unbounded
+}
+
+func (r *Range) gridRange(sheetId int64) *googlesheets.GridRange {
+	g := &googlesheets.GridRange{
+		SheetId:          sheetId,
+		StartRowIndex:    int64(r.StartRow),
+		StartColumnIndex: int64(r.StartColumn),
+	}
+	if r.EndRow != Unbounded {
+		g.EndRowIndex = int64(r.EndRow)
+	}
+	if r.EndColumn != Unbounded {
+		g.EndColumnIndex = int64(r.EndColumn)
+	}
+	return g
+}
+
+// QuoteSheetTitle quotes a sheet title for use in a range, apostrophes are escaped by doubling them
+func QuoteSheetTitle(title string) string {
+	return "'" + strings.ReplaceAll(title, "'", "''") + "'"
+}
+
+// ColumnIndex converts column letters to a zero-based index, e.g. 'A' to 0 and 'AA' to 26
+func ColumnIndex(letters string) (int, error) {
+	if letters == "" {
+		return -1, fmt.Errorf("empty column")
+	}
+
+	index := 0
+	for _, c := range strings.ToUpper(letters) {
+		if c < 'A' || c > 'Z' {
+			return -1, fmt.Errorf("invalid column %q", letters)
+		}
+		if index > (math.MaxInt-26)/26 {
+			return -1, fmt.Errorf("column %q out of range", letters)
+		}
+		index = index*26 + int(c-'A'+1)
+	}
+	return index - 1, nil
+}
+
+// ColumnLetters converts a zero-based column index to letters, e.g. 0 to 'A' and 26 to 'AA'
+func ColumnLetters(index int) string {
+	var letters []byte
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		letters = append([]byte{byte('A' + (n-1)%26)}, letters...)
+	}
+	return string(letters)
+}
+
+// cellParser returns the zero-based row and column of a cell reference, -1 if either is omitted
+type cellParser func(s string) (row int, column int, err error)
+
+func parseRange(s string, parseCell cellParser) (*Range, error) {
+	sheet, ref, hasSheet, err := splitSheetTitle(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" {
+		if sheet == "" {
+			return nil, fmt.Errorf("empty range")
+		}
+		return WholeSheet(sheet), nil
+	}
+
+	parts := strings.Split(ref, ":")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("invalid range %q", s)
+	}
+
+	startRow, startColumn, err := parseCell(parts[0])
+	if len(parts) == 1 {
+		isCell := err == nil && startRow >= 0 && startColumn >= 0
+		if !isCell && !hasSheet {
+			// not a cell, e.g. 'Data' is the title of a sheet
+			return WholeSheet(ref), nil
+		}
+		if !isCell {
+			return nil, fmt.Errorf("invalid range %q: single cell requires a row and column", s)
+		}
+		r := Cell(startRow, startColumn)
+		r.Sheet = sheet
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+
+	endRow, endColumn, err := parseCell(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+
+	r := &Range{
+		Sheet:       sheet,
+		StartRow:    max(startRow, 0),
+		StartColumn: max(startColumn, 0),
+		EndRow:      Unbounded,
+		EndColumn:   Unbounded,
+	}
+	if endRow >= 0 {
+		r.EndRow = endRow + 1
+	}
+	if endColumn >= 0 {
+		r.EndColumn = endColumn + 1
+	}
+
+	if (r.EndRow != Unbounded && r.EndRow <= r.StartRow) || (r.EndColumn != Unbounded && r.EndColumn <= r.StartColumn) {
+		return nil, fmt.Errorf("invalid range %q: end before start", s)
+	}
+	return r, nil
+}
+
+// splitSheetTitle splits 'Sheet1!A1:B2' into the unquoted sheet title and the cell references
+func splitSheetTitle(s string) (string, string, bool, error) {
+	if !strings.HasPrefix(s, "'") {
+		idx := strings.LastIndex(s, "!")
+		if idx < 0 {
+			return "", s, false, nil
+		}
+		return s[:idx], s[idx+1:], true, nil
+	}
+
+	var title strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != '\'' {
+			title.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\'' {
+			// escaped apostrophe
+			title.WriteByte('\'')
+			i++
+			continue
+		}
+
+		rest := s[i+1:]
+		if rest == "" {
+			return title.String(), "", true, nil
+		}
+		if rest[0] != '!' {
+			return "", "", false, fmt.Errorf("invalid range %q: expected '!' after sheet title", s)
+		}
+		return title.String(), rest[1:], true, nil
+	}
+	return "", "", false, fmt.Errorf("invalid range %q: unterminated sheet title", s)
+}
+
+func parseA1Cell(s string) (int, int, error) {
+	matches := a1CellPattern.FindStringSubmatch(s)
+	if matches == nil || s == "" {
+		return -1, -1, fmt.Errorf("invalid cell %q", s)
+	}
+
+	row, column := -1, -1
+	if matches[1] != "" {
+		c, err := ColumnIndex(matches[1])
+		if err != nil {
+			return -1, -1, err
+		}
+		column = c
+	}
+	if matches[2] != "" {
+		r, err := parseOneBased(matches[2])
+		if err != nil {
+			return -1, -1, fmt.Errorf("invalid cell %q: %w", s, err)
+		}
+		row = r
+	}
+	return row, column, nil
+}
+
+func parseR1C1Cell(s string) (int, int, error) {
+	matches := r1c1CellPattern.FindStringSubmatch(s)
+	if matches == nil || s == "" {
+		return -1, -1, fmt.Errorf("invalid cell %q", s)
+	}
+
+	row, column := -1, -1
+	if matches[1] != "" {
+		r, err := parseOneBased(matches[1])
+		if err != nil {
+			return -1, -1, fmt.Errorf("invalid cell %q: %w", s, err)
+		}
+		row = r
+	}
+	if matches[2] != "" {
+		c, err := parseOneBased(matches[2])
+		if err != nil {
+			return -1, -1, fmt.Errorf("invalid cell %q: %w", s, err)
+		}
+		column = c
+	}
+	return row, column, nil
+}
+
+func parseOneBased(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1, err
+	}
+	if n < 1 {
+		return -1, fmt.Errorf("index must be at least 1 but was %d", n)
+	}
+	return n - 1, nil
+}
@@ -0,0 +1,151 @@
+package sheets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		letters string
+		index   int
+	}{
+		{"A", 0},
+		{"Z", 25},
+		{"AA", 26},
+		{"AZ", 51},
+		{"BA", 52},
+		{"ZZ", 701},
+		{"AAA", 702},
+		{"xfd", 16383},
+	}
+	for _, test := range tests {
+		t.Run(test.letters, func(t *testing.T) {
+			idx, err := ColumnIndex(test.letters)
+			assert.NoError(t, err)
+			assert.Equal(t, test.index, idx)
+		})
+	}
+}
+
+func TestColumnIndex_Invalid(t *testing.T) {
+	for _, letters := range []string{"", "A1", "Ä", strings.Repeat("Z", 14), strings.Repeat("A", 100)} {
+		t.Run(letters, func(t *testing.T) {
+			_, err := ColumnIndex(letters)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestColumnLetters_RoundTrip(t *testing.T) {
+	for i := 0; i < 20000; i++ {
+		idx, err := ColumnIndex(ColumnLetters(i))
+		assert.NoError(t, err)
+		assert.Equal(t, i, idx)
+	}
+}
+
+func TestParseA1(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected Range
+		a1       string
+	}{
+		{raw: "A1", expected: Range{EndRow: 1, EndColumn: 1}, a1: "A1"},
+		{raw: "b2:C3", expected: Range{StartRow: 1, StartColumn: 1, EndRow: 3, EndColumn: 3}, a1: "B2:C3"},
+		{raw: "A:A", expected: Range{EndRow: Unbounded, EndColumn: 1}, a1: "A:A"},
+		{raw: "A5:B", expected: Range{StartRow: 4, EndRow: Unbounded, EndColumn: 2}, a1: "A5:B"},
+		{raw: "2:4", expected: Range{StartRow: 1, EndRow: 4, EndColumn: Unbounded}, a1: "2:4"},
+		{raw: "Sheet1!A1:B2", expected: Range{Sheet: "Sheet1", EndRow: 2, EndColumn: 2}, a1: "'Sheet1'!A1:B2"},
+		{raw: "'It''s here'!A:A", expected: Range{Sheet: "It's here", EndRow: Unbounded, EndColumn: 1}, a1: "'It''s here'!A:A"},
+		{raw: "'a!b'!C3", expected: Range{Sheet: "a!b", StartRow: 2, StartColumn: 2, EndRow: 3, EndColumn: 3}, a1: "'a!b'!C3"},
+		{raw: "Data", expected: Range{Sheet: "Data", EndRow: Unbounded, EndColumn: Unbounded}, a1: "'Data'"},
+		{raw: "My Sheet", expected: Range{Sheet: "My Sheet", EndRow: Unbounded, EndColumn: Unbounded}, a1: "'My Sheet'"},
+		{raw: "'My Sheet'", expected: Range{Sheet: "My Sheet", EndRow: Unbounded, EndColumn: Unbounded}, a1: "'My Sheet'"},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			r, err := ParseA1(test.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, *r)
+			assert.Equal(t, test.a1, r.A1())
+		})
+	}
+}
+
+func TestParseA1_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"A1:",
+		"B2:A1",
+		"Sheet1!A0",
+		"A1:B2:C3",
+		"'unterminated!A1",
+		"'Sheet'A1",
+		"Sheet1!A",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			_, err := ParseA1(test)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseR1C1(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected Range
+		r1c1     string
+	}{
+		{raw: "R1C1", expected: Range{EndRow: 1, EndColumn: 1}, r1c1: "R1C1"},
+		{raw: "R2C2:R3C4", expected: Range{StartRow: 1, StartColumn: 1, EndRow: 3, EndColumn: 4}, r1c1: "R2C2:R3C4"},
+		{raw: "R1:R3", expected: Range{EndRow: 3, EndColumn: Unbounded}, r1c1: "R1:R3"},
+		{raw: "'x'!C2:C3", expected: Range{Sheet: "x", StartColumn: 1, EndRow: Unbounded, EndColumn: 3}, r1c1: "'x'!C2:C3"},
+	}
+	for _, test := range tests {
+		t.Run(test.raw, func(t *testing.T) {
+			r, err := ParseR1C1(test.raw)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, *r)
+			assert.Equal(t, test.r1c1, r.R1C1())
+		})
+	}
+}
+
+func TestRange_A1_RoundTrip(t *testing.T) {
+	r := &Range{Sheet: "Report 'Q1'", StartRow: 9, StartColumn: 27, EndRow: 20, EndColumn: 30}
+	assert.Equal(t, "'Report ''Q1'''!AB10:AD20", r.A1())
+
+	parsed, err := ParseA1(r.A1())
+	assert.NoError(t, err)
+	assert.Equal(t, r, parsed)
+}
+
+func TestRange_Validate(t *testing.T) {
+	tests := []struct {
+		r     Range
+		valid bool
+	}{
+		{r: Range{EndRow: Unbounded, EndColumn: Unbounded}, valid: true},
+		{r: Range{StartRow: 2, EndRow: Unbounded, EndColumn: 3}, valid: true},
+		{r: Range{StartColumn: 2, EndRow: 4, EndColumn: Unbounded}, valid: true},
+		{r: Range{StartRow: 2, EndRow: Unbounded, EndColumn: Unbounded}},
+		{r: Range{StartColumn: 2, EndRow: Unbounded, EndColumn: Unbounded}},
+		{r: Range{StartRow: 2, EndRow: 2, EndColumn: 1}},
+		{r: Range{StartColumn: 3, EndRow: 1, EndColumn: 1}},
+		{r: Range{StartRow: -1, EndRow: 1, EndColumn: 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.r.A1(), func(t *testing.T) {
+			err := test.r.Validate()
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
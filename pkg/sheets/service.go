@@ -3,25 +3,15 @@ package sheets
 import (
 	"context"
 	"fmt"
-	"log/slog"
-	"net/http"
-	"strings"
 
-	"github.com/trichner/tb/pkg/oauth2tokenstore"
+	"github.com/trichner/tb/pkg/googleauth"
 
-	"golang.org/x/oauth2"
-
-	"github.com/trichner/oauthflows"
-	"golang.org/x/oauth2/google"
 	. "google.golang.org/api/option"
 	googlesheets "google.golang.org/api/sheets/v4"
 )
 
-const (
-	keyringItemServiceName = "toolbox googleapis.com"
-)
-
-var scopes = []string{
+// Scopes are requested for all Google APIs used by the toolbox
+var Scopes = []string{
 	"https://www.googleapis.com/auth/drive",
 	"https://www.googleapis.com/auth/drive.file",
 	"https://www.googleapis.com/auth/drive.readonly",
@@ -49,23 +39,23 @@ type Sheet struct {
 	Index int64
 }
 
+type contextKey struct{}
+
+// WithService returns a context for which NewSheetService returns svc rather than connecting to
+// Google, e.g. the in-memory sheetstest.Service
+func WithService(ctx context.Context, svc SheetsService) context.Context {
+	return context.WithValue(ctx, contextKey{}, svc)
+}
+
+// NewSheetService returns the service set by WithService, otherwise it authenticates as
+// configured by googleauth.FromContext
 func NewSheetService(ctx context.Context) (SheetsService, error) {
-	var err error
-	var client *http.Client
-
-	oauthConfig := &oauth2.Config{
-		ClientID:     deobfuscate("11f54bda98e094e03def692dffdfa93fb0773efcf9258ea5f28c4a3e9825a102d2c0628a3a7205c43f719c757ae2dfcbae35eddd2e5c3f276c83ed10259203240be3afa1ce519e"),
-		ClientSecret: deobfuscate("74a04fb1f8fbc9b443ea2345e2fbad3198607ed6f22cdad9"),
-		Endpoint:     google.Endpoint,
-		Scopes:       scopes,
+	if svc, ok := ctx.Value(contextKey{}).(SheetsService); ok && svc != nil {
+		return svc, nil
 	}
 
-	if client, err = google.DefaultClient(ctx, scopes...); err == nil {
-		// client already set
-		slog.Warn("using default client, use 'gcloud auth application-default revoke' to setup new credentials or make sure to use the correct scopes", "scopes", strings.Join(scopes, ", "))
-	} else if client, err = oauthflows.NewClient(oauthflows.WithConfig(oauthConfig), oauthflows.WithTokenStore(oauth2tokenstore.NewKeyringTokenStore(keyringItemServiceName))); err == nil {
-		// client already set
-	} else {
+	client, err := googleauth.NewClient(ctx, googleauth.FromContext(ctx), Scopes...)
+	if err != nil {
 		return nil, fmt.Errorf("cannot initialize oauth client: %w", err)
 	}
 
@@ -78,7 +68,7 @@ func NewSheetService(ctx context.Context) (SheetsService, error) {
 }
 
 func (s *sheetsService) GetSpreadSheet(id string) (SpreadsheetOps, error) {
-	res, err := s.service.Spreadsheets.Get(id).Do()
+	res, err := fetchSpreadsheet(s.service, id)
 	if err != nil {
 		return nil, err
 	}
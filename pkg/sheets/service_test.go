@@ -0,0 +1,15 @@
+package sheets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSheetService_WithService(t *testing.T) {
+	svc := &sheetsService{}
+	actual, err := NewSheetService(WithService(context.Background(), svc))
+	assert.NoError(t, err)
+	assert.Same(t, svc, actual)
+}
@@ -13,6 +13,34 @@ type SheetOps interface {
 	AppendValues(data [][]string) error
 	Values() ([][]any, error)
 	Get() (*Sheet, error)
+
+	// ReadRange returns the values within the range, trailing empty rows and cells are omitted
+	ReadRange(r *Range) ([][]any, error)
+	// WriteRange writes the data starting at the top left corner of the range
+	WriteRange(r *Range, data [][]string) error
+	// ClearRange clears the values within the range but keeps the formatting
+	ClearRange(r *Range) error
+
+	// Clear clears all values of the sheet but keeps the formatting
+	Clear() error
+	// DeleteRows deletes all rows the predicate matches and returns how many were deleted
+	DeleteRows(predicate func(row []any) bool) (int, error)
+	// Duplicate copies the sheet including its values and formatting into a new sheet
+	Duplicate(title string) (SheetOps, error)
+	Rename(title string) error
+	Delete() error
+	// Protect restricts editing the range to the given editors
+	Protect(r *Range, opts *ProtectOptions) error
+}
+
+type ProtectOptions struct {
+	Description string
+
+	// WarningOnly shows a warning when editing rather than preventing it
+	WarningOnly bool
+
+	// Editors are the emails of the users allowed to edit, the owner can always edit
+	Editors []string
 }
 
 type sheetOps struct {
@@ -33,7 +61,7 @@ func (s *sheetOps) Get() (*Sheet, error) {
 func (s *sheetOps) UpdateValues(data [][]string) error {
 	values := toValues(data)
 
-	if err := s.grow(values); err != nil {
+	if err := s.grow(len(values), len(values[0])); err != nil {
 		return err
 	}
 
@@ -73,7 +101,8 @@ func (s *sheetOps) UpdateValues(data [][]string) error {
 	return nil
 }
 
-func (s *sheetOps) grow(values [][]any) error {
+// grow appends rows and columns until the grid has at least the given size
+func (s *sheetOps) grow(rows, columns int) error {
 	sheet, err := s.filteredSheets(func(p *googlesheets.SheetProperties) bool {
 		return p.SheetId == s.sheetId
 	})
@@ -84,7 +113,7 @@ func (s *sheetOps) grow(values [][]any) error {
 	curRows := sheet.GridProperties.RowCount
 
 	var appendDimensions []*googlesheets.AppendDimensionRequest
-	missingColumns := max(len(values[0])-int(curColumns), 0)
+	missingColumns := max(columns-int(curColumns), 0)
 	if missingColumns > 0 {
 		appendDimensions = append(appendDimensions, &googlesheets.AppendDimensionRequest{
 			Dimension: "COLUMNS",
@@ -92,7 +121,7 @@ func (s *sheetOps) grow(values [][]any) error {
 			SheetId:   s.sheetId,
 		})
 	}
-	missingRows := max(len(values)-int(curRows), 0)
+	missingRows := max(rows-int(curRows), 0)
 	if missingRows > 0 {
 		appendDimensions = append(appendDimensions, &googlesheets.AppendDimensionRequest{
 			Dimension: "ROWS",
@@ -109,10 +138,7 @@ func (s *sheetOps) grow(values [][]any) error {
 	for _, r := range appendDimensions {
 		requests = append(requests, &googlesheets.Request{AppendDimension: r})
 	}
-	req := &googlesheets.BatchUpdateSpreadsheetRequest{
-		Requests: requests,
-	}
-	_, err = s.service.Spreadsheets.BatchUpdate(s.spreadsheetId(), req).Do()
+	_, err = s.batchUpdate(requests...)
 	if err != nil {
 		return fmt.Errorf("failed to expand data range to fit data: %w", err)
 	}
@@ -125,7 +151,7 @@ func (s *sheetOps) AppendValues(data [][]string) error {
 		return fmt.Errorf("unable to append data, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
 	}
 
-	insertRange := fmt.Sprintf("'%s'!A:A", sheet.Title)
+	insertRange := (&Range{Sheet: sheet.Title, EndRow: Unbounded, EndColumn: 1}).A1()
 	values := toValues(data)
 	valueRange := &googlesheets.ValueRange{
 		MajorDimension: "ROWS",
@@ -140,6 +166,9 @@ func (s *sheetOps) AppendValues(data [][]string) error {
 		return fmt.Errorf("unable to append data, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
 	}
 
+	// inserting rows grows the grid
+	s.invalidate()
+
 	return nil
 }
 
@@ -170,6 +199,183 @@ func (s *sheetOps) Values() ([][]any, error) {
 	return values, nil
 }
 
+func (s *sheetOps) ReadRange(r *Range) ([][]any, error) {
+	a1, err := s.resolveRange(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Spreadsheets.Values.Get(s.spreadsheetId(), a1).MajorDimension("ROWS").Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read range %s from spreadsheet '%s': %w", a1, s.spreadsheetId(), err)
+	}
+	return res.Values, nil
+}
+
+func (s *sheetOps) WriteRange(r *Range, data [][]string) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	a1, err := s.resolveRange(r)
+	if err != nil {
+		return err
+	}
+	if err := r.Fits(len(data), width(data)); err != nil {
+		return err
+	}
+
+	values := toValues(data)
+	if err := s.grow(r.StartRow+len(values), r.StartColumn+width(data)); err != nil {
+		return err
+	}
+
+	valueRange := &googlesheets.ValueRange{
+		MajorDimension: "ROWS",
+		Values:         values,
+	}
+	_, err = s.service.Spreadsheets.Values.Update(s.spreadsheetId(), a1, valueRange).
+		ValueInputOption("RAW").
+		Do()
+	if err != nil {
+		return fmt.Errorf("unable to write range %s to spreadsheet '%s': %w", a1, s.spreadsheetId(), err)
+	}
+	return nil
+}
+
+func (s *sheetOps) ClearRange(r *Range) error {
+	a1, err := s.resolveRange(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.service.Spreadsheets.Values.Clear(s.spreadsheetId(), a1, &googlesheets.ClearValuesRequest{}).Do()
+	if err != nil {
+		return fmt.Errorf("unable to clear range %s in spreadsheet '%s': %w", a1, s.spreadsheetId(), err)
+	}
+	return nil
+}
+
+func (s *sheetOps) Clear() error {
+	return s.ClearRange(WholeSheet(""))
+}
+
+func (s *sheetOps) DeleteRows(predicate func(row []any) bool) (int, error) {
+	values, err := s.ReadRange(WholeSheet(""))
+	if err != nil {
+		return 0, err
+	}
+
+	// delete from the bottom up, this keeps the indices of the remaining rows stable
+	var requests []*googlesheets.Request
+	for i := len(values) - 1; i >= 0; i-- {
+		if !predicate(values[i]) {
+			continue
+		}
+		requests = append(requests, &googlesheets.Request{DeleteDimension: &googlesheets.DeleteDimensionRequest{
+			Range: &googlesheets.DimensionRange{
+				Dimension:  "ROWS",
+				SheetId:    s.sheetId,
+				StartIndex: int64(i),
+				EndIndex:   int64(i + 1),
+			},
+		}})
+	}
+
+	if len(requests) == 0 {
+		return 0, nil
+	}
+
+	_, err = s.batchUpdate(requests...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete rows, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	return len(requests), nil
+}
+
+func (s *sheetOps) Duplicate(title string) (SheetOps, error) {
+	res, err := s.batchUpdate(&googlesheets.Request{DuplicateSheet: &googlesheets.DuplicateSheetRequest{
+		SourceSheetId: s.sheetId,
+		NewSheetName:  title,
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("unable to duplicate sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+
+	props := res.Replies[0].DuplicateSheet.Properties
+	return s.toSheetOps(props), nil
+}
+
+func (s *sheetOps) Rename(title string) error {
+	_, err := s.batchUpdate(&googlesheets.Request{UpdateSheetProperties: &googlesheets.UpdateSheetPropertiesRequest{
+		Properties: &googlesheets.SheetProperties{
+			SheetId: s.sheetId,
+			Title:   title,
+		},
+		Fields: "title",
+	}})
+	if err != nil {
+		return fmt.Errorf("unable to rename sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	return nil
+}
+
+func (s *sheetOps) Delete() error {
+	_, err := s.batchUpdate(&googlesheets.Request{DeleteSheet: &googlesheets.DeleteSheetRequest{
+		SheetId: s.sheetId,
+	}})
+	if err != nil {
+		return fmt.Errorf("unable to delete sheet, spreadsheet='%s' sheetId='%d': %w", s.spreadsheetId(), s.sheetId, err)
+	}
+	return nil
+}
+
+func (s *sheetOps) Protect(r *Range, opts *ProtectOptions) error {
+	if _, err := s.resolveRange(r); err != nil {
+		return err
+	}
+
+	protected := &googlesheets.ProtectedRange{
+		Range:       r.gridRange(s.sheetId),
+		Description: opts.Description,
+		WarningOnly: opts.WarningOnly,
+	}
+	if !opts.WarningOnly {
+		protected.Editors = &googlesheets.Editors{Users: opts.Editors}
+	}
+
+	_, err := s.batchUpdate(&googlesheets.Request{AddProtectedRange: &googlesheets.AddProtectedRangeRequest{
+		ProtectedRange: protected,
+	}})
+	if err != nil {
+		return fmt.Errorf("unable to protect range %s, spreadsheet='%s' sheetId='%d': %w", r, s.spreadsheetId(), s.sheetId, err)
+	}
+	return nil
+}
+
+// resolveRange formats the range in A1 notation within this sheet
+func (s *sheetOps) resolveRange(r *Range) (string, error) {
+	sheet, err := s.Get()
+	if err != nil {
+		return "", err
+	}
+	if r.Sheet != "" && r.Sheet != sheet.Title {
+		return "", fmt.Errorf("range %s is not within sheet %q", r, sheet.Title)
+	}
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	return r.InSheet(sheet.Title).A1(), nil
+}
+
+func width(data [][]string) int {
+	w := 0
+	for _, row := range data {
+		w = max(w, len(row))
+	}
+	return w
+}
+
 func toValues(data [][]string) [][]interface{} {
 	values := make([][]interface{}, len(data))
 	for i, row := range data {
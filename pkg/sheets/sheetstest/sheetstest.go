@@ -0,0 +1,411 @@
+// Package sheetstest provides an in-memory implementation of sheets.SheetsService to test code
+// talking to Google Sheets without hitting the real APIs. sheets.WithService makes
+// sheets.NewSheetService return it.
+package sheetstest
+
+import (
+	"fmt"
+
+	"github.com/trichner/tb/pkg/sheets"
+)
+
+const (
+	// defaults of a freshly created Google spreadsheet
+	defaultSheetTitle  = "Sheet1"
+	defaultRowCount    = 1000
+	defaultColumnCount = 26
+)
+
+var (
+	_ sheets.SheetsService  = (*Service)(nil)
+	_ sheets.SpreadsheetOps = (*Spreadsheet)(nil)
+	_ sheets.SheetOps       = (*Sheet)(nil)
+)
+
+// Service is an in-memory sheets.SheetsService
+type Service struct {
+	spreadsheets map[string]*Spreadsheet
+	created      int
+}
+
+func NewService() *Service {
+	return &Service{spreadsheets: make(map[string]*Spreadsheet)}
+}
+
+func (s *Service) CreateSpreadSheet(title string) (sheets.SpreadsheetOps, error) {
+	s.created++
+	id := fmt.Sprintf("spreadsheet-%d", s.created)
+	return s.AddSpreadSheet(id, title), nil
+}
+
+func (s *Service) GetSpreadSheet(id string) (sheets.SpreadsheetOps, error) {
+	ss, ok := s.spreadsheets[id]
+	if !ok {
+		return nil, fmt.Errorf("spreadsheet %q: %w", id, sheets.ErrNotFound)
+	}
+	return ss, nil
+}
+
+// AddSpreadSheet adds a new spreadsheet with the given id and a single default sheet
+func (s *Service) AddSpreadSheet(id, title string) *Spreadsheet {
+	ss := &Spreadsheet{Id: id, Title: title}
+	ss.addSheet(defaultSheetTitle, 0)
+	s.spreadsheets[id] = ss
+	return ss
+}
+
+// Spreadsheet returns the spreadsheet with the given id or nil if there is none
+func (s *Service) Spreadsheet(id string) *Spreadsheet {
+	return s.spreadsheets[id]
+}
+
+// Spreadsheet is an in-memory sheets.SpreadsheetOps
+type Spreadsheet struct {
+	Id     string
+	Title  string
+	Sheets []*Sheet
+
+	nextSheetId int64
+}
+
+func (s *Spreadsheet) CreateSheet(opts *sheets.CreateSheetOptions) (sheets.SheetOps, error) {
+	for _, sheet := range s.Sheets {
+		if sheet.Title == opts.Title {
+			return nil, fmt.Errorf("sheet %q already exists in %q", opts.Title, s.Id)
+		}
+	}
+	return s.addSheet(opts.Title, 0), nil
+}
+
+func (s *Spreadsheet) FirstSheet() (sheets.SheetOps, error) {
+	return s.SheetByIndex(0)
+}
+
+func (s *Spreadsheet) SheetByIndex(index int64) (sheets.SheetOps, error) {
+	return s.find(func(sheet *Sheet) bool {
+		return sheet.Index == index
+	})
+}
+
+func (s *Spreadsheet) SheetById(id int64) (sheets.SheetOps, error) {
+	return s.find(func(sheet *Sheet) bool {
+		return sheet.Id == id
+	})
+}
+
+func (s *Spreadsheet) SheetByTitle(title string) (sheets.SheetOps, error) {
+	return s.find(func(sheet *Sheet) bool {
+		return sheet.Title == title
+	})
+}
+
+func (s *Spreadsheet) Get() (*sheets.SpreadSheet, error) {
+	sts := make([]*sheets.Sheet, 0, len(s.Sheets))
+	for _, sheet := range s.Sheets {
+		sts = append(sts, sheet.toSheet())
+	}
+	return &sheets.SpreadSheet{Id: s.Id, Sheets: sts}, nil
+}
+
+func (s *Spreadsheet) find(predicate func(sheet *Sheet) bool) (sheets.SheetOps, error) {
+	for _, sheet := range s.Sheets {
+		if predicate(sheet) {
+			return sheet, nil
+		}
+	}
+	return nil, sheets.ErrNotFound
+}
+
+func (s *Spreadsheet) addSheet(title string, index int) *Sheet {
+	sheet := &Sheet{
+		Id:          s.nextSheetId,
+		Title:       title,
+		RowCount:    defaultRowCount,
+		ColumnCount: defaultColumnCount,
+		spreadsheet: s,
+	}
+	s.nextSheetId++
+
+	s.Sheets = append(s.Sheets, nil)
+	copy(s.Sheets[index+1:], s.Sheets[index:])
+	s.Sheets[index] = sheet
+	s.reindex()
+
+	return sheet
+}
+
+func (s *Spreadsheet) reindex() {
+	for i, sheet := range s.Sheets {
+		sheet.Index = int64(i)
+	}
+}
+
+// Sheet is an in-memory sheets.SheetOps, the cells are stored row by row and are only as large as
+// the written data while RowCount and ColumnCount track the grid size as seen by the API.
+type Sheet struct {
+	Id    int64
+	Title string
+	Index int64
+
+	RowCount    int
+	ColumnCount int
+	Cells       [][]string
+
+	Protections []*Protection
+
+	spreadsheet *Spreadsheet
+}
+
+type Protection struct {
+	Range   *sheets.Range
+	Options sheets.ProtectOptions
+}
+
+func (s *Sheet) Get() (*sheets.Sheet, error) {
+	return s.toSheet(), nil
+}
+
+// UpdateValues writes the data starting at A1, growing the grid if necessary
+func (s *Sheet) UpdateValues(data [][]string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	s.grow(len(data), width(data))
+	s.write(0, 0, data)
+	return nil
+}
+
+// AppendValues inserts the data as new rows after the last row containing any value
+func (s *Sheet) AppendValues(data [][]string) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	// just like the API with 'INSERT_ROWS', new rows are inserted rather than overwritten
+	s.RowCount += len(data)
+	s.grow(0, width(data))
+	s.write(s.lastRow()+1, 0, data)
+	return nil
+}
+
+// Values returns the values of the sheet, trailing empty rows and cells are omitted just like by the API
+func (s *Sheet) Values() ([][]any, error) {
+	last := s.lastRow()
+	if last < 0 {
+		return nil, fmt.Errorf("empty spreadsheet, no values found")
+	}
+
+	values := make([][]any, last+1)
+	for i, row := range s.Cells[:last+1] {
+		row = trimRow(row)
+		values[i] = make([]any, len(row))
+		for j, cell := range row {
+			values[i][j] = cell
+		}
+	}
+	return values, nil
+}
+
+func (s *Sheet) ReadRange(r *sheets.Range) ([][]any, error) {
+	if err := s.checkRange(r); err != nil {
+		return nil, err
+	}
+
+	endRow, endColumn := s.bounds(r)
+	var rows [][]string
+	for i := r.StartRow; i < endRow && i < len(s.Cells); i++ {
+		row := s.Cells[i]
+		row = row[min(r.StartColumn, len(row)):min(endColumn, len(row))]
+		rows = append(rows, trimRow(row))
+	}
+
+	// the API omits trailing empty rows and cells
+	for len(rows) > 0 && len(rows[len(rows)-1]) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+
+	values := make([][]any, len(rows))
+	for i, row := range rows {
+		values[i] = make([]any, len(row))
+		for j, cell := range row {
+			values[i][j] = cell
+		}
+	}
+	return values, nil
+}
+
+func (s *Sheet) WriteRange(r *sheets.Range, data [][]string) error {
+	if err := s.checkRange(r); err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := r.Fits(len(data), width(data)); err != nil {
+		return err
+	}
+
+	s.grow(r.StartRow+len(data), r.StartColumn+width(data))
+	s.write(r.StartRow, r.StartColumn, data)
+	return nil
+}
+
+func (s *Sheet) ClearRange(r *sheets.Range) error {
+	if err := s.checkRange(r); err != nil {
+		return err
+	}
+
+	endRow, endColumn := s.bounds(r)
+	for i := r.StartRow; i < endRow && i < len(s.Cells); i++ {
+		for j := r.StartColumn; j < endColumn && j < len(s.Cells[i]); j++ {
+			s.Cells[i][j] = ""
+		}
+	}
+	return nil
+}
+
+func (s *Sheet) Clear() error {
+	s.Cells = nil
+	return nil
+}
+
+func (s *Sheet) DeleteRows(predicate func(row []any) bool) (int, error) {
+	values, err := s.ReadRange(sheets.WholeSheet(""))
+	if err != nil {
+		return 0, err
+	}
+
+	var kept [][]string
+	deleted := 0
+	for i, row := range s.Cells {
+		if i < len(values) && predicate(values[i]) {
+			deleted++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	s.Cells = kept
+	s.RowCount -= deleted
+	return deleted, nil
+}
+
+func (s *Sheet) Duplicate(title string) (sheets.SheetOps, error) {
+	if _, err := s.spreadsheet.SheetByTitle(title); err == nil {
+		return nil, fmt.Errorf("sheet %q already exists in %q", title, s.spreadsheet.Id)
+	}
+
+	// the API inserts the copy right after the original
+	dup := s.spreadsheet.addSheet(title, int(s.Index)+1)
+	dup.RowCount = s.RowCount
+	dup.ColumnCount = s.ColumnCount
+	for _, row := range s.Cells {
+		dup.Cells = append(dup.Cells, append([]string(nil), row...))
+	}
+	return dup, nil
+}
+
+func (s *Sheet) Rename(title string) error {
+	if other, err := s.spreadsheet.SheetByTitle(title); err == nil && other != sheets.SheetOps(s) {
+		return fmt.Errorf("sheet %q already exists in %q", title, s.spreadsheet.Id)
+	}
+	s.Title = title
+	return nil
+}
+
+func (s *Sheet) Delete() error {
+	if len(s.spreadsheet.Sheets) == 1 {
+		return fmt.Errorf("cannot delete the only sheet of %q", s.spreadsheet.Id)
+	}
+
+	for i, sheet := range s.spreadsheet.Sheets {
+		if sheet == s {
+			s.spreadsheet.Sheets = append(s.spreadsheet.Sheets[:i], s.spreadsheet.Sheets[i+1:]...)
+			break
+		}
+	}
+	s.spreadsheet.reindex()
+	return nil
+}
+
+func (s *Sheet) Protect(r *sheets.Range, opts *sheets.ProtectOptions) error {
+	if err := s.checkRange(r); err != nil {
+		return err
+	}
+	s.Protections = append(s.Protections, &Protection{Range: r.InSheet(s.Title), Options: *opts})
+	return nil
+}
+
+func (s *Sheet) checkRange(r *sheets.Range) error {
+	if r.Sheet != "" && r.Sheet != s.Title {
+		return fmt.Errorf("range %s is not within sheet %q", r, s.Title)
+	}
+	return r.Validate()
+}
+
+// bounds returns the exclusive end of the range within the grid
+func (s *Sheet) bounds(r *sheets.Range) (int, int) {
+	endRow, endColumn := s.RowCount, s.ColumnCount
+	if r.EndRow != sheets.Unbounded {
+		endRow = min(endRow, r.EndRow)
+	}
+	if r.EndColumn != sheets.Unbounded {
+		endColumn = min(endColumn, r.EndColumn)
+	}
+	return endRow, endColumn
+}
+
+func (s *Sheet) toSheet() *sheets.Sheet {
+	return &sheets.Sheet{
+		Id:    s.Id,
+		Title: s.Title,
+		Index: s.Index,
+	}
+}
+
+func (s *Sheet) grow(rows, columns int) {
+	s.RowCount = max(s.RowCount, rows)
+	s.ColumnCount = max(s.ColumnCount, columns)
+}
+
+func (s *Sheet) write(rowOffset, columnOffset int, data [][]string) {
+	for i, row := range data {
+		r := rowOffset + i
+		for len(s.Cells) <= r {
+			s.Cells = append(s.Cells, nil)
+		}
+		for j, cell := range row {
+			c := columnOffset + j
+			for len(s.Cells[r]) <= c {
+				s.Cells[r] = append(s.Cells[r], "")
+			}
+			s.Cells[r][c] = cell
+		}
+	}
+}
+
+// lastRow returns the index of the last row with a non-empty cell, or -1 for an empty sheet
+func (s *Sheet) lastRow() int {
+	for i := len(s.Cells) - 1; i >= 0; i-- {
+		if len(trimRow(s.Cells[i])) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func trimRow(row []string) []string {
+	end := len(row)
+	for end > 0 && row[end-1] == "" {
+		end--
+	}
+	return row[:end]
+}
+
+func width(data [][]string) int {
+	w := 0
+	for _, row := range data {
+		w = max(w, len(row))
+	}
+	return w
+}
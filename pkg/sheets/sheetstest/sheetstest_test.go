@@ -0,0 +1,130 @@
+package sheetstest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/sheets"
+)
+
+func TestSheet_AppendValues(t *testing.T) {
+	svc := NewService()
+	ss, _ := svc.CreateSpreadSheet("test")
+	sheet, err := ss.FirstSheet()
+	assert.NoError(t, err)
+
+	assert.NoError(t, sheet.AppendValues([][]string{{"a", "b"}}))
+	assert.NoError(t, sheet.AppendValues([][]string{{"c"}, {"d", "e", "f"}}))
+
+	values, err := sheet.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b"}, {"c"}, {"d", "e", "f"}}, values)
+
+	s := svc.Spreadsheet("spreadsheet-1").Sheets[0]
+	assert.Equal(t, defaultRowCount+3, s.RowCount)
+}
+
+func TestSheet_Values_Empty(t *testing.T) {
+	svc := NewService()
+	ss, _ := svc.CreateSpreadSheet("test")
+	sheet, _ := ss.FirstSheet()
+
+	_, err := sheet.Values()
+	assert.Error(t, err)
+}
+
+func TestSpreadsheet_CreateSheet(t *testing.T) {
+	svc := NewService()
+	ss, _ := svc.CreateSpreadSheet("test")
+
+	_, err := ss.CreateSheet(&sheets.CreateSheetOptions{Title: "second"})
+	assert.NoError(t, err)
+	_, err = ss.CreateSheet(&sheets.CreateSheetOptions{Title: "second"})
+	assert.Error(t, err)
+
+	first, err := ss.FirstSheet()
+	assert.NoError(t, err)
+	info, _ := first.Get()
+	assert.Equal(t, &sheets.Sheet{Id: 1, Title: "second", Index: 0}, info)
+
+	byTitle, err := ss.SheetByTitle(defaultSheetTitle)
+	assert.NoError(t, err)
+	info, _ = byTitle.Get()
+	assert.Equal(t, int64(1), info.Index)
+
+	_, err = ss.SheetById(42)
+	assert.ErrorIs(t, err, sheets.ErrNotFound)
+}
+
+func TestSheet_Ranges(t *testing.T) {
+	svc := NewService()
+	ss, _ := svc.CreateSpreadSheet("test")
+	sheet, _ := ss.FirstSheet()
+
+	r, err := sheets.ParseA1("B2:C3")
+	assert.NoError(t, err)
+
+	assert.NoError(t, sheet.WriteRange(r, [][]string{{"a", "b"}, {"c", "d"}}))
+	assert.Error(t, sheet.WriteRange(r, [][]string{{"a", "b", "c"}}))
+
+	values, err := sheet.ReadRange(&sheets.Range{StartRow: 0, StartColumn: 2, EndRow: sheets.Unbounded, EndColumn: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{}, {"b"}, {"d"}}, values)
+
+	assert.NoError(t, sheet.ClearRange(sheets.Cell(1, 1)))
+	values, err = sheet.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{}, {"", "", "b"}, {"", "c", "d"}}, values)
+
+	_, err = sheet.ReadRange(sheets.WholeSheet("other"))
+	assert.Error(t, err)
+}
+
+func TestSheet_DeleteRows(t *testing.T) {
+	svc := NewService()
+	ss, _ := svc.CreateSpreadSheet("test")
+	sheet, _ := ss.FirstSheet()
+	assert.NoError(t, sheet.UpdateValues([][]string{{"keep", "1"}, {"drop", "2"}, {"keep", "3"}}))
+
+	deleted, err := sheet.DeleteRows(func(row []any) bool {
+		return row[0] == "drop"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	values, _ := sheet.Values()
+	assert.Equal(t, [][]any{{"keep", "1"}, {"keep", "3"}}, values)
+}
+
+func TestSheet_Manage(t *testing.T) {
+	svc := NewService()
+	ss, _ := svc.CreateSpreadSheet("test")
+	sheet, _ := ss.FirstSheet()
+	assert.NoError(t, sheet.UpdateValues([][]string{{"a"}}))
+
+	assert.Error(t, sheet.Delete())
+
+	dup, err := sheet.Duplicate("copy")
+	assert.NoError(t, err)
+	values, _ := dup.Values()
+	assert.Equal(t, [][]any{{"a"}}, values)
+
+	assert.Error(t, sheet.Rename("copy"))
+	assert.NoError(t, sheet.Rename("original"))
+
+	info, _ := ss.Get()
+	assert.Equal(t, []*sheets.Sheet{{Id: 0, Title: "original", Index: 0}, {Id: 1, Title: "copy", Index: 1}}, info.Sheets)
+
+	assert.NoError(t, sheet.Clear())
+	_, err = sheet.Values()
+	assert.Error(t, err)
+
+	assert.NoError(t, sheet.Delete())
+	info, _ = ss.Get()
+	assert.Equal(t, []*sheets.Sheet{{Id: 1, Title: "copy", Index: 0}}, info.Sheets)
+
+	assert.NoError(t, dup.Protect(sheets.WholeSheet(""), &sheets.ProtectOptions{Description: "locked"}))
+	protections := svc.Spreadsheet("spreadsheet-1").Sheets[0].Protections
+	assert.Equal(t, "copy", protections[0].Range.Sheet)
+	assert.Equal(t, "locked", protections[0].Options.Description)
+}
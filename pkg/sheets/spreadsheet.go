@@ -4,11 +4,15 @@ import (
 	"errors"
 	"fmt"
 
+	"google.golang.org/api/googleapi"
 	googlesheets "google.golang.org/api/sheets/v4"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// metadataFields restricts metadata requests to the sheet properties, the grid data is never needed
+var metadataFields = []googleapi.Field{"spreadsheetId", "properties.title", "sheets.properties"}
+
 type CreateSheetOptions struct {
 	Title string
 }
@@ -25,6 +29,9 @@ type SpreadsheetOps interface {
 type spreadsheetOps struct {
 	service     *googlesheets.Service
 	spreadsheet *googlesheets.Spreadsheet
+
+	// stale marks the cached metadata in spreadsheet as outdated, e.g. after a mutating batchUpdate
+	stale bool
 }
 
 func (s *spreadsheetOps) CreateSheet(opts *CreateSheetOptions) (SheetOps, error) {
@@ -36,9 +43,7 @@ func (s *spreadsheetOps) CreateSheet(opts *CreateSheetOptions) (SheetOps, error)
 		},
 	}
 
-	breq := &googlesheets.BatchUpdateSpreadsheetRequest{Requests: []*googlesheets.Request{{AddSheet: req}}}
-
-	res, err := s.service.Spreadsheets.BatchUpdate(s.spreadsheet.SpreadsheetId, breq).Do()
+	res, err := s.batchUpdate(&googlesheets.Request{AddSheet: req})
 	if err != nil {
 		return nil, fmt.Errorf("unable to add sheet %q to %q: %w", s.spreadsheet.SpreadsheetId, opts.Title, err)
 	}
@@ -71,12 +76,12 @@ func (s *spreadsheetOps) SheetByTitle(title string) (SheetOps, error) {
 }
 
 func (s *spreadsheetOps) Get() (*SpreadSheet, error) {
-	err := s.refresh()
+	sheets, err := s.getSheets()
 	if err != nil {
 		return nil, err
 	}
 
-	return &SpreadSheet{Id: s.spreadsheet.SpreadsheetId, Sheets: mapSheets(s.spreadsheet.Sheets)}, nil
+	return &SpreadSheet{Id: s.spreadsheet.SpreadsheetId, Sheets: mapSheets(sheets)}, nil
 }
 
 func (s *spreadsheetOps) filteredSheets(predicate func(p *googlesheets.SheetProperties) bool) (*googlesheets.SheetProperties, error) {
@@ -98,25 +103,50 @@ func (s *spreadsheetOps) spreadsheetId() string {
 	return s.spreadsheet.SpreadsheetId
 }
 
+// getSheets returns the cached sheet metadata, it is only fetched again once invalidated
 func (s *spreadsheetOps) getSheets() ([]*googlesheets.Sheet, error) {
-	err := s.refresh()
-	if err != nil {
-		return nil, err
+	if s.stale {
+		err := s.refresh()
+		if err != nil {
+			return nil, err
+		}
 	}
 	return s.spreadsheet.Sheets, nil
 }
 
+// batchUpdate applies the requests and invalidates the cached metadata as they may have changed it
+func (s *spreadsheetOps) batchUpdate(requests ...*googlesheets.Request) (*googlesheets.BatchUpdateSpreadsheetResponse, error) {
+	req := &googlesheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+	res, err := s.service.Spreadsheets.BatchUpdate(s.spreadsheetId(), req).Do()
+	s.invalidate()
+	return res, err
+}
+
+// invalidate must be called after any request that changes the sheet properties, e.g. the grid size
+func (s *spreadsheetOps) invalidate() {
+	s.stale = true
+}
+
 func (s *spreadsheetOps) refresh() error {
-	res, err := s.service.Spreadsheets.Get(s.spreadsheet.SpreadsheetId).Do()
+	res, err := fetchSpreadsheet(s.service, s.spreadsheet.SpreadsheetId)
 	if err != nil {
-		return fmt.Errorf("cannot create spreadsheet: %w", err)
+		return err
 	}
 
 	s.spreadsheet = res
+	s.stale = false
 
 	return nil
 }
 
+func fetchSpreadsheet(service *googlesheets.Service, id string) (*googlesheets.Spreadsheet, error) {
+	res, err := service.Spreadsheets.Get(id).Fields(metadataFields...).Do()
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch spreadsheet %q: %w", id, err)
+	}
+	return res, nil
+}
+
 func (s *spreadsheetOps) toSheetOpsWithErr(sheet *googlesheets.SheetProperties, err error) (*sheetOps, error) {
 	return s.toSheetOps(sheet), err
 }
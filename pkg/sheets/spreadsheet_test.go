@@ -0,0 +1,168 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	googlesheets "google.golang.org/api/sheets/v4"
+)
+
+type fakeSheetsApi struct {
+	metadataRequests int
+	fields           []string
+	batchUpdates     int
+	requests         []*googlesheets.Request
+	valueRanges      []string
+}
+
+func (f *fakeSheetsApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v4/spreadsheets/sid/values/"):
+		f.valueRanges = append(f.valueRanges, strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/sid/values/"))
+		_ = json.NewEncoder(w).Encode(&googlesheets.ValueRange{Values: [][]any{{"a"}, {"b"}, {"a"}}})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v4/spreadsheets/"):
+		f.metadataRequests++
+		f.fields = append(f.fields, r.URL.Query().Get("fields"))
+		_ = json.NewEncoder(w).Encode(&googlesheets.Spreadsheet{
+			SpreadsheetId: "sid",
+			Sheets: []*googlesheets.Sheet{{Properties: &googlesheets.SheetProperties{
+				SheetId:        7,
+				Title:          "it's data",
+				GridProperties: &googlesheets.GridProperties{ColumnCount: 1, RowCount: 1},
+			}}},
+		})
+	case strings.HasSuffix(r.URL.Path, ":batchUpdate"):
+		f.batchUpdates++
+		var req googlesheets.BatchUpdateSpreadsheetRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.requests = append(f.requests, req.Requests...)
+		_ = json.NewEncoder(w).Encode(&googlesheets.BatchUpdateSpreadsheetResponse{SpreadsheetId: "sid"})
+	case strings.HasSuffix(r.URL.Path, ":batchUpdateByDataFilter"):
+		_ = json.NewEncoder(w).Encode(&googlesheets.BatchUpdateValuesByDataFilterResponse{SpreadsheetId: "sid"})
+	default:
+		http.Error(w, "unexpected request "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+func newFakeSheetsService(t *testing.T, api *fakeSheetsApi) *sheetsService {
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	svc, err := googlesheets.NewService(context.Background(), option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	assert.NoError(t, err)
+	return &sheetsService{service: svc}
+}
+
+func TestSpreadsheetOps_CachesMetadata(t *testing.T) {
+	api := &fakeSheetsApi{}
+	svc := newFakeSheetsService(t, api)
+
+	ss, err := svc.GetSpreadSheet("sid")
+	assert.NoError(t, err)
+
+	sheet, err := ss.SheetById(7)
+	assert.NoError(t, err)
+	_, err = ss.SheetByTitle("it's data")
+	assert.NoError(t, err)
+	_, err = sheet.Get()
+	assert.NoError(t, err)
+	_, err = ss.Get()
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, api.metadataRequests)
+	assert.Equal(t, []string{"spreadsheetId,properties.title,sheets.properties"}, api.fields)
+}
+
+func TestSpreadsheetOps_InvalidatesAfterGrow(t *testing.T) {
+	api := &fakeSheetsApi{}
+	svc := newFakeSheetsService(t, api)
+
+	ss, err := svc.GetSpreadSheet("sid")
+	assert.NoError(t, err)
+
+	sheet, err := ss.SheetById(7)
+	assert.NoError(t, err)
+
+	err = sheet.UpdateValues([][]string{{"a", "b"}, {"c", "d"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, api.batchUpdates)
+	assert.Equal(t, 1, api.metadataRequests)
+
+	_, err = sheet.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, api.metadataRequests)
+}
+
+func TestSheetOps_Ranges(t *testing.T) {
+	api := &fakeSheetsApi{}
+	svc := newFakeSheetsService(t, api)
+
+	ss, err := svc.GetSpreadSheet("sid")
+	assert.NoError(t, err)
+	sheet, err := ss.SheetById(7)
+	assert.NoError(t, err)
+
+	r, err := ParseA1("A1:B2")
+	assert.NoError(t, err)
+
+	values, err := sheet.ReadRange(r)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a"}, {"b"}, {"a"}}, values)
+
+	assert.NoError(t, sheet.ClearRange(r))
+	assert.NoError(t, sheet.AppendValues([][]string{{"a"}}))
+
+	_, err = sheet.ReadRange(WholeSheet("other"))
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"'it''s data'!A1:B2", "'it''s data'!A1:B2:clear", "'it''s data'!A:A:append"}, api.valueRanges)
+}
+
+func TestSheetOps_WriteRange_ExceedsRange(t *testing.T) {
+	api := &fakeSheetsApi{}
+	svc := newFakeSheetsService(t, api)
+
+	ss, err := svc.GetSpreadSheet("sid")
+	assert.NoError(t, err)
+	sheet, err := ss.SheetById(7)
+	assert.NoError(t, err)
+
+	r, err := ParseA1("B2:C3")
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, sheet.WriteRange(r, [][]string{{"a", "b", "c"}}), "data with 3 columns exceeds range B2:C3")
+	assert.ErrorContains(t, sheet.WriteRange(r, [][]string{{"a"}, {"b"}, {"c"}}), "data with 3 rows exceeds range B2:C3")
+
+	// neither grown nor written
+	assert.Equal(t, 0, api.batchUpdates)
+	assert.Empty(t, api.valueRanges)
+}
+
+func TestSheetOps_DeleteRows(t *testing.T) {
+	api := &fakeSheetsApi{}
+	svc := newFakeSheetsService(t, api)
+
+	ss, err := svc.GetSpreadSheet("sid")
+	assert.NoError(t, err)
+	sheet, err := ss.SheetById(7)
+	assert.NoError(t, err)
+
+	deleted, err := sheet.DeleteRows(func(row []any) bool {
+		return row[0] == "a"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	// rows are deleted bottom up to keep the indices stable
+	assert.Equal(t, 1, api.batchUpdates)
+	assert.Equal(t, int64(2), api.requests[0].DeleteDimension.Range.StartIndex)
+	assert.Equal(t, int64(0), api.requests[1].DeleteDimension.Range.StartIndex)
+	assert.Equal(t, int64(7), api.requests[1].DeleteDimension.Range.SheetId)
+}
@@ -0,0 +1,240 @@
+// Package sql2json runs SQL queries against any registered database driver and writes the
+// resulting rows as JSON.
+package sql2json
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Config describes a database connection, User, Password and Name override the respective
+// parts of the connection URI if set
+type Config struct {
+	ConnectionUri string
+
+	User     string
+	Password string
+	Name     string
+}
+
+// Driver knows how to turn a connection URI into a DSN for a database/sql driver
+type Driver struct {
+	// SqlDriver is the name the database/sql driver is registered with
+	SqlDriver string
+
+	// Placeholder is the bind parameter syntax of the driver
+	Placeholder Placeholder
+
+	// Dialect is the SQL dialect spoken by the database, e.g. for DDL and upserts
+	Dialect Dialect
+
+	// Dsn builds the data source name from the parsed connection URI and the overrides
+	Dsn func(u *url.URL, cfg *Config) (string, error)
+}
+
+var drivers = map[string]*Driver{
+	"mysql": {
+		SqlDriver:   "mysql",
+		Placeholder: PlaceholderQuestion,
+		Dialect:     DialectMysql,
+		Dsn:         mysqlDsn,
+	},
+	"postgres": {
+		SqlDriver:   "pgx",
+		Placeholder: PlaceholderDollar,
+		Dialect:     DialectPostgres,
+		Dsn:         postgresDsn,
+	},
+	"postgresql": {
+		SqlDriver:   "pgx",
+		Placeholder: PlaceholderDollar,
+		Dialect:     DialectPostgres,
+		Dsn:         postgresDsn,
+	},
+	"sqlite": {
+		SqlDriver:   "sqlite",
+		Placeholder: PlaceholderQuestion,
+		Dialect:     DialectSqlite,
+		Dsn:         sqliteDsn,
+	},
+}
+
+// Placeholder is the syntax of bind parameters in queries
+type Placeholder int
+
+const (
+	// PlaceholderQuestion binds parameters with '?', e.g. MySQL and SQLite
+	PlaceholderQuestion Placeholder = iota
+	// PlaceholderDollar binds parameters with '$1', '$2', ..., e.g. PostgreSQL
+	PlaceholderDollar
+)
+
+type Dialect string
+
+const (
+	DialectMysql    = Dialect("mysql")
+	DialectPostgres = Dialect("postgres")
+	DialectSqlite   = Dialect("sqlite")
+)
+
+// DB is a database connection along with the driver it was opened with
+type DB struct {
+	*sql.DB
+	Driver *Driver
+}
+
+// Register adds a driver for connection URIs with the given scheme
+func Register(scheme string, d *Driver) {
+	drivers[scheme] = d
+}
+
+// Schemes returns the supported URI schemes
+func Schemes() []string {
+	schemes := make([]string, 0, len(drivers))
+	for s := range drivers {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open connects to the database described by the configuration and verifies the connection.
+// Connection URIs without a scheme are treated as MySQL DSNs, e.g. 'root@tcp(127.0.0.1:3306)/mydb'.
+func Open(ctx context.Context, cfg *Config) (*DB, error) {
+	driver, dsn, err := resolve(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driver.SqlDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open database: %w", err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot connect to database: %w", err)
+	}
+	return &DB{DB: db, Driver: driver}, nil
+}
+
+func resolve(cfg *Config) (*Driver, string, error) {
+	if !strings.Contains(cfg.ConnectionUri, "://") {
+		dsn, err := legacyMysqlDsn(cfg)
+		return drivers["mysql"], dsn, err
+	}
+
+	u, err := url.Parse(cfg.ConnectionUri)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid connection URI: %w", err)
+	}
+
+	driver, ok := drivers[u.Scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported database %q, supported are: %s", u.Scheme, strings.Join(Schemes(), ", "))
+	}
+
+	dsn, err := driver.Dsn(u, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return driver, dsn, nil
+}
+
+func legacyMysqlDsn(cfg *Config) (string, error) {
+	mcfg, err := mysql.ParseDSN(cfg.ConnectionUri)
+	if err != nil {
+		return "", fmt.Errorf("invalid mysql connection URI: %w", err)
+	}
+	return overrideMysql(mcfg, cfg), nil
+}
+
+func mysqlDsn(u *url.URL, cfg *Config) (string, error) {
+	mcfg := mysql.NewConfig()
+	mcfg.Net = "tcp"
+	mcfg.Addr = u.Host
+	if u.Port() == "" {
+		mcfg.Addr = net.JoinHostPort(u.Hostname(), "3306")
+	}
+	mcfg.DBName = strings.TrimPrefix(u.Path, "/")
+	if u.User != nil {
+		mcfg.User = u.User.Username()
+		mcfg.Passwd, _ = u.User.Password()
+	}
+
+	// let the driver deal with its parameters, e.g. 'parseTime=true'
+	if u.RawQuery != "" {
+		parsed, err := mysql.ParseDSN(mcfg.FormatDSN() + "?" + u.RawQuery)
+		if err != nil {
+			return "", fmt.Errorf("invalid mysql connection URI: %w", err)
+		}
+		mcfg = parsed
+	}
+
+	return overrideMysql(mcfg, cfg), nil
+}
+
+func overrideMysql(mcfg *mysql.Config, cfg *Config) string {
+	if cfg.Name != "" {
+		mcfg.DBName = cfg.Name
+	}
+	if cfg.User != "" {
+		mcfg.User = cfg.User
+	}
+	if cfg.Password != "" {
+		mcfg.Passwd = cfg.Password
+	}
+	return mcfg.FormatDSN()
+}
+
+func postgresDsn(u *url.URL, cfg *Config) (string, error) {
+	c := *u
+	if cfg.Name != "" {
+		c.Path = "/" + cfg.Name
+	}
+	if cfg.User != "" || cfg.Password != "" {
+		user, password := "", ""
+		if c.User != nil {
+			user = c.User.Username()
+			password, _ = c.User.Password()
+		}
+		if cfg.User != "" {
+			user = cfg.User
+		}
+		if cfg.Password != "" {
+			password = cfg.Password
+		}
+		c.User = url.UserPassword(user, password)
+	}
+	return c.String(), nil
+}
+
+// sqliteDsn accepts 'sqlite:///abs/path.db' as well as 'sqlite://relative/path.db', the query
+// is passed on to the driver, e.g. '?_pragma=foreign_keys(1)'
+func sqliteDsn(u *url.URL, cfg *Config) (string, error) {
+	if cfg.User != "" || cfg.Password != "" || u.User != nil {
+		return "", fmt.Errorf("sqlite does not support users or passwords")
+	}
+
+	path := u.Host + u.Path
+	if cfg.Name != "" {
+		path = cfg.Name
+	}
+	if path == "" {
+		return "", fmt.Errorf("invalid sqlite connection URI %q: missing path", cfg.ConnectionUri)
+	}
+
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path, nil
+}
@@ -0,0 +1,46 @@
+package sql2json
+
+import "fmt"
+
+// ConnectionFlags can be embedded into a kong CLI to select the database connection
+type ConnectionFlags struct {
+	Profile         string `help:"named connection profile, see 'tb sql2json profile add'" optional:"" env:"SQL2JSON_PROFILE"`
+	DbConnectionUri string `help:"database connection URI, e.g.: 'postgres://user@localhost:5432/mydb', 'mysql://root@127.0.0.1:3306/mydb', 'sqlite:///tmp/my.db' or the MySQL DSN 'root@tcp(127.0.0.1:3306)/mydb'" optional:"" env:"SQL2JSON_DB_CONNECTION_URI"`
+
+	DbUser     string `help:"user for the database, overrides the profile" optional:"" env:"SQL2JSON_DB_USER"`
+	DbPassword string `help:"password for the database, overrides the profile" optional:"" env:"SQL2JSON_DB_PASSWORD"`
+	DbName     string `help:"name of the database, the file for SQLite, overrides the profile" optional:"" env:"SQL2JSON_DB_NAME"`
+}
+
+// Config starts from the profile if any, the flags override its parts
+func (f *ConnectionFlags) Config() (*Config, error) {
+	cfg := &Config{}
+	if f.Profile != "" {
+		path, err := DefaultProfilesPath()
+		if err != nil {
+			return nil, err
+		}
+		cfg, err = NewProfileStore(path).Config(f.Profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if f.DbConnectionUri != "" {
+		cfg.ConnectionUri = f.DbConnectionUri
+	}
+	if f.DbUser != "" {
+		cfg.User = f.DbUser
+	}
+	if f.DbPassword != "" {
+		cfg.Password = f.DbPassword
+	}
+	if f.DbName != "" {
+		cfg.Name = f.DbName
+	}
+
+	if cfg.ConnectionUri == "" {
+		return nil, fmt.Errorf("either --profile or --db-connection-uri is required")
+	}
+	return cfg, nil
+}
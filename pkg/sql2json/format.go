@@ -0,0 +1,277 @@
+package sql2json
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ResultSetColumn is added to rows of all but the first result set in JSON formats and holds the
+// zero-based index of the result set. Options.LabelResultSets adds it to the rows of every result
+// set in all formats.
+const ResultSetColumn = "_resultSet"
+
+type Format string
+
+const (
+	FormatNdjson    = Format("ndjson")
+	FormatJsonArray = Format("json-array")
+	FormatCsv       = Format("csv")
+	FormatTsv       = Format("tsv")
+	FormatTable     = Format("table")
+)
+
+// RowWriter writes the rows of one or more result sets
+type RowWriter interface {
+	// BeginResultSet is called before the rows of each result set, index is zero-based. It may be
+	// called repeatedly for the same result set, e.g. once per parameter set in batch mode.
+	BeginResultSet(index int, columns []string) error
+	WriteRow(row *Row) error
+
+	// Close finishes the output, it does not close the underlying writer
+	Close() error
+}
+
+// NewRowWriter returns a writer producing the given format
+func NewRowWriter(format Format, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatNdjson, "":
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatJsonArray:
+		return &jsonArrayWriter{w: w}, nil
+	case FormatCsv:
+		return newCsvWriter(w, ','), nil
+	case FormatTsv:
+		return newCsvWriter(w, '\t'), nil
+	case FormatTable:
+		return &tableWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type ndjsonWriter struct {
+	encoder   *json.Encoder
+	resultSet int
+}
+
+func (n *ndjsonWriter) BeginResultSet(index int, _ []string) error {
+	n.resultSet = index
+	return nil
+}
+
+func (n *ndjsonWriter) WriteRow(row *Row) error {
+	return n.encoder.Encode(tagResultSet(row, n.resultSet))
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+type jsonArrayWriter struct {
+	w         io.Writer
+	resultSet int
+	rows      int
+}
+
+func (j *jsonArrayWriter) BeginResultSet(index int, _ []string) error {
+	j.resultSet = index
+	return nil
+}
+
+func (j *jsonArrayWriter) WriteRow(row *Row) error {
+	b, err := json.Marshal(tagResultSet(row, j.resultSet))
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if j.rows == 0 {
+		sep = "[\n"
+	}
+	j.rows++
+
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, b)
+	return err
+}
+
+func (j *jsonArrayWriter) Close() error {
+	if j.rows == 0 {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// tagResultSet adds ResultSetColumn to rows of all but the first result set, unless it is there
+// already
+func tagResultSet(row *Row, index int) *Row {
+	if index == 0 || slices.Contains(row.Columns, ResultSetColumn) {
+		return row
+	}
+	return &Row{
+		Columns: append(slices.Clip(row.Columns), ResultSetColumn),
+		Values:  append(slices.Clip(row.Values), index),
+	}
+}
+
+// tabular keeps track of the columns of the current result set, rows may lack columns with
+// NULL values
+type tabular struct {
+	resultSet int
+	columns   []string
+	started   bool
+}
+
+// begin returns whether a new result set starts
+func (t *tabular) begin(index int, columns []string) bool {
+	if t.started && t.resultSet == index && slices.Equal(t.columns, columns) {
+		return false
+	}
+	t.resultSet = index
+	t.columns = columns
+	t.started = true
+	return true
+}
+
+func (t *tabular) cells(row *Row, null string) []string {
+	cells := make([]string, len(t.columns))
+	for i := range cells {
+		cells[i] = null
+	}
+	for i, column := range row.Columns {
+		if idx := slices.Index(t.columns, column); idx >= 0 {
+			cells[idx] = formatCell(row.Values[i], null)
+		}
+	}
+	return cells
+}
+
+type csvWriter struct {
+	tabular
+	w      io.Writer
+	writer *csv.Writer
+}
+
+func newCsvWriter(w io.Writer, comma rune) *csvWriter {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	return &csvWriter{w: w, writer: writer}
+}
+
+// BeginResultSet writes the header, result sets are separated by an empty line
+func (c *csvWriter) BeginResultSet(index int, columns []string) error {
+	separate := c.started
+	if !c.begin(index, columns) {
+		return nil
+	}
+	if separate {
+		c.writer.Flush()
+		if _, err := io.WriteString(c.w, "\n"); err != nil {
+			return err
+		}
+	}
+	return c.writer.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(row *Row) error {
+	return c.writer.Write(c.cells(row, ""))
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type tableWriter struct {
+	tabular
+	w      io.Writer
+	writer *tabwriter.Writer
+}
+
+// BeginResultSet writes the header, result sets after the first are captioned with their index
+func (t *tableWriter) BeginResultSet(index int, columns []string) error {
+	separate := t.started
+	if !t.begin(index, columns) {
+		return nil
+	}
+	if err := t.flush(); err != nil {
+		return err
+	}
+	if separate {
+		if _, err := fmt.Fprintf(t.w, "\nresult set %d\n", index); err != nil {
+			return err
+		}
+	}
+
+	t.writer = tabwriter.NewWriter(t.w, 0, 0, 2, ' ', 0)
+	if err := t.writeLine(columns); err != nil {
+		return err
+	}
+
+	underline := make([]string, len(columns))
+	for i, column := range columns {
+		underline[i] = strings.Repeat("-", max(len(column), 1))
+	}
+	return t.writeLine(underline)
+}
+
+func (t *tableWriter) WriteRow(row *Row) error {
+	return t.writeLine(t.cells(row, "NULL"))
+}
+
+func (t *tableWriter) Close() error {
+	return t.flush()
+}
+
+func (t *tableWriter) writeLine(cells []string) error {
+	for i, cell := range cells {
+		// tabs and newlines would break the alignment
+		cells[i] = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(cell)
+	}
+	_, err := io.WriteString(t.writer, strings.Join(cells, "\t")+"\n")
+	return err
+}
+
+func (t *tableWriter) flush() error {
+	if t.writer == nil {
+		return nil
+	}
+	return t.writer.Flush()
+}
+
+func formatCell(v any, null string) string {
+	switch v := v.(type) {
+	case nil:
+		return null
+	case string:
+		return v
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case json.RawMessage:
+		return string(v)
+	case json.Number:
+		return v.String()
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,198 @@
+package sql2json
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowWriter_Formats(t *testing.T) {
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format: FormatNdjson,
+			expected: `{"id":1,"name":"Alice"}
+{"id":2,"name":null}
+{"n":2,"_resultSet":1}
+`,
+		},
+		{
+			format: FormatJsonArray,
+			expected: `[
+{"id":1,"name":"Alice"},
+{"id":2,"name":null},
+{"n":2,"_resultSet":1}
+]
+`,
+		},
+		{
+			format: FormatCsv,
+			expected: `id,name
+1,Alice
+2,
+
+n
+2
+`,
+		},
+		{
+			format:   FormatTsv,
+			expected: "id\tname\n1\tAlice\n2\t\n\nn\n2\n",
+		},
+		{
+			format: FormatTable,
+			expected: `id  name
+--  ----
+1   Alice
+2   NULL
+
+result set 1
+n
+-
+2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			db := openMultiResultDb(t)
+
+			var buf bytes.Buffer
+			w, err := NewRowWriter(tt.format, &buf)
+			assert.NoError(t, err)
+
+			err = ExecQuery(context.Background(), db, "CALL stats()", nil, w, &Options{})
+			assert.NoError(t, err)
+			assert.NoError(t, w.Close())
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestExecQuery_LabelResultSets(t *testing.T) {
+	db := openMultiResultDb(t)
+
+	var buf bytes.Buffer
+	w, err := NewRowWriter(FormatNdjson, &buf)
+	assert.NoError(t, err)
+
+	err = ExecQuery(context.Background(), db, "CALL stats()", nil, w, &Options{LabelResultSets: true})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, `{"id":1,"name":"Alice","_resultSet":0}
+{"id":2,"name":null,"_resultSet":0}
+{"n":2,"_resultSet":1}
+`, buf.String())
+}
+
+func TestRowWriter_EmptyJsonArray(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewRowWriter(FormatJsonArray, &buf)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestRowWriter_CsvOmitNullAndBatch(t *testing.T) {
+	db := openUsersDb(t)
+	_, err := db.Exec(`INSERT INTO users (name) VALUES (NULL)`)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewRowWriter(FormatCsv, &buf)
+	assert.NoError(t, err)
+
+	// the header is written once and omitted columns stay aligned
+	err = ExecBatch(context.Background(), db, "SELECT name, id FROM users WHERE id = :id", bytes.NewBufferString("{\"id\":3}\n{\"id\":1}\n"), w, &Options{OmitNull: true})
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	expected := `name,id,_params
+,3,"{""id"":3}"
+Alice,1,"{""id"":1}"
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestNewRowWriter_Unsupported(t *testing.T) {
+	_, err := NewRowWriter("xml", io.Discard)
+	assert.Error(t, err)
+}
+
+// multiResultDriver returns two fixed result sets for any query, just like a stored procedure
+type multiResultDriver struct{}
+
+func (multiResultDriver) Open(string) (driver.Conn, error) {
+	return multiResultConn{}, nil
+}
+
+type multiResultConn struct{}
+
+func (multiResultConn) Prepare(string) (driver.Stmt, error) { return multiResultStmt{}, nil }
+func (multiResultConn) Close() error                        { return nil }
+func (multiResultConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+type multiResultStmt struct{}
+
+func (multiResultStmt) Close() error                               { return nil }
+func (multiResultStmt) NumInput() int                              { return -1 }
+func (multiResultStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (multiResultStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &multiResultRows{sets: []resultSet{
+		{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "Alice"}, {int64(2), nil}}},
+		{columns: []string{"n"}, rows: [][]driver.Value{{int64(2)}}},
+	}}, nil
+}
+
+type resultSet struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+type multiResultRows struct {
+	sets []resultSet
+	set  int
+	row  int
+}
+
+func (r *multiResultRows) Columns() []string { return r.sets[r.set].columns }
+func (r *multiResultRows) Close() error      { return nil }
+
+func (r *multiResultRows) Next(dest []driver.Value) error {
+	set := r.sets[r.set]
+	if r.row >= len(set.rows) {
+		return io.EOF
+	}
+	copy(dest, set.rows[r.row])
+	r.row++
+	return nil
+}
+
+func (r *multiResultRows) HasNextResultSet() bool { return r.set+1 < len(r.sets) }
+
+func (r *multiResultRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set++
+	r.row = 0
+	return nil
+}
+
+func init() {
+	sql.Register("sql2json-multi-result", multiResultDriver{})
+}
+
+func openMultiResultDb(t *testing.T) *DB {
+	db, err := sql.Open("sql2json-multi-result", "")
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return &DB{DB: db, Driver: &Driver{Placeholder: PlaceholderQuestion}}
+}
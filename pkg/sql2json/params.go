@@ -0,0 +1,220 @@
+package sql2json
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Params are bind parameters of a query, either named and referenced as ':name' in the query
+// or positional and referenced with the placeholders of the driver, e.g. '?' or '$1'
+type Params struct {
+	Named      map[string]any
+	Positional []any
+}
+
+// ParseParam parses a '--param' flag, 'name=value' sets a named parameter while anything else is
+// appended as a positional parameter
+func (p *Params) ParseParam(s string) {
+	name, value, ok := strings.Cut(s, "=")
+	if ok && paramNamePattern.MatchString(name) {
+		if p.Named == nil {
+			p.Named = make(map[string]any)
+		}
+		p.Named[name] = value
+		return
+	}
+	p.Positional = append(p.Positional, s)
+}
+
+// ParseParamsJson parses a set of parameters from JSON, an object holds named and an array
+// positional parameters
+func ParseParamsJson(b []byte) (*Params, error) {
+	var raw any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	switch raw := raw.(type) {
+	case map[string]any:
+		named := make(map[string]any, len(raw))
+		for k, v := range raw {
+			named[k] = fromJson(v)
+		}
+		return &Params{Named: named}, nil
+	case []any:
+		positional := make([]any, len(raw))
+		for i, v := range raw {
+			positional[i] = fromJson(v)
+		}
+		return &Params{Positional: positional}, nil
+	}
+	return nil, fmt.Errorf("invalid parameters, expected an object or array but got: %s", b)
+}
+
+// fromJson converts a decoded JSON value to a value the drivers can bind, nested objects and
+// arrays are bound as their JSON text
+func fromJson(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]any, []any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return v
+}
+
+// statement is a query with named parameters rewritten to the placeholders of a driver
+type statement struct {
+	query string
+
+	// names of the parameters in order of the placeholders, empty if the query uses positional
+	// parameters
+	names []string
+}
+
+// compile rewrites ':name' parameters to driver placeholders, string literals, quoted identifiers,
+// comments and PostgreSQL '::' casts are left untouched
+func compile(query string, placeholder Placeholder) *statement {
+	var out strings.Builder
+	var names []string
+	indices := make(map[string]int)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+			out.WriteString(query[i:end])
+			i = end - 1
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			out.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+
+			switch placeholder {
+			case PlaceholderDollar:
+				idx, ok := indices[name]
+				if !ok {
+					names = append(names, name)
+					idx = len(names)
+					indices[name] = idx
+				}
+				out.WriteString("$" + strconv.Itoa(idx))
+			default:
+				names = append(names, name)
+				out.WriteByte('?')
+			}
+			i = end - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return &statement{query: out.String(), names: names}
+}
+
+// args returns the arguments in order of the placeholders
+func (s *statement) args(params *Params) ([]any, error) {
+	if params == nil {
+		params = &Params{}
+	}
+
+	if len(s.names) == 0 {
+		if len(params.Named) > 0 {
+			return nil, fmt.Errorf("query has no named parameters but got: %s", strings.Join(sortedKeys(params.Named), ", "))
+		}
+		return params.Positional, nil
+	}
+
+	if len(params.Positional) > 0 {
+		return nil, fmt.Errorf("cannot mix named parameters in the query with %d positional parameters", len(params.Positional))
+	}
+
+	used := make(map[string]bool)
+	args := make([]any, len(s.names))
+	for i, name := range s.names {
+		v, ok := params.Named[name]
+		if !ok {
+			return nil, fmt.Errorf("missing parameter %q", name)
+		}
+		args[i] = v
+		used[name] = true
+	}
+
+	for _, name := range sortedKeys(params.Named) {
+		if !used[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return args, nil
+}
+
+// skipQuoted returns the index after the quoted section starting at i, quotes within are escaped
+// by doubling them
+func skipQuoted(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != quote {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(s)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package sql2json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		placeholder Placeholder
+		expected    string
+		names       []string
+	}{
+		{
+			name:        "question",
+			query:       "SELECT * FROM t WHERE id = :id AND a = :a OR b = :id",
+			placeholder: PlaceholderQuestion,
+			expected:    "SELECT * FROM t WHERE id = ? AND a = ? OR b = ?",
+			names:       []string{"id", "a", "id"},
+		},
+		{
+			name:        "dollar reuses indices",
+			query:       "SELECT * FROM t WHERE id = :id AND a = :a OR b = :id",
+			placeholder: PlaceholderDollar,
+			expected:    "SELECT * FROM t WHERE id = $1 AND a = $2 OR b = $1",
+			names:       []string{"id", "a"},
+		},
+		{
+			name:        "quotes, comments and casts",
+			query:       "SELECT ':no', \"x:no\", `y:no`, 'it''s :no', created::date -- :no\n/* :no */ FROM t WHERE id = :id",
+			placeholder: PlaceholderDollar,
+			expected:    "SELECT ':no', \"x:no\", `y:no`, 'it''s :no', created::date -- :no\n/* :no */ FROM t WHERE id = $1",
+			names:       []string{"id"},
+		},
+		{
+			name:        "positional",
+			query:       "SELECT * FROM t WHERE id = ? AND t = '12:30'",
+			placeholder: PlaceholderQuestion,
+			expected:    "SELECT * FROM t WHERE id = ? AND t = '12:30'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := compile(tt.query, tt.placeholder)
+			assert.Equal(t, tt.expected, stmt.query)
+			assert.Equal(t, tt.names, stmt.names)
+		})
+	}
+}
+
+func TestStatement_Args(t *testing.T) {
+	stmt := compile("SELECT :a, :b, :a", PlaceholderQuestion)
+
+	args, err := stmt.args(&Params{Named: map[string]any{"a": 1, "b": "x"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1, "x", 1}, args)
+
+	_, err = stmt.args(&Params{Named: map[string]any{"a": 1}})
+	assert.ErrorContains(t, err, `missing parameter "b"`)
+
+	_, err = stmt.args(&Params{Named: map[string]any{"a": 1, "b": 2, "c": 3}})
+	assert.ErrorContains(t, err, `unknown parameter "c"`)
+
+	_, err = stmt.args(&Params{Positional: []any{1}})
+	assert.Error(t, err)
+
+	positional := compile("SELECT ?", PlaceholderQuestion)
+	args, err = positional.args(&Params{Positional: []any{"1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"1"}, args)
+}
+
+func TestParams_ParseParam(t *testing.T) {
+	var p Params
+	p.ParseParam("id=42")
+	p.ParseParam("q=a=b")
+	p.ParseParam("plain")
+	p.ParseParam("1=2")
+
+	assert.Equal(t, map[string]any{"id": "42", "q": "a=b"}, p.Named)
+	assert.Equal(t, []any{"plain", "1=2"}, p.Positional)
+}
+
+func TestParseParamsJson(t *testing.T) {
+	p, err := ParseParamsJson([]byte(`{"id":42,"ratio":0.5,"name":"x","tags":["a"],"none":null}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": int64(42), "ratio": 0.5, "name": "x", "tags": `["a"]`, "none": nil}, p.Named)
+
+	p, err = ParseParamsJson([]byte(`[1,true]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []any{int64(1), true}, p.Positional)
+
+	_, err = ParseParamsJson([]byte(`"id"`))
+	assert.Error(t, err)
+}
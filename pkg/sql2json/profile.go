@@ -0,0 +1,228 @@
+package sql2json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/trichner/tb/pkg/keyring"
+)
+
+const keyringItemServiceName = "toolbox sql2json"
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Profile is a named database connection, its password is kept in the keyring rather than in
+// the profiles file
+type Profile struct {
+	Name          string `json:"name"`
+	ConnectionUri string `json:"connectionUri"`
+	User          string `json:"user,omitempty"`
+	Database      string `json:"database,omitempty"`
+
+	// StoredPassword is set if the keyring holds a password for the profile, the keyring is not
+	// touched otherwise
+	StoredPassword bool `json:"storedPassword,omitempty"`
+}
+
+// ProfileStore keeps profiles in a JSON file and their passwords in the keyring
+type ProfileStore struct {
+	path string
+}
+
+// DefaultProfilesPath is the profiles file in the user's configuration directory, e.g.
+// '~/.config/tb/sql2json-profiles.json' on Linux
+func DefaultProfilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "tb", "sql2json-profiles.json"), nil
+}
+
+func NewProfileStore(path string) *ProfileStore {
+	return &ProfileStore{path: path}
+}
+
+// List returns all profiles sorted by name
+func (s *ProfileStore) List() ([]*Profile, error) {
+	profiles, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+func (s *ProfileStore) Get(name string) (*Profile, error) {
+	profiles, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("profile %q: %w", name, ErrProfileNotFound)
+}
+
+// Put adds or replaces the profile, an empty password removes any stored password
+func (s *ProfileStore) Put(profile *Profile, password string) error {
+	if profile.Name == "" {
+		return fmt.Errorf("profile requires a name")
+	}
+	if hasPassword(profile.ConnectionUri) {
+		return fmt.Errorf("connection URI of profile %q must not contain a password, it is stored in the keyring instead", profile.Name)
+	}
+	if _, _, err := resolve(&Config{ConnectionUri: profile.ConnectionUri}); err != nil {
+		return err
+	}
+
+	profiles, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(profiles, func(p *Profile) bool {
+		return p.Name == profile.Name
+	})
+
+	profile.StoredPassword = password != ""
+	if profile.StoredPassword || (idx >= 0 && profiles[idx].StoredPassword) {
+		ring, err := keyring.Open(keyringItemServiceName)
+		if err != nil {
+			return err
+		}
+
+		var item *keyring.Item
+		if profile.StoredPassword {
+			item = &keyring.Item{Secret: password}
+		}
+		err = ring.Put(profile.Name, item)
+		if err != nil && !(item == nil && errors.Is(err, keyring.ErrNotFound)) {
+			return fmt.Errorf("cannot store password of profile %q: %w", profile.Name, err)
+		}
+	}
+
+	if idx >= 0 {
+		profiles[idx] = profile
+	} else {
+		profiles = append(profiles, profile)
+	}
+	return s.write(profiles)
+}
+
+// Remove deletes the profile along with its password
+func (s *ProfileStore) Remove(name string) error {
+	profiles, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(profiles, func(p *Profile) bool {
+		return p.Name == name
+	})
+	if idx < 0 {
+		return fmt.Errorf("profile %q: %w", name, ErrProfileNotFound)
+	}
+
+	if profiles[idx].StoredPassword {
+		ring, err := keyring.Open(keyringItemServiceName)
+		if err != nil {
+			return err
+		}
+		if err := ring.Put(name, nil); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+			return fmt.Errorf("cannot remove password of profile %q: %w", name, err)
+		}
+	}
+
+	return s.write(append(profiles[:idx], profiles[idx+1:]...))
+}
+
+// Config returns the connection configuration of the profile including its password
+func (s *ProfileStore) Config(name string) (*Config, error) {
+	profile, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	password := ""
+	if profile.StoredPassword {
+		ring, err := keyring.Open(keyringItemServiceName)
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := ring.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read password of profile %q: %w", name, err)
+		}
+		password = item.Secret
+	}
+
+	return &Config{
+		ConnectionUri: profile.ConnectionUri,
+		User:          profile.User,
+		Password:      password,
+		Name:          profile.Database,
+	}, nil
+}
+
+func (s *ProfileStore) read() ([]*Profile, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read profiles: %w", err)
+	}
+
+	var profiles []*Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profiles in %s: %w", s.path, err)
+	}
+	return profiles, nil
+}
+
+func (s *ProfileStore) write(profiles []*Profile) error {
+	if profiles == nil {
+		profiles = []*Profile{}
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("cannot create profiles directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("cannot write profiles: %w", err)
+	}
+	return nil
+}
+
+// hasPassword reports whether a connection URI or MySQL DSN carries a password
+func hasPassword(uri string) bool {
+	if !strings.Contains(uri, "://") {
+		cfg, err := mysql.ParseDSN(uri)
+		return err == nil && cfg.Passwd != ""
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || u.User == nil {
+		return false
+	}
+	_, ok := u.User.Password()
+	return ok
+}
@@ -0,0 +1,82 @@
+package sql2json
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	zk "github.com/zalando/go-keyring"
+)
+
+func newTestProfileStore(t *testing.T) *ProfileStore {
+	zk.MockInit()
+	return NewProfileStore(filepath.Join(t.TempDir(), "tb", "profiles.json"))
+}
+
+func TestProfileStore(t *testing.T) {
+	store := newTestProfileStore(t)
+
+	profiles, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+
+	replica := &Profile{Name: "replica", ConnectionUri: "postgres://reporting@db.local/app", Database: "reports"}
+	assert.NoError(t, store.Put(replica, "s3cret"))
+	assert.NoError(t, store.Put(&Profile{Name: "local", ConnectionUri: "sqlite:///tmp/local.db"}, ""))
+
+	profiles, err = store.List()
+	assert.NoError(t, err)
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, "local", profiles[0].Name)
+
+	cfg, err := store.Config("replica")
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{ConnectionUri: "postgres://reporting@db.local/app", Password: "s3cret", Name: "reports"}, cfg)
+
+	// the password never ends up in the file
+	data, err := os.ReadFile(store.path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cret")
+
+	assert.NoError(t, store.Remove("replica"))
+	_, err = store.Config("replica")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+	assert.ErrorIs(t, store.Remove("replica"), ErrProfileNotFound)
+}
+
+func TestProfileStore_Replace(t *testing.T) {
+	store := newTestProfileStore(t)
+
+	assert.NoError(t, store.Put(&Profile{Name: "db", ConnectionUri: "mysql://root@localhost/a"}, "first"))
+	assert.NoError(t, store.Put(&Profile{Name: "db", ConnectionUri: "mysql://root@localhost/b"}, ""))
+
+	cfg, err := store.Config("db")
+	assert.NoError(t, err)
+	assert.Equal(t, "mysql://root@localhost/b", cfg.ConnectionUri)
+	assert.Empty(t, cfg.Password)
+}
+
+func TestProfileStore_RejectsPasswords(t *testing.T) {
+	store := newTestProfileStore(t)
+
+	assert.Error(t, store.Put(&Profile{Name: "a", ConnectionUri: "postgres://user:pw@localhost/db"}, ""))
+	assert.Error(t, store.Put(&Profile{Name: "b", ConnectionUri: "root:pw@tcp(localhost:3306)/db"}, ""))
+	assert.Error(t, store.Put(&Profile{Name: "c", ConnectionUri: "oracle://localhost/db"}, ""))
+	assert.Error(t, store.Put(&Profile{ConnectionUri: "sqlite:///tmp/x.db"}, ""))
+}
+
+func TestProfileStore_Open(t *testing.T) {
+	store := newTestProfileStore(t)
+
+	path := filepath.Join(t.TempDir(), "test.db")
+	assert.NoError(t, store.Put(&Profile{Name: "fixtures", ConnectionUri: "sqlite://" + path}, ""))
+
+	cfg, err := store.Config("fixtures")
+	assert.NoError(t, err)
+
+	db, err := Open(context.Background(), cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+}
@@ -0,0 +1,127 @@
+package sql2json
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/trichner/tb/pkg/jsontree/lexer"
+)
+
+// Row is a result row which marshals to a JSON object with the columns in the order of the query
+type Row struct {
+	Columns []string
+	Values  []any
+}
+
+func (r *Row) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range r.Columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value, err := json.Marshal(r.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal column %q: %w", column, err)
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// valueKind is how values of a column are represented in JSON
+type valueKind int
+
+const (
+	kindText valueKind = iota
+	kindNumber
+	kindBool
+	kindJson
+	kindBinary
+)
+
+// kindOf maps the database type names reported by the drivers, e.g. 'UNSIGNED BIGINT' by MySQL or
+// 'INT8' by PostgreSQL. Unknown and empty type names, e.g. of expressions in SQLite, are text.
+func kindOf(t *sql.ColumnType) valueKind {
+	name := strings.ToUpper(t.DatabaseTypeName())
+	name = strings.TrimPrefix(name, "UNSIGNED ")
+	if idx := strings.IndexByte(name, '('); idx >= 0 {
+		name = name[:idx]
+	}
+
+	switch strings.TrimSpace(name) {
+	case "INT", "INTEGER", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT", "INT2", "INT4", "INT8",
+		"DECIMAL", "NUMERIC", "FLOAT", "DOUBLE", "REAL", "FLOAT4", "FLOAT8", "YEAR":
+		return kindNumber
+	case "BOOL", "BOOLEAN":
+		return kindBool
+	case "JSON", "JSONB":
+		return kindJson
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BYTEA", "GEOMETRY":
+		return kindBinary
+	}
+	return kindText
+}
+
+// toJsonValue converts a value as returned by the driver into a value marshalling to the
+// matching JSON type
+func toJsonValue(kind valueKind, v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if kind == kindBinary {
+			// marshals as base64
+			return v
+		}
+		return textToJsonValue(kind, string(v))
+	case string:
+		return textToJsonValue(kind, v)
+	case int64:
+		if kind == kindBool {
+			return v != 0
+		}
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return v
+	}
+	return v
+}
+
+// textToJsonValue interprets the textual representation some drivers use, e.g. MySQL returns
+// all values as text unless prepared statements are used
+func textToJsonValue(kind valueKind, s string) any {
+	switch kind {
+	case kindNumber:
+		if lexer.IsValidNumber(s) {
+			return json.Number(s)
+		}
+	case kindBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case kindJson:
+		if json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	case kindBinary:
+		return []byte(s)
+	}
+	return s
+}
@@ -0,0 +1,32 @@
+package sql2json
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRow_MarshalJSON(t *testing.T) {
+	row := &Row{
+		Columns: []string{"z", "a", "m\"x"},
+		Values:  []any{1, nil, "v"},
+	}
+
+	b, err := json.Marshal(row)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"z":1,"a":null,"m\"x":"v"}`, string(b))
+}
+
+func TestTextToJsonValue(t *testing.T) {
+	// MySQL returns text for all types when not using prepared statements
+	assert.Equal(t, json.Number("12.50"), textToJsonValue(kindNumber, "12.50"))
+	assert.Equal(t, json.Number("-3"), textToJsonValue(kindNumber, "-3"))
+	assert.Equal(t, "NaN", textToJsonValue(kindNumber, "NaN"))
+	assert.Equal(t, "1 ", textToJsonValue(kindNumber, "1 "))
+	assert.Equal(t, "1\n", textToJsonValue(kindNumber, "1\n"))
+	assert.Equal(t, true, textToJsonValue(kindBool, "t"))
+	assert.Equal(t, json.RawMessage(`[1,2]`), textToJsonValue(kindJson, "[1,2]"))
+	assert.Equal(t, "{broken", textToJsonValue(kindJson, "{broken"))
+	assert.Equal(t, "007", textToJsonValue(kindText, "007"))
+}
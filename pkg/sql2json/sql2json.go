@@ -0,0 +1,245 @@
+package sql2json
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// ParamsColumn is added to rows of a batch and holds the parameters the row was queried with
+const ParamsColumn = "_params"
+
+// defaultProgressInterval is used if Options.ProgressInterval is not set
+const defaultProgressInterval = 5 * time.Second
+
+// ErrMaxRows is returned once Options.MaxRows rows are written, the rows written so far are complete
+var ErrMaxRows = errors.New("maximum number of rows written")
+
+// Options control how rows are written
+type Options struct {
+	// OmitNull leaves out NULL columns instead of writing them as null
+	OmitNull bool
+
+	// MaxRows stops the query after writing that many rows across all result sets, 0 is unlimited
+	MaxRows int
+
+	// LabelResultSets adds ResultSetColumn to the rows of every result set, including the first.
+	// Rows are written as they are read, so whether more result sets follow is not known yet
+	// when the rows of the first one are written.
+	LabelResultSets bool
+
+	// Progress is called periodically with the number of rows written so far
+	Progress         func(rows int)
+	ProgressInterval time.Duration
+}
+
+// counter tracks the rows written for the limit and progress reports
+type counter struct {
+	opts       *Options
+	rows       int
+	lastReport time.Time
+}
+
+func newCounter(opts *Options) *counter {
+	return &counter{opts: opts, lastReport: time.Now()}
+}
+
+func (c *counter) add() error {
+	c.rows++
+
+	interval := c.opts.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	if c.opts.Progress != nil && time.Since(c.lastReport) >= interval {
+		c.lastReport = time.Now()
+		c.opts.Progress(c.rows)
+	}
+
+	if c.opts.MaxRows > 0 && c.rows >= c.opts.MaxRows {
+		return ErrMaxRows
+	}
+	return nil
+}
+
+// ExecQuery runs the query with the given parameters and writes the rows of all result sets,
+// values are converted to the matching JSON type
+func ExecQuery(ctx context.Context, db *DB, query string, params *Params, writer RowWriter, opts *Options) error {
+	stmt := compile(query, db.Driver.Placeholder)
+	args, err := stmt.args(params)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, stmt.query, args...)
+	if err != nil {
+		return fmt.Errorf("cannot execute query: %w", err)
+	}
+	defer rows.Close()
+
+	err = writeRows(rows, writer, newCounter(opts), &Row{})
+	if errors.Is(err, ErrMaxRows) {
+		// stop the query rather than reading the remaining rows when closing them
+		cancel()
+	}
+	return err
+}
+
+// ExecBatch prepares the query and runs it once for each set of parameters read as NDJSON from
+// the reader, the parameters are added to the resulting rows as ParamsColumn
+func ExecBatch(ctx context.Context, db *DB, query string, params io.Reader, writer RowWriter, opts *Options) error {
+	stmt := compile(query, db.Driver.Placeholder)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	prepared, err := db.PrepareContext(ctx, stmt.query)
+	if err != nil {
+		return fmt.Errorf("cannot prepare query: %w", err)
+	}
+	defer prepared.Close()
+
+	scanner := bufio.NewScanner(params)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	c := newCounter(opts)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		p, err := ParseParamsJson(raw)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		args, err := stmt.args(p)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rows, err := prepared.QueryContext(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("line %d: cannot execute query: %w", line, err)
+		}
+
+		tag := &Row{
+			Columns: []string{ParamsColumn},
+			Values:  []any{json.RawMessage(append([]byte(nil), raw...))},
+		}
+		err = writeRows(rows, writer, c, tag)
+		if errors.Is(err, ErrMaxRows) {
+			cancel()
+		}
+		rows.Close()
+		if errors.Is(err, ErrMaxRows) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read parameters: %w", err)
+	}
+	return nil
+}
+
+// writeRows writes the rows of all result sets, the extra columns are appended to every row
+func writeRows(rows *sql.Rows, writer RowWriter, c *counter, extra *Row) error {
+	for index := 0; ; index++ {
+		if err := writeResultSet(rows, index, writer, c, extra); err != nil {
+			return err
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("cannot scan rows: %w", err)
+	}
+	return nil
+}
+
+func writeResultSet(rows *sql.Rows, index int, writer RowWriter, c *counter, extra *Row) error {
+	header, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("cannot get result column names: %w", err)
+	}
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("cannot get result column types: %w", err)
+	}
+
+	kinds := make([]valueKind, len(columnTypes))
+	for i, t := range columnTypes {
+		kinds[i] = kindOf(t)
+	}
+
+	if c.opts.LabelResultSets {
+		extra = &Row{
+			Columns: append(slices.Clip(extra.Columns), ResultSetColumn),
+			Values:  append(slices.Clip(extra.Values), index),
+		}
+	}
+
+	if err := writer.BeginResultSet(index, append(slices.Clip(header), extra.Columns...)); err != nil {
+		return fmt.Errorf("cannot write result set: %w", err)
+	}
+
+	length := len(header)
+	for rows.Next() {
+		pointers := make([]any, length)
+		row := make([]any, length)
+
+		for i := range pointers {
+			pointers[i] = &row[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("cannot scan row: %w", err)
+		}
+
+		mapped := mapRow(header, kinds, row, c.opts)
+		mapped.Columns = append(mapped.Columns, extra.Columns...)
+		mapped.Values = append(mapped.Values, extra.Values...)
+		if err := writer.WriteRow(mapped); err != nil {
+			return fmt.Errorf("cannot write row: %w", err)
+		}
+		if err := c.add(); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func mapRow(headers []string, kinds []valueKind, r []any, opts *Options) *Row {
+	mapped := &Row{
+		Columns: make([]string, 0, len(headers)),
+		Values:  make([]any, 0, len(headers)),
+	}
+
+	for i, v := range r {
+		if v == nil && opts.OmitNull {
+			continue
+		}
+		mapped.Columns = append(mapped.Columns, headers[i])
+		mapped.Values = append(mapped.Values, toJsonValue(kinds[i], v))
+	}
+	return mapped
+}
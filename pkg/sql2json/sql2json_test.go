@@ -0,0 +1,176 @@
+package sql2json
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecQuery_Sqlite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := Open(context.Background(), &Config{ConnectionUri: "sqlite://" + path})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO users (name, email) VALUES ('Alice', 'alice@example.com'), ('Bob', NULL)`)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = ExecQuery(context.Background(), db, "SELECT name, id, email FROM users ORDER BY id", nil, ndjson(&buf), &Options{})
+	assert.NoError(t, err)
+
+	expected := `{"name":"Alice","id":1,"email":"alice@example.com"}
+{"name":"Bob","id":2,"email":null}
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExecQuery_OmitNull(t *testing.T) {
+	db := openTestDb(t)
+
+	var buf bytes.Buffer
+	err := ExecQuery(context.Background(), db, "SELECT 1 AS id, NULL AS email", nil, ndjson(&buf), &Options{OmitNull: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":1}\n", buf.String())
+}
+
+func TestExecQuery_Types(t *testing.T) {
+	db := openTestDb(t)
+
+	_, err := db.Exec(`CREATE TABLE things (
+		amount DECIMAL(10, 2),
+		ratio REAL,
+		active BOOLEAN,
+		attributes JSON,
+		payload BLOB,
+		label TEXT
+	)`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO things VALUES ('12.50', 0.5, 1, '{"tags":["a","b"]}', x'00ff10', '42')`)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = ExecQuery(context.Background(), db, "SELECT * FROM things", nil, ndjson(&buf), &Options{})
+	assert.NoError(t, err)
+
+	expected := `{"amount":12.5,"ratio":0.5,"active":true,"attributes":{"tags":["a","b"]},"payload":"AP8Q","label":"42"}
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExecQuery_InvalidQuery(t *testing.T) {
+	db := openTestDb(t)
+
+	err := ExecQuery(context.Background(), db, "SELECT * FROM missing", nil, ndjson(&bytes.Buffer{}), &Options{})
+	assert.Error(t, err)
+}
+
+func TestExecQuery_Params(t *testing.T) {
+	db := openUsersDb(t)
+
+	var buf bytes.Buffer
+	params := &Params{Named: map[string]any{"name": "Bob' OR '1'='1"}}
+	err := ExecQuery(context.Background(), db, "SELECT id FROM users WHERE name = :name", params, ndjson(&buf), &Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	params = &Params{Named: map[string]any{"name": "Bob"}}
+	err = ExecQuery(context.Background(), db, "SELECT id FROM users WHERE name = :name", params, ndjson(&buf), &Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":2}\n", buf.String())
+}
+
+func TestExecBatch(t *testing.T) {
+	db := openUsersDb(t)
+
+	input := `{"id":1}
+
+{"id":2}
+{"id":3}
+`
+
+	var buf bytes.Buffer
+	err := ExecBatch(context.Background(), db, "SELECT name FROM users WHERE id = :id", strings.NewReader(input), ndjson(&buf), &Options{})
+	assert.NoError(t, err)
+
+	expected := `{"name":"Alice","_params":{"id":1}}
+{"name":"Bob","_params":{"id":2}}
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExecBatch_InvalidLine(t *testing.T) {
+	db := openUsersDb(t)
+
+	input := "[1]\n{\"name\":\"x\"}\n"
+	err := ExecBatch(context.Background(), db, "SELECT name FROM users WHERE id = ?", strings.NewReader(input), ndjson(&bytes.Buffer{}), &Options{})
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func openUsersDb(t *testing.T) *DB {
+	db := openTestDb(t)
+
+	_, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)`)
+	assert.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO users (name) VALUES ('Alice'), ('Bob')`)
+	assert.NoError(t, err)
+	return db
+}
+
+func openTestDb(t *testing.T) *DB {
+	db, err := Open(context.Background(), &Config{ConnectionUri: "sqlite://" + filepath.Join(t.TempDir(), "test.db")})
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func ndjson(w io.Writer) RowWriter {
+	return &ndjsonWriter{encoder: json.NewEncoder(w)}
+}
+
+func TestExecQuery_MaxRows(t *testing.T) {
+	db := openUsersDb(t)
+
+	var buf bytes.Buffer
+	err := ExecQuery(context.Background(), db, "SELECT name FROM users ORDER BY id", nil, ndjson(&buf), &Options{MaxRows: 1})
+	assert.ErrorIs(t, err, ErrMaxRows)
+	assert.Equal(t, "{\"name\":\"Alice\"}\n", buf.String())
+
+	buf.Reset()
+	err = ExecBatch(context.Background(), db, "SELECT name FROM users WHERE id <= :id", strings.NewReader("{\"id\":1}\n{\"id\":2}\n"), ndjson(&buf), &Options{MaxRows: 2})
+	assert.ErrorIs(t, err, ErrMaxRows)
+	assert.Equal(t, "{\"name\":\"Alice\",\"_params\":{\"id\":1}}\n{\"name\":\"Alice\",\"_params\":{\"id\":2}}\n", buf.String())
+}
+
+func TestExecQuery_Progress(t *testing.T) {
+	db := openUsersDb(t)
+
+	var reported []int
+	opts := &Options{
+		Progress:         func(rows int) { reported = append(reported, rows) },
+		ProgressInterval: time.Nanosecond,
+	}
+	err := ExecQuery(context.Background(), db, "SELECT name FROM users", nil, ndjson(io.Discard), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, reported)
+}
+
+func TestExecQuery_Cancelled(t *testing.T) {
+	db := openUsersDb(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := ExecQuery(ctx, db, "SELECT name FROM users", nil, ndjson(io.Discard), &Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,194 @@
+// Package syncjob runs declarative jobs copying rows from a source such as a SQL query or a CSV
+// file to a destination such as a sheet or a SQL table.
+package syncjob
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+// Job is described in YAML, e.g.
+//
+//	source:
+//	  sql:
+//	    profile: reporting-replica
+//	    query: SELECT id, name FROM users WHERE created > :since
+//	    params:
+//	      since: 2024-01-01
+//	destination:
+//	  sheet:
+//	    spreadsheetUrl: https://docs.google.com/spreadsheets/d/.../edit#gid=0
+type Job struct {
+	Source      Source      `yaml:"source"`
+	Destination Destination `yaml:"destination"`
+}
+
+// Source must have exactly one of its fields set, paths are relative to the job file
+type Source struct {
+	Sql    *SqlSource `yaml:"sql"`
+	Csv    string     `yaml:"csv"`
+	Ndjson string     `yaml:"ndjson"`
+}
+
+// Destination must have exactly one of its fields set, paths are relative to the job file
+type Destination struct {
+	Sheet *SheetDestination `yaml:"sheet"`
+	Csv   string            `yaml:"csv"`
+	Sql   *SqlDestination   `yaml:"sql"`
+}
+
+// Connection selects the database, passwords are not part of the job but come from the keyring
+// via a profile or from an environment variable
+type Connection struct {
+	Profile       string `yaml:"profile"`
+	ConnectionUri string `yaml:"connectionUri"`
+	User          string `yaml:"user"`
+	Database      string `yaml:"database"`
+	PasswordEnv   string `yaml:"passwordEnv"`
+}
+
+type SqlSource struct {
+	Connection `yaml:",inline"`
+
+	Query     string         `yaml:"query"`
+	QueryFile string         `yaml:"queryFile"`
+	Params    map[string]any `yaml:"params"`
+}
+
+// SheetDestination replaces the contents of the sheet
+type SheetDestination struct {
+	SpreadsheetUrl string `yaml:"spreadsheetUrl"`
+}
+
+type SqlDestination struct {
+	Connection `yaml:",inline"`
+
+	Table     string   `yaml:"table"`
+	Create    bool     `yaml:"create"`
+	UpsertKey []string `yaml:"upsertKey"`
+	BatchSize int      `yaml:"batchSize"`
+}
+
+// LoadJob reads and validates a job file, relative paths within are resolved against its directory
+func LoadJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read job: %w", err)
+	}
+
+	job, err := ParseJob(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job %s: %w", path, err)
+	}
+
+	job.resolvePaths(filepath.Dir(path))
+	return job, nil
+}
+
+// ParseJob parses and validates a job, unknown fields are rejected to catch typos early
+func ParseJob(data []byte) (*Job, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var job Job
+	if err := dec.Decode(&job); err != nil {
+		return nil, err
+	}
+
+	if err := job.validate(); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (j *Job) validate() error {
+	sources := 0
+	if j.Source.Sql != nil {
+		sources++
+		if (j.Source.Sql.Query == "") == (j.Source.Sql.QueryFile == "") {
+			return fmt.Errorf("sql source requires either 'query' or 'queryFile'")
+		}
+		if err := j.Source.Sql.Connection.validate(); err != nil {
+			return err
+		}
+	}
+	if j.Source.Csv != "" {
+		sources++
+	}
+	if j.Source.Ndjson != "" {
+		sources++
+	}
+	if sources != 1 {
+		return fmt.Errorf("job requires exactly one source of 'sql', 'csv' or 'ndjson' but got %d", sources)
+	}
+
+	destinations := 0
+	if j.Destination.Sheet != nil {
+		destinations++
+		if j.Destination.Sheet.SpreadsheetUrl == "" {
+			return fmt.Errorf("sheet destination requires 'spreadsheetUrl'")
+		}
+	}
+	if j.Destination.Csv != "" {
+		destinations++
+	}
+	if j.Destination.Sql != nil {
+		destinations++
+		if j.Destination.Sql.Table == "" {
+			return fmt.Errorf("sql destination requires 'table'")
+		}
+		if err := j.Destination.Sql.Connection.validate(); err != nil {
+			return err
+		}
+	}
+	if destinations != 1 {
+		return fmt.Errorf("job requires exactly one destination of 'sheet', 'csv' or 'sql' but got %d", destinations)
+	}
+	return nil
+}
+
+func (j *Job) resolvePaths(dir string) {
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+
+	resolve(&j.Source.Csv)
+	resolve(&j.Source.Ndjson)
+	resolve(&j.Destination.Csv)
+	if j.Source.Sql != nil {
+		resolve(&j.Source.Sql.QueryFile)
+	}
+}
+
+func (c *Connection) validate() error {
+	if c.Profile == "" && c.ConnectionUri == "" {
+		return fmt.Errorf("connection requires either 'profile' or 'connectionUri'")
+	}
+	return nil
+}
+
+// config resolves the connection like the '--profile' and '--db-*' flags of sql2json
+func (c *Connection) config() (*sql2json.Config, error) {
+	flags := &sql2json.ConnectionFlags{
+		Profile:         c.Profile,
+		DbConnectionUri: c.ConnectionUri,
+		DbUser:          c.User,
+		DbName:          c.Database,
+	}
+	if c.PasswordEnv != "" {
+		password, ok := os.LookupEnv(c.PasswordEnv)
+		if !ok {
+			return nil, fmt.Errorf("password variable %s is not set", c.PasswordEnv)
+		}
+		flags.DbPassword = password
+	}
+	return flags.Config()
+}
@@ -0,0 +1,57 @@
+package syncjob
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJob(t *testing.T) {
+	job, err := ParseJob([]byte(`
+source:
+  sql:
+    profile: reporting
+    query: SELECT * FROM users WHERE id > :min
+    params:
+      min: 10
+destination:
+  sheet:
+    spreadsheetUrl: https://docs.google.com/spreadsheets/d/abc/edit#gid=0
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, "reporting", job.Source.Sql.Profile)
+	assert.Equal(t, map[string]any{"min": 10}, job.Source.Sql.Params)
+	assert.Equal(t, "https://docs.google.com/spreadsheets/d/abc/edit#gid=0", job.Destination.Sheet.SpreadsheetUrl)
+}
+
+func TestParseJob_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":        "source:\n  csv: a.csv\n  typo: 1\ndestination:\n  csv: b.csv\n",
+		"no source":            "destination:\n  csv: b.csv\n",
+		"two sources":          "source:\n  csv: a.csv\n  ndjson: a.ndjson\ndestination:\n  csv: b.csv\n",
+		"no destination":       "source:\n  csv: a.csv\n",
+		"sql without query":    "source:\n  sql:\n    profile: p\ndestination:\n  csv: b.csv\n",
+		"sql without database": "source:\n  sql:\n    query: SELECT 1\ndestination:\n  csv: b.csv\n",
+		"table missing":        "source:\n  csv: a.csv\ndestination:\n  sql:\n    profile: p\n",
+		"sheet without url":    "source:\n  csv: a.csv\ndestination:\n  sheet: {}\n",
+	}
+	for name, job := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseJob([]byte(job))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadJob_ResolvesPaths(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "job.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("source:\n  csv: in.csv\ndestination:\n  csv: /tmp/out.csv\n"), 0o600))
+
+	job, err := LoadJob(path)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "in.csv"), job.Source.Csv)
+	assert.Equal(t, "/tmp/out.csv", job.Destination.Csv)
+}
@@ -0,0 +1,209 @@
+package syncjob
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/trichner/tb/pkg/csv2json"
+	"github.com/trichner/tb/pkg/json2sheet"
+	"github.com/trichner/tb/pkg/json2sql"
+	"github.com/trichner/tb/pkg/sheets"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+// Run copies the rows of the source to the destination, rows are streamed between both as NDJSON.
+// It returns once both are done, a source without rows leaves the destination empty.
+func Run(ctx context.Context, job *Job) error {
+	pr, pw := io.Pipe()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(readSource(ctx, &job.Source, pw))
+	}()
+
+	err := writeDestination(ctx, &job.Destination, pr)
+
+	// unblock the source if the destination gave up early
+	pr.CloseWithError(io.ErrClosedPipe)
+	cancel()
+	<-done
+	return err
+}
+
+func readSource(ctx context.Context, src *Source, w io.Writer) error {
+	switch {
+	case src.Sql != nil:
+		return readSql(ctx, src.Sql, w)
+	case src.Csv != "":
+		f, err := os.Open(src.Csv)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return csv2json.Convert(f, w)
+	case src.Ndjson != "":
+		f, err := os.Open(src.Ndjson)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	}
+	panic("unvalidated source")
+}
+
+func readSql(ctx context.Context, src *SqlSource, w io.Writer) error {
+	query := src.Query
+	if src.QueryFile != "" {
+		b, err := os.ReadFile(src.QueryFile)
+		if err != nil {
+			return fmt.Errorf("cannot read query: %w", err)
+		}
+		query = string(b)
+	}
+
+	cfg, err := src.config()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql2json.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	writer, err := sql2json.NewRowWriter(sql2json.FormatNdjson, w)
+	if err != nil {
+		return err
+	}
+
+	var params *sql2json.Params
+	if len(src.Params) > 0 {
+		params = &sql2json.Params{Named: src.Params}
+	}
+	if err := sql2json.ExecQuery(ctx, db, query, params, writer, &sql2json.Options{}); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func writeDestination(ctx context.Context, dst *Destination, r io.Reader) error {
+	switch {
+	case dst.Sheet != nil:
+		return writeSheet(ctx, dst.Sheet, r)
+	case dst.Csv != "":
+		return writeCsv(dst.Csv, r)
+	case dst.Sql != nil:
+		return writeSql(ctx, dst.Sql, r)
+	}
+	panic("unvalidated destination")
+}
+
+func writeSheet(ctx context.Context, dst *SheetDestination, r io.Reader) error {
+	svc, err := sheets.NewSheetService(ctx)
+	if err != nil {
+		return err
+	}
+
+	spreadsheetId, sheetId, err := sheets.ParseSpreadsheetUrl(dst.SpreadsheetUrl)
+	if err != nil {
+		return err
+	}
+
+	ss, err := svc.GetSpreadSheet(spreadsheetId)
+	if err != nil {
+		return err
+	}
+
+	sheet, err := ss.SheetById(sheetId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := readRows(r)
+	if err != nil {
+		return err
+	}
+
+	// replace rather than overwrite, a shorter result must not leave stale rows behind
+	if err := sheet.Clear(); err != nil {
+		return err
+	}
+	if len(rows) > 0 {
+		if err := sheet.UpdateValues(rows); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("wrote %d rows to %s", max(len(rows)-1, 0), dst.SpreadsheetUrl)
+	return nil
+}
+
+func writeCsv(path string, r io.Reader) error {
+	rows, err := readRows(r)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := csv.NewWriter(f).WriteAll(rows); err != nil {
+		return fmt.Errorf("cannot write %s: %w", path, err)
+	}
+
+	log.Printf("wrote %d rows to %s", max(len(rows)-1, 0), path)
+	return f.Close()
+}
+
+// readRows reads the NDJSON objects into a header and one row per object, there are no rows
+// at all, not even a header, if there are no objects
+func readRows(r io.Reader) ([][]string, error) {
+	rows, err := json2sheet.ReadObjectRows(r)
+	if err != nil || len(rows) > 1 {
+		return rows, err
+	}
+	return nil, nil
+}
+
+func writeSql(ctx context.Context, dst *SqlDestination, r io.Reader) error {
+	cfg, err := dst.config()
+	if err != nil {
+		return err
+	}
+
+	db, err := sql2json.Open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := json2sql.Load(ctx, db, r, &json2sql.Options{
+		Table:     dst.Table,
+		Create:    dst.Create,
+		UpsertKey: dst.UpsertKey,
+		BatchSize: dst.BatchSize,
+	})
+	if err != nil {
+		return err
+	}
+	if stats.Rejected > 0 {
+		return fmt.Errorf("rejected %d of %d rows for table %q", stats.Rejected, stats.Records, dst.Table)
+	}
+
+	log.Printf("loaded %d rows into %q", stats.Loaded, dst.Table)
+	return nil
+}
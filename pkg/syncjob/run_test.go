@@ -0,0 +1,133 @@
+package syncjob
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/trichner/tb/pkg/sheets"
+	"github.com/trichner/tb/pkg/sheets/sheetstest"
+	"github.com/trichner/tb/pkg/sql2json"
+)
+
+func newTestDb(t *testing.T, statements ...string) string {
+	uri := "sqlite://" + filepath.Join(t.TempDir(), "test.db")
+	db, err := sql2json.Open(context.Background(), &sql2json.Config{ConnectionUri: uri})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	for _, s := range statements {
+		_, err := db.Exec(s)
+		assert.NoError(t, err)
+	}
+	return uri
+}
+
+func TestRun_SqlToCsv(t *testing.T) {
+	uri := newTestDb(t,
+		`CREATE TABLE users (id INTEGER, name TEXT)`,
+		`INSERT INTO users VALUES (1, 'Alice'), (2, 'Bob'), (3, 'Carol')`,
+	)
+	out := filepath.Join(t.TempDir(), "out.csv")
+
+	job := &Job{
+		Source: Source{Sql: &SqlSource{
+			Connection: Connection{ConnectionUri: uri},
+			Query:      "SELECT id, name FROM users WHERE id >= :min ORDER BY id",
+			Params:     map[string]any{"min": 2},
+		}},
+		Destination: Destination{Csv: out},
+	}
+	assert.NoError(t, Run(context.Background(), job))
+
+	data, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name\n2,Bob\n3,Carol\n", string(data))
+}
+
+func TestRun_NdjsonToSql(t *testing.T) {
+	uri := newTestDb(t)
+	in := filepath.Join(t.TempDir(), "in.ndjson")
+	assert.NoError(t, os.WriteFile(in, []byte("{\"id\":1,\"name\":\"Alice\"}\n{\"id\":2,\"name\":\"Bob\"}\n"), 0o600))
+
+	job := &Job{
+		Source: Source{Ndjson: in},
+		Destination: Destination{Sql: &SqlDestination{
+			Connection: Connection{ConnectionUri: uri},
+			Table:      "users",
+			Create:     true,
+		}},
+	}
+	assert.NoError(t, Run(context.Background(), job))
+
+	db, err := sql2json.Open(context.Background(), &sql2json.Config{ConnectionUri: uri})
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var count int
+	assert.NoError(t, db.QueryRow("SELECT count(*) FROM users").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestRun_CsvToSheet(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+	ss := fake.AddSpreadSheet("sid", "report")
+	sheet := ss.Sheets[0]
+	assert.NoError(t, sheet.UpdateValues([][]string{{"stale"}, {"stale"}, {"stale"}, {"stale"}}))
+
+	in := filepath.Join(t.TempDir(), "in.csv")
+	assert.NoError(t, os.WriteFile(in, []byte("a,b\n1,2\n"), 0o600))
+
+	job := &Job{
+		Source:      Source{Csv: in},
+		Destination: Destination{Sheet: &SheetDestination{SpreadsheetUrl: "https://docs.google.com/spreadsheets/d/sid/edit#gid=0"}},
+	}
+	assert.NoError(t, Run(ctx, job))
+
+	values, err := sheet.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]any{{"a", "b"}, {"1", "2"}}, values)
+}
+
+func TestRun_EmptySource(t *testing.T) {
+	fake := sheetstest.NewService()
+	ctx := sheets.WithService(context.Background(), fake)
+	ss := fake.AddSpreadSheet("sid", "report")
+	sheet := ss.Sheets[0]
+	assert.NoError(t, sheet.UpdateValues([][]string{{"stale"}, {"stale"}}))
+
+	uri := newTestDb(t, `CREATE TABLE users (id INTEGER, name TEXT)`)
+	source := Source{Sql: &SqlSource{
+		Connection: Connection{ConnectionUri: uri},
+		Query:      "SELECT id, name FROM users",
+	}}
+
+	job := &Job{
+		Source:      source,
+		Destination: Destination{Sheet: &SheetDestination{SpreadsheetUrl: "https://docs.google.com/spreadsheets/d/sid/edit#gid=0"}},
+	}
+	assert.NoError(t, Run(ctx, job))
+
+	// the sheet is cleared rather than left with stale rows or an empty header
+	_, err := sheet.Values()
+	assert.ErrorContains(t, err, "no values found")
+
+	out := filepath.Join(t.TempDir(), "out.csv")
+	job = &Job{Source: source, Destination: Destination{Csv: out}}
+	assert.NoError(t, Run(ctx, job))
+
+	data, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Empty(t, string(data))
+}
+
+func TestRun_SourceError(t *testing.T) {
+	job := &Job{
+		Source:      Source{Ndjson: filepath.Join(t.TempDir(), "missing.ndjson")},
+		Destination: Destination{Csv: filepath.Join(t.TempDir(), "out.csv")},
+	}
+	assert.Error(t, Run(context.Background(), job))
+}